@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"go.uber.org/zap"
@@ -16,14 +17,22 @@ import (
 var ConfigBackup Config // 用于在开始任务之前，检测配置是否更新，更新后要重启服务端
 
 type App struct {
-	SegmentDuration       int      `toml:"segment_duration"`
-	TranscribeParallelNum int      `toml:"transcribe_parallel_num"`
-	TranslateParallelNum  int      `toml:"translate_parallel_num"`
-	TranscribeMaxAttempts int      `toml:"transcribe_max_attempts"`
-	TranslateMaxAttempts  int      `toml:"translate_max_attempts"`
-	MaxSentenceLength     int      `toml:"max_sentence_length"`
-	Proxy                 string   `toml:"proxy"`
-	ParsedProxy           *url.URL `toml:"-"`
+	SegmentDuration           int      `toml:"segment_duration"`
+	TranscribeParallelNum     int      `toml:"transcribe_parallel_num"`
+	TranslateParallelNum      int      `toml:"translate_parallel_num"`
+	TranscribeMaxAttempts     int      `toml:"transcribe_max_attempts"`
+	TranslateMaxAttempts      int      `toml:"translate_max_attempts"`
+	MaxSentenceLength         int      `toml:"max_sentence_length"`
+	TranslateMode             string   `toml:"translate_mode"`
+	TranslateBatchSize        int      `toml:"translate_batch_size"`
+	EnableTranslationMemory   bool     `toml:"enable_translation_memory"`
+	TranslationMemoryFuzzy    float64  `toml:"translation_memory_fuzzy_threshold"`
+	EnableHallucinationFilter bool     `toml:"enable_hallucination_filter"`
+	LowConfidenceThreshold    float64  `toml:"low_confidence_threshold"`
+	EnableConfidenceSidecar   bool     `toml:"enable_confidence_sidecar"`
+	PromptTemplateDir         string   `toml:"prompt_template_dir"` // 自定义提示词模板目录，其中的<模板名>.tmpl覆盖内置模板
+	Proxy                     string   `toml:"proxy"`
+	ParsedProxy               *url.URL `toml:"-"`
 }
 
 type Server struct {
@@ -37,6 +46,30 @@ type OpenaiCompatibleConfig struct {
 	Model   string `toml:"model"`
 }
 
+// LlmPurposeConfig 某一用途的大模型请求参数
+type LlmPurposeConfig struct {
+	Model        string  `toml:"model"`         // 为空时使用[llm]中的model
+	SystemPrompt string  `toml:"system_prompt"` // 为空时使用[llm]中的system_prompt
+	Temperature  float64 `toml:"temperature"`
+	MaxTokens    int     `toml:"max_tokens"`
+	Timeout      int     `toml:"timeout"` // 单次请求超时时间，单位秒，0表示不限制
+}
+
+type LlmConfig struct {
+	Provider     string           `toml:"provider"` // 为空或openai表示兼容openai格式的接口，可选 anthropic, gemini, ollama, azure
+	BaseUrl      string           `toml:"base_url"`
+	ApiKey       string           `toml:"api_key"`
+	Model        string           `toml:"model"`         // azure填写部署名称
+	ApiVersion   string           `toml:"api_version"`   // 仅azure使用
+	Json         bool             `toml:"json"`          // 接口是否支持按json schema结构化输出
+	SystemPrompt string           `toml:"system_prompt"` // 各用途默认的系统提示词
+	Translate    LlmPurposeConfig `toml:"translate"`
+	Split        LlmPurposeConfig `toml:"split"`
+	Align        LlmPurposeConfig `toml:"align"`
+	Title        LlmPurposeConfig `toml:"title"`
+	Review       LlmPurposeConfig `toml:"review"`
+}
+
 type LocalModelConfig struct {
 	Model string `toml:"model"`
 }
@@ -54,13 +87,15 @@ type AliyunOssConfig struct {
 }
 
 type AliyunTranscribeConfig struct {
-	Oss    AliyunOssConfig    `toml:"oss"`
-	Speech AliyunSpeechConfig `toml:"speech"`
+	Oss          AliyunOssConfig    `toml:"oss"`
+	Speech       AliyunSpeechConfig `toml:"speech"`
+	VocabularyId string             `toml:"vocabulary_id"` // 阿里云控制台创建的热词表id
 }
 
 type Transcribe struct {
 	Provider              string                 `toml:"provider"`
 	EnableGpuAcceleration bool                   `toml:"enable_gpu_acceleration"`
+	Hotwords              []string               `toml:"hotwords"` // 全局热词，会和任务中传入的热词合并
 	Openai                OpenaiCompatibleConfig `toml:"openai"`
 	Fasterwhisper         LocalModelConfig       `toml:"fasterwhisper"`
 	Whisperkit            LocalModelConfig       `toml:"whisperkit"`
@@ -75,9 +110,59 @@ type AliyunTtsConfig struct {
 }
 
 type Tts struct {
-	Provider string                 `toml:"provider"`
-	Openai   OpenaiCompatibleConfig `toml:"openai"`
-	Aliyun   AliyunTtsConfig        `toml:"aliyun"`
+	Provider   string                 `toml:"provider"`
+	VoiceCodes map[string]string      `toml:"voice_codes"` // 各目标语言默认的配音音色，键为语言代码
+	Openai     OpenaiCompatibleConfig `toml:"openai"`
+	Aliyun     AliyunTtsConfig        `toml:"aliyun"`
+}
+
+type PyannoteConfig struct {
+	Model   string `toml:"model"`
+	HfToken string `toml:"hf_token"`
+}
+
+type HttpServiceConfig struct {
+	BaseUrl string `toml:"base_url"`
+	ApiKey  string `toml:"api_key"`
+}
+
+type Diarize struct {
+	Provider string            `toml:"provider"` // 为空表示不启用说话人分离
+	Whisperx PyannoteConfig    `toml:"whisperx"`
+	Pyannote PyannoteConfig    `toml:"pyannote"`
+	Http     HttpServiceConfig `toml:"http"`
+}
+
+type Translator struct {
+	Provider       string            `toml:"provider"`       // 为空表示使用大模型翻译，可选 deepl, google, libretranslate
+	LanguagePairs  []string          `toml:"language_pairs"` // 使用机器翻译的语言对，如en->de、*->ja，为空时所有语言对都使用
+	Deepl          HttpServiceConfig `toml:"deepl"`
+	Google         HttpServiceConfig `toml:"google"`
+	Libretranslate HttpServiceConfig `toml:"libretranslate"`
+}
+
+type Vad struct {
+	Provider           string  `toml:"provider"`             // 为空表示按固定时长切分音频，可选 energy, silero
+	EnergyThresholdDb  float64 `toml:"energy_threshold_db"`  // 高于底噪多少分贝视为语音，仅energy使用
+	MinSpeechDuration  float64 `toml:"min_speech_duration"`  // 短于该时长的语音片段视为噪声丢弃，单位秒
+	MinSilenceDuration float64 `toml:"min_silence_duration"` // 短于该时长的静音不打断语音片段，单位秒
+	MaxSilenceDuration float64 `toml:"max_silence_duration"` // 超过该时长的静音直接跳过，不送去转录，单位秒
+	SpeechPadding      float64 `toml:"speech_padding"`       // 语音片段前后补充的时长，单位秒
+	SkipMusic          bool    `toml:"skip_music"`           // 是否跳过疑似纯音乐的片段，仅energy使用
+}
+
+// Readability 字幕可读性约束，生成字幕后调整换行和时间轴，原文和译文都会检查，为0的项不检查
+type Readability struct {
+	Enable           bool    `toml:"enable"`
+	MaxCps           float64 `toml:"max_cps"`             // 每秒最多字符数
+	MaxCpsCjk        float64 `toml:"max_cps_cjk"`         // 中日韩文字每秒最多字符数
+	MaxLineLength    int     `toml:"max_line_length"`     // 每行最多字符数
+	MaxLineLengthCjk int     `toml:"max_line_length_cjk"` // 中日韩文字每行最多字符数
+	MaxLines         int     `toml:"max_lines"`           // 原文和译文各自最多行数
+	MinDuration      float64 `toml:"min_duration"`        // 字幕最短显示时长，单位秒
+	MaxDuration      float64 `toml:"max_duration"`        // 字幕最长显示时长，单位秒
+	MinGap           float64 `toml:"min_gap"`             // 相邻字幕的最小间隔，单位秒
+	MaxMergeGap      float64 `toml:"max_merge_gap"`       // 延长后仍然过短的字幕与间隔不超过该值的相邻字幕合并，单位秒，0表示不合并
 }
 
 type OpenAiWhisper struct {
@@ -86,28 +171,46 @@ type OpenAiWhisper struct {
 }
 
 type Config struct {
-	App        App                    `toml:"app"`
-	Server     Server                 `toml:"server"`
-	Llm        OpenaiCompatibleConfig `toml:"llm"`
-	Transcribe Transcribe             `toml:"transcribe"`
-	Tts        Tts                    `toml:"tts"`
+	App         App         `toml:"app"`
+	Server      Server      `toml:"server"`
+	Llm         LlmConfig   `toml:"llm"`
+	Transcribe  Transcribe  `toml:"transcribe"`
+	Tts         Tts         `toml:"tts"`
+	Diarize     Diarize     `toml:"diarize"`
+	Translator  Translator  `toml:"translator"`
+	Vad         Vad         `toml:"vad"`
+	Readability Readability `toml:"readability"`
 }
 
 var Conf = Config{
 	App: App{
-		SegmentDuration:       5,
-		TranslateParallelNum:  3,
-		TranscribeParallelNum: 1,
-		TranscribeMaxAttempts: 3,
-		TranslateMaxAttempts:  3,
-		MaxSentenceLength:     70,
+		SegmentDuration:           5,
+		TranslateParallelNum:      3,
+		TranscribeParallelNum:     1,
+		TranscribeMaxAttempts:     3,
+		TranslateMaxAttempts:      3,
+		MaxSentenceLength:         70,
+		TranslateMode:             "sentence",
+		TranslateBatchSize:        20,
+		EnableTranslationMemory:   false,
+		TranslationMemoryFuzzy:    0.95,
+		EnableHallucinationFilter: false,
+		LowConfidenceThreshold:    0.6,
+		PromptTemplateDir:         "./prompts",
 	},
 	Server: Server{
 		Host: "127.0.0.1",
 		Port: 8888,
 	},
-	Llm: OpenaiCompatibleConfig{
-		Model: "gpt-4o-mini",
+	Llm: LlmConfig{
+		Model:        "gpt-4o-mini",
+		ApiVersion:   "2024-06-01",
+		SystemPrompt: "You are an assistant that helps with subtitle translation.",
+		Translate:    LlmPurposeConfig{Temperature: 0.3, MaxTokens: 8192, Timeout: 300},
+		Split:        LlmPurposeConfig{Temperature: 0.1, MaxTokens: 4096, Timeout: 120},
+		Align:        LlmPurposeConfig{Temperature: 0.1, MaxTokens: 4096, Timeout: 120},
+		Title:        LlmPurposeConfig{Temperature: 0.5, MaxTokens: 2048, Timeout: 120},
+		Review:       LlmPurposeConfig{Temperature: 0, MaxTokens: 4096, Timeout: 180},
 	},
 	Transcribe: Transcribe{
 		Provider:              "openai",
@@ -134,6 +237,31 @@ var Conf = Config{
 			Model: "gpt-4o-mini-tts",
 		},
 	},
+	Diarize: Diarize{
+		Pyannote: PyannoteConfig{
+			Model: "pyannote/speaker-diarization-3.1",
+		},
+	},
+	Vad: Vad{
+		EnergyThresholdDb:  12,
+		MinSpeechDuration:  0.25,
+		MinSilenceDuration: 0.5,
+		MaxSilenceDuration: 3,
+		SpeechPadding:      0.2,
+		SkipMusic:          true,
+	},
+	Readability: Readability{
+		Enable:           false,
+		MaxCps:           17,
+		MaxCpsCjk:        9,
+		MaxLineLength:    42,
+		MaxLineLengthCjk: 16,
+		MaxLines:         2,
+		MinDuration:      1,
+		MaxDuration:      7,
+		MinGap:           0.08,
+		MaxMergeGap:      0.5,
+	},
 }
 
 // 检查必要的配置是否完整
@@ -180,6 +308,123 @@ func validateConfig() error {
 		return errors.New("不支持的转录提供商")
 	}
 
+	// 检查说话人分离配置
+	switch Conf.Diarize.Provider {
+	case "":
+	case "whisperx":
+		if Conf.Transcribe.Provider != "whisperx" {
+			return errors.New("使用whisperx进行说话人分离时，转录提供商也必须是whisperx")
+		}
+		if Conf.Diarize.Whisperx.HfToken == "" {
+			return errors.New("使用whisperx进行说话人分离需要配置 HuggingFace Token")
+		}
+	case "pyannote":
+		if Conf.Diarize.Pyannote.HfToken == "" {
+			return errors.New("使用pyannote进行说话人分离需要配置 HuggingFace Token")
+		}
+	case "http":
+		if Conf.Diarize.Http.BaseUrl == "" {
+			return errors.New("使用http说话人分离服务需要配置 base_url")
+		}
+	default:
+		return errors.New("不支持的说话人分离提供商")
+	}
+
+	// 检查大模型服务配置
+	switch Conf.Llm.Provider {
+	case "", "openai":
+	case "anthropic", "gemini":
+		if Conf.Llm.ApiKey == "" || Conf.Llm.Model == "" {
+			return errors.New("使用Anthropic或Gemini大模型服务需要配置 api_key 和 model")
+		}
+	case "ollama":
+		if Conf.Llm.Model == "" {
+			return errors.New("使用Ollama需要配置 model")
+		}
+	case "azure":
+		if Conf.Llm.BaseUrl == "" || Conf.Llm.ApiKey == "" || Conf.Llm.Model == "" || Conf.Llm.ApiVersion == "" {
+			return errors.New("使用Azure OpenAI需要配置 base_url(资源终结点)、api_key、model(部署名称) 和 api_version")
+		}
+	default:
+		return errors.New("不支持的大模型提供商，可选值：openai,anthropic,gemini,ollama,azure")
+	}
+	for name, purposeConfig := range map[string]LlmPurposeConfig{
+		"translate": Conf.Llm.Translate,
+		"split":     Conf.Llm.Split,
+		"align":     Conf.Llm.Align,
+		"title":     Conf.Llm.Title,
+		"review":    Conf.Llm.Review,
+	} {
+		if purposeConfig.Temperature < 0 || purposeConfig.Temperature > 2 {
+			return fmt.Errorf("llm.%s 的 temperature 取值范围为0-2", name)
+		}
+		if purposeConfig.MaxTokens <= 0 {
+			return fmt.Errorf("llm.%s 的 max_tokens 必须大于0", name)
+		}
+		if purposeConfig.Timeout < 0 {
+			return fmt.Errorf("llm.%s 的 timeout 不能小于0", name)
+		}
+	}
+
+	// 检查机器翻译引擎配置
+	switch Conf.Translator.Provider {
+	case "":
+	case "deepl":
+		if Conf.Translator.Deepl.ApiKey == "" {
+			return errors.New("使用DeepL翻译需要配置 api_key")
+		}
+	case "google":
+		if Conf.Translator.Google.ApiKey == "" {
+			return errors.New("使用Google翻译需要配置 api_key")
+		}
+	case "libretranslate":
+		if Conf.Translator.Libretranslate.BaseUrl == "" {
+			return errors.New("使用LibreTranslate需要配置 base_url")
+		}
+	default:
+		return errors.New("不支持的机器翻译提供商，可选值：deepl,google,libretranslate")
+	}
+	for _, pair := range Conf.Translator.LanguagePairs {
+		if source, target, ok := strings.Cut(pair, "->"); !ok || strings.TrimSpace(source) == "" || strings.TrimSpace(target) == "" {
+			return fmt.Errorf("机器翻译语言对%s格式错误，应为源语言->目标语言，如en->de", pair)
+		}
+	}
+
+	// 检查翻译模式配置
+	switch Conf.App.TranslateMode {
+	case "", "sentence":
+	case "batch":
+		if Conf.App.TranslateBatchSize <= 0 {
+			return errors.New("批量翻译模式下 translate_batch_size 必须大于0")
+		}
+	default:
+		return errors.New("不支持的翻译模式，可选值：sentence,batch")
+	}
+
+	if Conf.App.TranslationMemoryFuzzy < 0 || Conf.App.TranslationMemoryFuzzy > 1 {
+		return errors.New("translation_memory_fuzzy_threshold 的取值范围为0-1")
+	}
+
+	// 检查语音活动检测配置
+	switch Conf.Vad.Provider {
+	case "", "energy", "silero":
+	default:
+		return errors.New("不支持的语音活动检测提供商")
+	}
+	if Conf.Vad.Provider != "" && (Conf.Vad.MinSilenceDuration <= 0 || Conf.Vad.MaxSilenceDuration < Conf.Vad.MinSilenceDuration) {
+		return errors.New("语音活动检测的静音时长配置不正确，max_silence_duration需大于等于min_silence_duration且均大于0")
+	}
+
+	// 检查字幕可读性约束
+	readability := Conf.Readability
+	if readability.MaxCps < 0 || readability.MaxCpsCjk < 0 || readability.MaxLineLength < 0 || readability.MaxLineLengthCjk < 0 || readability.MaxLines < 0 ||
+		readability.MinDuration < 0 || readability.MaxDuration < 0 || readability.MinGap < 0 || readability.MaxMergeGap < 0 {
+		return errors.New("字幕可读性约束的配置不能小于0")
+	}
+	if readability.MaxDuration > 0 && readability.MaxDuration < readability.MinDuration {
+		return errors.New("字幕可读性约束的max_duration需大于等于min_duration")
+	}
+
 	return nil
 }
 
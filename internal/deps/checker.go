@@ -93,6 +93,18 @@ func CheckDependency() error {
 			log.GetLogger().Error("edge-tts环境准备失败", zap.Error(err))
 		}
 	}
+	if config.Conf.Diarize.Provider == "pyannote" {
+		if err = checkPyannote(); err != nil {
+			log.GetLogger().Error("pyannote环境准备失败", zap.Error(err))
+			return err
+		}
+	}
+	if config.Conf.Vad.Provider == "silero" {
+		if err = checkSileroVad(); err != nil {
+			log.GetLogger().Error("silero-vad环境准备失败", zap.Error(err))
+			return err
+		}
+	}
 
 	return nil
 }
@@ -632,4 +644,46 @@ func checkEdgeTts() error {
 	storage.EdgeTtsPath = EdgeTtsBinFilePath
 	log.GetLogger().Info("edge-tts安装完成", zap.String("路径", EdgeTtsBinFilePath))
 	return nil
-}
\ No newline at end of file
+}
+
+// 检测pyannote说话人分离命令行工具
+func checkPyannote() error {
+	_, err := exec.LookPath("pyannote-diarize")
+	if err == nil {
+		log.GetLogger().Info("已找到pyannote-diarize")
+		storage.PyannotePath = "pyannote-diarize"
+		return nil
+	}
+
+	pyannoteBinFilePath := "./bin/pyannote/pyannote-diarize"
+	if runtime.GOOS == "windows" {
+		pyannoteBinFilePath += ".exe"
+	}
+	if _, err = os.Stat(pyannoteBinFilePath); err != nil {
+		return fmt.Errorf("没有找到pyannote-diarize，请先安装并放到PATH或%s", pyannoteBinFilePath)
+	}
+	storage.PyannotePath = pyannoteBinFilePath
+	log.GetLogger().Info("pyannote检查完成", zap.String("路径", pyannoteBinFilePath))
+	return nil
+}
+
+// 检测silero-vad语音活动检测命令行工具
+func checkSileroVad() error {
+	_, err := exec.LookPath("silero-vad")
+	if err == nil {
+		log.GetLogger().Info("已找到silero-vad")
+		storage.SileroVadPath = "silero-vad"
+		return nil
+	}
+
+	sileroBinFilePath := "./bin/silero/silero-vad"
+	if runtime.GOOS == "windows" {
+		sileroBinFilePath += ".exe"
+	}
+	if _, err = os.Stat(sileroBinFilePath); err != nil {
+		return fmt.Errorf("没有找到silero-vad，请先安装并放到PATH或%s", sileroBinFilePath)
+	}
+	storage.SileroVadPath = sileroBinFilePath
+	log.GetLogger().Info("silero-vad检查完成", zap.String("路径", sileroBinFilePath))
+	return nil
+}
@@ -730,6 +730,7 @@ func (sm *SubtitleManager) pollTaskStatus(taskId string) {
 				SubtitleInfo      []api.SubtitleResult `json:"subtitle_info"`
 				SpeechDownloadURL string               `json:"speech_download_url"`
 				TaskId            string               `json:"task_id"`
+				Warnings          []string             `json:"warnings"`
 			} `json:"data"`
 		}
 
@@ -773,7 +774,11 @@ func (sm *SubtitleManager) pollTaskStatus(taskId string) {
 
 			sm.displayMultiTaskDownloadLinks()
 
-			sm.tipsLabel.SetText(fmt.Sprintf("若需要查看合成的视频或者文字稿，请到软件目录下的/tasks/%s/output 目录下查看。", result.Data.TaskId))
+			tips := fmt.Sprintf("若需要查看合成的视频或者文字稿，请到软件目录下的/tasks/%s/output 目录下查看。", result.Data.TaskId)
+			for _, warning := range result.Data.Warnings {
+				tips += "\n" + warning
+			}
+			sm.tipsLabel.SetText(tips)
 			sm.tipsLabel.Show()
 
 			return
@@ -11,6 +11,7 @@ import (
 	"krillin-ai/static"
 	"net/url"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -1129,7 +1130,8 @@ func createStartButton(window fyne.Window, sm *SubtitleManager, videoInputContai
 		}
 		btn.Hide()
 
-		if config.ConfigBackup != config.Conf {
+		// 配置中有切片和map，不能直接用!=比较
+		if !reflect.DeepEqual(config.ConfigBackup, config.Conf) {
 			if err = server.StopBackend(); err != nil {
 				dialog.ShowError(fmt.Errorf("停止后端服务失败: %v", err), window)
 				log.GetLogger().Error("停止后端服务失败", zap.Error(err))
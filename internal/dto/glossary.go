@@ -0,0 +1,7 @@
+package dto
+
+type GlossaryInfo struct {
+	Name              string `json:"name"`
+	TermNum           int    `json:"term_num"`
+	DoNotTranslateNum int    `json:"do_not_translate_num"`
+}
@@ -1,22 +1,72 @@
 package dto
 
+import (
+	"encoding/json"
+	"krillin-ai/internal/types"
+	"strings"
+)
+
+// TargetLanguages 目标语言，兼容单个字符串(可用逗号分隔多个)和字符串数组两种写法
+type TargetLanguages []string
+
+func (t *TargetLanguages) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		var single string
+		if err = json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		list = strings.Split(single, ",")
+	}
+	*t = TargetLanguages{}
+	for _, lang := range list {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			*t = append(*t, lang)
+		}
+	}
+	return nil
+}
+
 type StartVideoSubtitleTaskReq struct {
-	AppId                     uint32   `json:"app_id"`
-	Url                       string   `json:"url"`
-	OriginLanguage            string   `json:"origin_lang"`
-	TargetLang                string   `json:"target_lang"`
-	Bilingual                 uint8    `json:"bilingual"`
-	TranslationSubtitlePos    uint8    `json:"translation_subtitle_pos"`
-	ModalFilter               uint8    `json:"modal_filter"`
-	Tts                       uint8    `json:"tts"`
-	TtsVoiceCode              string   `json:"tts_voice_code"`
-	TtsVoiceCloneSrcFileUrl   string   `json:"tts_voice_clone_src_file_url"`
-	Replace                   []string `json:"replace"`
-	Language                  string   `json:"language"`
-	EmbedSubtitleVideoType    string   `json:"embed_subtitle_video_type"`
-	VerticalMajorTitle        string   `json:"vertical_major_title"`
-	VerticalMinorTitle        string   `json:"vertical_minor_title"`
-	OriginLanguageWordOneLine int      `json:"origin_language_word_one_line"`
+	AppId                     uint32              `json:"app_id"`
+	Url                       string              `json:"url"`
+	OriginLanguage            string              `json:"origin_lang"`
+	TargetLang                TargetLanguages     `json:"target_lang"` // 目标语言，多个时转录和分句只做一次，每种语言分别翻译和生成结果
+	Bilingual                 uint8               `json:"bilingual"`
+	TranslationSubtitlePos    uint8               `json:"translation_subtitle_pos"`
+	ModalFilter               uint8               `json:"modal_filter"`
+	Tts                       uint8               `json:"tts"`
+	TtsVoiceCode              string              `json:"tts_voice_code"`
+	TtsVoiceCodes             map[string]string   `json:"tts_voice_codes"` // 每种目标语言的配音音色，键为语言代码，未指定时主目标语言使用tts_voice_code，其余语言使用配置中的默认音色
+	TtsVoiceCloneSrcFileUrl   string              `json:"tts_voice_clone_src_file_url"`
+	Replace                   []string            `json:"replace"`       // 文字替换，如原词|替换词，按完整的词区分大小写匹配
+	ReplaceRules              []types.ReplaceRule `json:"replace_rules"` // 文字替换规则，支持忽略大小写、正则表达式和单词内部匹配，在replace之后应用
+	Language                  string              `json:"language"`
+	EmbedSubtitleVideoType    string              `json:"embed_subtitle_video_type"`
+	VerticalMajorTitle        string              `json:"vertical_major_title"`
+	VerticalMinorTitle        string              `json:"vertical_minor_title"`
+	OriginLanguageWordOneLine int                 `json:"origin_language_word_one_line"`
+	Diarization               uint8               `json:"diarization"`
+	SpeakerNames              []string            `json:"speaker_names"`            // 说话人名称映射，格式同replace，如SPEAKER_00|张三
+	SpeakerLabel              string              `json:"speaker_label"`            // prefix或style，默认prefix
+	Hotwords                  []string            `json:"hotwords"`                 // 转录热词，会和全局配置的热词合并
+	GlossaryNames             []string            `json:"glossary_names"`           // 使用已保存的术语表
+	GlossaryFile              string              `json:"glossary_file"`            // 本任务上传的csv/tsv术语表，只能使用上传接口返回的路径，如local:./uploads/terms.csv
+	Glossary                  []string            `json:"glossary"`                 // 本任务的术语，格式同replace，如原文术语|译文术语
+	DoNotTranslate            []string            `json:"do_not_translate"`         // 本任务不翻译的词
+	GlossaryRetry             uint8               `json:"glossary_retry"`           // 译文违反术语表时是否重新请求翻译
+	StyleProfile              string              `json:"style_profile"`            // 使用的风格配置名称，如education、gaming
+	StyleGuide                string              `json:"style_guide"`              // 翻译风格要求，会填入提示词模板，与风格配置同时使用时附加在后面
+	PromptTemplates           map[string]string   `json:"prompt_templates"`         // 本任务覆盖的提示词模板，键为模板名称，如translate
+	QualityReview             uint8               `json:"quality_review"`           // 翻译后是否审查译文质量并重新翻译有问题的句子
+	SubtitleFormats           []string            `json:"subtitle_formats"`         // 除srt外额外导出的字幕格式，可选vtt、ttml、dfxp、sbv，以及剪辑软件使用的fcpxml、xmeml、scc
+	VttCueSettings            string              `json:"vtt_cue_settings"`         // webvtt字幕的cue设置，如line:85% align:center
+	AssStyle                  string              `json:"ass_style"`                // 使用的字幕样式预设名称，如classic
+	AssStyleConfig            json.RawMessage     `json:"ass_style_config"`         // 本任务的字幕样式，格式同字幕样式预设，优先于ass_style
+	SubtitleSpec              string              `json:"subtitle_spec"`            // 检查字幕使用的规范名称，如netflix、ebu，为空不检查
+	TranscriptFormats         []string            `json:"transcript_formats"`       // 导出的文稿格式，可选txt、md、docx、json
+	TranscriptParagraphGap    float64             `json:"transcript_paragraph_gap"` // 文稿中停顿超过多少秒另起一段，默认2秒
+	TranscriptTimestamps      uint8               `json:"transcript_timestamps"`    // 文稿每段前是否标注开始时间
 }
 
 type StartVideoSubtitleTaskResData struct {
@@ -53,6 +103,14 @@ type GetVideoSubtitleTaskResData struct {
 	SubtitleInfo      []*SubtitleInfo `json:"subtitle_info"`
 	TargetLanguage    string          `json:"target_language"`
 	SpeechDownloadUrl string          `json:"speech_download_url"`
+	TranslationMemory *TmStats        `json:"translation_memory"`
+	Warnings          []string        `json:"warnings,omitempty"` // 不影响任务完成、需要提示用户的问题
+}
+
+type TmStats struct {
+	ExactHit int `json:"exact_hit"`
+	FuzzyHit int `json:"fuzzy_hit"`
+	Miss     int `json:"miss"`
 }
 
 type GetVideoSubtitleTaskRes struct {
@@ -60,3 +118,47 @@ type GetVideoSubtitleTaskRes struct {
 	Msg   string                       `json:"msg"`
 	Data  *GetVideoSubtitleTaskResData `json:"data"`
 }
+
+type GetSubtitleCuesReq struct {
+	TaskId   string `form:"taskId"`
+	Language string `form:"language"` // 多目标语言时指定语言，为空时为第一个目标语言
+}
+
+// SubtitleCue 一条字幕，时间单位为秒
+type SubtitleCue struct {
+	Index              int      `json:"index"`
+	Start              float64  `json:"start"`
+	End                float64  `json:"end"`
+	OriginText         string   `json:"origin_text"`
+	TranslatedText     string   `json:"translated_text"`
+	Speaker            string   `json:"speaker,omitempty"`
+	Confidence         float64  `json:"confidence,omitempty"`
+	LowConfidenceWords []string `json:"low_confidence_words,omitempty"`
+}
+
+type GetSubtitleCuesResData struct {
+	TaskId   string         `json:"task_id"`
+	Language string         `json:"language"`
+	Cues     []*SubtitleCue `json:"cues"`
+}
+
+// SubtitleCuePatch 对一条字幕的修改，未传的字段保持不变
+type SubtitleCuePatch struct {
+	Index          int      `json:"index"`
+	Start          *float64 `json:"start"`
+	End            *float64 `json:"end"`
+	OriginText     *string  `json:"origin_text"`
+	TranslatedText *string  `json:"translated_text"`
+}
+
+type UpdateSubtitleCuesReq struct {
+	TaskId   string             `json:"task_id"`
+	Language string             `json:"language"` // 多目标语言时指定语言，为空时为第一个目标语言
+	Cues     []SubtitleCuePatch `json:"cues"`
+}
+
+type UpdateSubtitleCuesResData struct {
+	TaskId        string `json:"task_id"`
+	ChangedCueNum int    `json:"changed_cue_num"`
+	TtsCueNum     int    `json:"tts_cue_num"` // 需要重新配音的句子数
+}
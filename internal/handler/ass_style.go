@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"krillin-ai/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveAssStyle 保存字幕样式，同名覆盖
+func (h Handler) SaveAssStyle(c *gin.Context) {
+	saveNamedJson(c, func(style *types.AssStyle) *string { return &style.Name }, h.Service.SaveAssStyle)
+}
+
+func (h Handler) ListAssStyles(c *gin.Context) {
+	styles, err := h.Service.ListAssStyles()
+	respond(c, styles, err)
+}
+
+func (h Handler) GetAssStyle(c *gin.Context) {
+	style, err := h.Service.LoadAssStyle(c.Param("name"))
+	respond(c, style, err)
+}
+
+func (h Handler) DeleteAssStyle(c *gin.Context) {
+	respond(c, nil, h.Service.DeleteAssStyle(c.Param("name")))
+}
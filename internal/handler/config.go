@@ -28,9 +28,11 @@ type ConfigRequest struct {
 		Port int    `json:"port"`
 	} `json:"server"`
 	Llm struct {
-		BaseUrl string `json:"baseUrl"`
-		ApiKey  string `json:"apiKey"`
-		Model   string `json:"model"`
+		Provider   string `json:"provider"`
+		BaseUrl    string `json:"baseUrl"`
+		ApiKey     string `json:"apiKey"`
+		Model      string `json:"model"`
+		ApiVersion string `json:"apiVersion"`
 	} `json:"llm"`
 	Transcribe struct {
 		Provider              string `json:"provider"`
@@ -115,13 +117,17 @@ func (h Handler) GetConfig(c *gin.Context) {
 			Port: config.Conf.Server.Port,
 		},
 		Llm: struct {
-			BaseUrl string `json:"baseUrl"`
-			ApiKey  string `json:"apiKey"`
-			Model   string `json:"model"`
+			Provider   string `json:"provider"`
+			BaseUrl    string `json:"baseUrl"`
+			ApiKey     string `json:"apiKey"`
+			Model      string `json:"model"`
+			ApiVersion string `json:"apiVersion"`
 		}{
-			BaseUrl: config.Conf.Llm.BaseUrl,
-			ApiKey:  config.Conf.Llm.ApiKey,
-			Model:   config.Conf.Llm.Model,
+			Provider:   config.Conf.Llm.Provider,
+			BaseUrl:    config.Conf.Llm.BaseUrl,
+			ApiKey:     config.Conf.Llm.ApiKey,
+			Model:      config.Conf.Llm.Model,
+			ApiVersion: config.Conf.Llm.ApiVersion,
 		},
 	}
 
@@ -198,6 +204,13 @@ func (h Handler) UpdateConfig(c *gin.Context) {
 	config.Conf.Llm.BaseUrl = req.Llm.BaseUrl
 	config.Conf.Llm.ApiKey = req.Llm.ApiKey
 	config.Conf.Llm.Model = req.Llm.Model
+	// 旧版前端不传provider和apiVersion，此时保留原配置
+	if req.Llm.Provider != "" {
+		config.Conf.Llm.Provider = req.Llm.Provider
+	}
+	if req.Llm.ApiVersion != "" {
+		config.Conf.Llm.ApiVersion = req.Llm.ApiVersion
+	}
 
 	// 更新转录配置
 	config.Conf.Transcribe.Provider = req.Transcribe.Provider
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"krillin-ai/internal/dto"
+	"krillin-ai/internal/response"
+	"krillin-ai/internal/service"
+	"krillin-ai/log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UploadGlossary 上传csv/tsv术语表并以name保存，同名覆盖
+func (h Handler) UploadGlossary(c *gin.Context) {
+	name := strings.TrimSpace(c.PostForm("name"))
+	file, err := c.FormFile("file")
+	if err != nil || name == "" {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "参数错误，需要name和file",
+			Data:  nil,
+		})
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if ext != ".csv" && ext != ".tsv" {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "术语表只支持csv和tsv格式",
+			Data:  nil,
+		})
+		return
+	}
+
+	tmpFile, err := os.CreateTemp("", "glossary_*"+ext)
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "文件保存失败: " + file.Filename,
+			Data:  nil,
+		})
+		return
+	}
+	savePath := tmpFile.Name()
+	_ = tmpFile.Close()
+	if err = c.SaveUploadedFile(file, savePath); err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "文件保存失败: " + file.Filename,
+			Data:  nil,
+		})
+		return
+	}
+	defer os.Remove(savePath)
+
+	glossary, err := service.ParseGlossaryFile(savePath)
+	if err != nil {
+		log.GetLogger().Error("UploadGlossary ParseGlossaryFile err", zap.Error(err))
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "术语表解析失败: " + err.Error(),
+			Data:  nil,
+		})
+		return
+	}
+	glossary.Name = name
+	respond(c, dto.GlossaryInfo{
+		Name:              glossary.Name,
+		TermNum:           len(glossary.Terms),
+		DoNotTranslateNum: len(glossary.DoNotTranslate),
+	}, h.Service.SaveGlossary(glossary))
+}
+
+func (h Handler) ListGlossaries(c *gin.Context) {
+	glossaries, err := h.Service.ListGlossaries()
+	infos := make([]dto.GlossaryInfo, 0, len(glossaries))
+	for _, glossary := range glossaries {
+		infos = append(infos, dto.GlossaryInfo{
+			Name:              glossary.Name,
+			TermNum:           len(glossary.Terms),
+			DoNotTranslateNum: len(glossary.DoNotTranslate),
+		})
+	}
+	respond(c, infos, err)
+}
+
+func (h Handler) GetGlossary(c *gin.Context) {
+	glossary, err := h.Service.LoadGlossary(c.Param("name"))
+	respond(c, glossary, err)
+}
+
+func (h Handler) DeleteGlossary(c *gin.Context) {
+	respond(c, nil, h.Service.DeleteGlossary(c.Param("name")))
+}
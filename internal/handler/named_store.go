@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"krillin-ai/internal/response"
+	"krillin-ai/log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// respond 有错误时返回错误信息，否则返回data
+func respond(c *gin.Context, data any, err error) {
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   err.Error(),
+			Data:  nil,
+		})
+		return
+	}
+	response.R(c, response.Response{
+		Error: 0,
+		Msg:   "成功",
+		Data:  data,
+	})
+}
+
+// saveNamedJson 解析请求中json格式的命名配置，去掉名称首尾的空格后保存，同名覆盖
+func saveNamedJson[T any](c *gin.Context, name func(*T) *string, save func(*T) error) {
+	var item T
+	if err := c.ShouldBindJSON(&item); err != nil {
+		log.GetLogger().Error("saveNamedJson ShouldBindJSON err", zap.String("path", c.FullPath()), zap.Error(err))
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "参数错误",
+			Data:  nil,
+		})
+		return
+	}
+	*name(&item) = strings.TrimSpace(*name(&item))
+	respond(c, item, save(&item))
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"krillin-ai/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SaveStyleProfile 保存风格配置，同名覆盖
+func (h Handler) SaveStyleProfile(c *gin.Context) {
+	saveNamedJson(c, func(profile *types.StyleProfile) *string { return &profile.Name }, h.Service.SaveStyleProfile)
+}
+
+func (h Handler) ListStyleProfiles(c *gin.Context) {
+	profiles, err := h.Service.ListStyleProfiles()
+	respond(c, profiles, err)
+}
+
+func (h Handler) GetStyleProfile(c *gin.Context) {
+	profile, err := h.Service.LoadStyleProfile(c.Param("name"))
+	respond(c, profile, err)
+}
+
+func (h Handler) DeleteStyleProfile(c *gin.Context) {
+	respond(c, nil, h.Service.DeleteStyleProfile(c.Param("name")))
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"krillin-ai/internal/dto"
+	"krillin-ai/internal/response"
+	"krillin-ai/log"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetSubtitleCues 获取已完成任务的字幕
+func (h Handler) GetSubtitleCues(c *gin.Context) {
+	var req dto.GetSubtitleCuesReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "参数错误",
+			Data:  nil,
+		})
+		return
+	}
+	data, err := h.Service.GetSubtitleCues(req)
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   err.Error(),
+			Data:  nil,
+		})
+		return
+	}
+	response.R(c, response.Response{
+		Error: 0,
+		Msg:   "成功",
+		Data:  data,
+	})
+}
+
+// UpdateSubtitleCues 修改字幕的文字或时间，任务会重新配音和合成视频，进度通过任务查询接口获取
+func (h Handler) UpdateSubtitleCues(c *gin.Context) {
+	var req dto.UpdateSubtitleCuesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.GetLogger().Error("UpdateSubtitleCues ShouldBindJSON err", zap.Error(err))
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "参数错误",
+			Data:  nil,
+		})
+		return
+	}
+	data, err := h.Service.UpdateSubtitleCues(req)
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   err.Error(),
+			Data:  nil,
+		})
+		return
+	}
+	response.R(c, response.Response{
+		Error: 0,
+		Msg:   "成功",
+		Data:  data,
+	})
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"krillin-ai/internal/response"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SaveSubtitleSpec 保存自定义字幕规范，同名覆盖
+func (h Handler) SaveSubtitleSpec(c *gin.Context) {
+	saveNamedJson(c, func(spec *subtitle.Spec) *string { return &spec.Name }, h.Service.SaveSubtitleSpec)
+}
+
+func (h Handler) ListSubtitleSpecs(c *gin.Context) {
+	specs, err := h.Service.ListSubtitleSpecs()
+	respond(c, specs, err)
+}
+
+func (h Handler) GetSubtitleSpec(c *gin.Context) {
+	spec, err := h.Service.LoadSubtitleSpec(c.Param("name"))
+	respond(c, spec, err)
+}
+
+func (h Handler) DeleteSubtitleSpec(c *gin.Context) {
+	respond(c, nil, h.Service.DeleteSubtitleSpec(c.Param("name")))
+}
+
+// ValidateSubtitleFile 按规范检查上传的srt/vtt/ass字幕文件，language用于选择语言对应的规范，如netflix_ja
+func (h Handler) ValidateSubtitleFile(c *gin.Context) {
+	specName := strings.TrimSpace(c.PostForm("spec"))
+	file, err := c.FormFile("file")
+	if err != nil || specName == "" {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "参数错误，需要spec和file",
+			Data:  nil,
+		})
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if ext != ".srt" && ext != ".vtt" && ext != ".ass" {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "字幕检查只支持srt、vtt和ass格式",
+			Data:  nil,
+		})
+		return
+	}
+
+	tmpFile, err := os.CreateTemp("", "subtitle_spec_*"+ext)
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "文件保存失败: " + file.Filename,
+			Data:  nil,
+		})
+		return
+	}
+	savePath := tmpFile.Name()
+	_ = tmpFile.Close()
+	if err = c.SaveUploadedFile(file, savePath); err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "文件保存失败: " + file.Filename,
+			Data:  nil,
+		})
+		return
+	}
+	defer os.Remove(savePath)
+
+	report, err := h.Service.ValidateSubtitleFile(savePath, specName, types.StandardLanguageCode(strings.TrimSpace(c.PostForm("language"))))
+	if err != nil {
+		log.GetLogger().Error("ValidateSubtitleFile err", zap.String("file", file.Filename), zap.Error(err))
+	}
+	respond(c, report, err)
+}
@@ -4,6 +4,7 @@ import (
 	"krillin-ai/internal/dto"
 	"krillin-ai/internal/response"
 	"krillin-ai/internal/service"
+	"krillin-ai/internal/types"
 	"krillin-ai/internal/deps"
 	"krillin-ai/log"
 	"os"
@@ -110,7 +111,7 @@ func (h Handler) UploadFile(c *gin.Context) {
 	// 保存每个文件
 	var savedFiles []string
 	for _, file := range files {
-		savePath := "./uploads/" + file.Filename
+		savePath := types.UploadDir + "/" + file.Filename
 		if err := c.SaveUploadedFile(file, savePath); err != nil {
 			response.R(c, response.Response{
 				Error: -1,
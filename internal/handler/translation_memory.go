@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"bytes"
+	"krillin-ai/internal/response"
+	"krillin-ai/log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ImportTranslationMemory 导入TMX文件到翻译记忆，同一语言对的相同原文以导入的为准
+func (h Handler) ImportTranslationMemory(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "参数错误，需要file",
+			Data:  nil,
+		})
+		return
+	}
+	reader, err := file.Open()
+	if err != nil {
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "文件读取失败: " + file.Filename,
+			Data:  nil,
+		})
+		return
+	}
+	defer reader.Close()
+
+	count, err := h.Service.ImportTmx(reader)
+	if err != nil {
+		log.GetLogger().Error("ImportTranslationMemory ImportTmx err", zap.Error(err))
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "TMX导入失败: " + err.Error(),
+			Data:  nil,
+		})
+		return
+	}
+	response.R(c, response.Response{
+		Error: 0,
+		Msg:   "成功",
+		Data:  gin.H{"count": count},
+	})
+}
+
+// ExportTranslationMemory 以TMX格式导出翻译记忆，可以通过source和target筛选语言对
+func (h Handler) ExportTranslationMemory(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := h.Service.ExportTmx(&buf, c.Query("source"), c.Query("target")); err != nil {
+		log.GetLogger().Error("ExportTranslationMemory ExportTmx err", zap.Error(err))
+		response.R(c, response.Response{
+			Error: -1,
+			Msg:   "TMX导出失败: " + err.Error(),
+			Data:  nil,
+		})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=translation_memory.tmx")
+	c.Data(http.StatusOK, "application/x-tmx+xml", buf.Bytes())
+}
@@ -15,11 +15,32 @@ func SetupRouter(r *gin.Engine) {
 	{
 		api.POST("/capability/subtitleTask", hdl.StartSubtitleTask)
 		api.GET("/capability/subtitleTask", hdl.GetSubtitleTask)
+		api.GET("/capability/subtitleTask/cues", hdl.GetSubtitleCues)
+		api.PATCH("/capability/subtitleTask/cues", hdl.UpdateSubtitleCues)
 		api.POST("/file", hdl.UploadFile)
 		api.GET("/file/*filepath", hdl.DownloadFile)
 		api.HEAD("/file/*filepath", hdl.DownloadFile)
 		api.GET("/config", hdl.GetConfig)
 		api.POST("/config", hdl.UpdateConfig)
+		api.GET("/glossary", hdl.ListGlossaries)
+		api.POST("/glossary", hdl.UploadGlossary)
+		api.GET("/glossary/:name", hdl.GetGlossary)
+		api.DELETE("/glossary/:name", hdl.DeleteGlossary)
+		api.GET("/styleProfile", hdl.ListStyleProfiles)
+		api.POST("/styleProfile", hdl.SaveStyleProfile)
+		api.GET("/styleProfile/:name", hdl.GetStyleProfile)
+		api.DELETE("/styleProfile/:name", hdl.DeleteStyleProfile)
+		api.GET("/assStyle", hdl.ListAssStyles)
+		api.POST("/assStyle", hdl.SaveAssStyle)
+		api.GET("/assStyle/:name", hdl.GetAssStyle)
+		api.DELETE("/assStyle/:name", hdl.DeleteAssStyle)
+		api.GET("/subtitleSpec", hdl.ListSubtitleSpecs)
+		api.POST("/subtitleSpec", hdl.SaveSubtitleSpec)
+		api.POST("/subtitleSpec/validate", hdl.ValidateSubtitleFile)
+		api.GET("/subtitleSpec/:name", hdl.GetSubtitleSpec)
+		api.DELETE("/subtitleSpec/:name", hdl.DeleteSubtitleSpec)
+		api.GET("/translationMemory/tmx", hdl.ExportTranslationMemory)
+		api.POST("/translationMemory/tmx", hdl.ImportTranslationMemory)
 	}
 
 	r.GET("/", func(c *gin.Context) {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"krillin-ai/config"
 	"krillin-ai/internal/router"
+	"krillin-ai/internal/service"
 	"krillin-ai/log"
 	"net/http"
 
@@ -15,6 +16,10 @@ import (
 var BackEnd *http.Server
 
 func StartBackend() error {
+	if err := service.LoadPromptTemplates(); err != nil {
+		log.GetLogger().Error("加载提示词模板失败", zap.Error(err))
+		return err
+	}
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.Default()
 	router.SetupRouter(engine)
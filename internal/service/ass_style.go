@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const assStyleDir = "./ass_styles"
+
+var assStyleStore = namedStore[types.AssStyle]{
+	dir:      assStyleDir,
+	label:    "字幕样式",
+	builtins: types.BuiltinAssStyles,
+	nameOf:   func(style *types.AssStyle) string { return style.Name },
+	validate: (*types.AssStyle).Validate,
+}
+
+func (s Service) SaveAssStyle(style *types.AssStyle) error {
+	return assStyleStore.save(style)
+}
+
+// LoadAssStyle 读取已保存的字幕样式，不存在时使用同名的内置预设
+func (s Service) LoadAssStyle(name string) (*types.AssStyle, error) {
+	return assStyleStore.load(name)
+}
+
+// ListAssStyles 列出内置和已保存的字幕样式
+func (s Service) ListAssStyles() ([]*types.AssStyle, error) {
+	return assStyleStore.list()
+}
+
+// DeleteAssStyle 删除已保存的字幕样式，内置预设无法删除
+func (s Service) DeleteAssStyle(name string) error {
+	return assStyleStore.delete(name)
+}
+
+// assLineStyles 返回字幕块第一行(Major)和第二行(Minor)对应的样式
+func assLineStyles(style *types.AssStyle, stepParam *types.SubtitleTaskStepParam) (types.AssLineStyle, types.AssLineStyle) {
+	if stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop {
+		return style.Target, style.Origin
+	}
+	return style.Origin, style.Target
+}
+
+func assAlignment(position string) int {
+	if position == types.AssPositionTop {
+		return 8
+	}
+	return 2
+}
+
+// buildStyledAssHeader 按样式和分辨率生成ass头部，尺寸由百分比换算为像素
+func buildStyledAssHeader(style *types.AssStyle, stepParam *types.SubtitleTaskStepParam, width, height int) string {
+	pixels := func(percent float64, base int) float64 {
+		return percent * float64(base) / 100
+	}
+	bold := 0
+	if style.Bold {
+		bold = -1
+	}
+	major, minor := assLineStyles(style, stepParam)
+	var styleLines []string
+	for _, item := range []struct {
+		name string
+		line types.AssLineStyle
+	}{{"Major", major}, {"Minor", minor}} {
+		marginH := int(math.Round(pixels(style.MarginH, width)))
+		styleLines = append(styleLines, fmt.Sprintf("Style: %s,%s,%d,%s,&H000000FF,%s,&H64000000,%d,0,0,0,100,100,0,0,1,%.1f,%.1f,%d,%d,%d,%d,1",
+			item.name, style.FontFamily, int(math.Round(pixels(item.line.FontSize, height))), types.AssColor(item.line.PrimaryColor), types.AssColor(style.OutlineColor), bold,
+			pixels(style.OutlineWidth, height), pixels(style.Shadow, height), assAlignment(item.line.Position), marginH, marginH, int(math.Round(pixels(style.MarginV, height)))))
+	}
+	return fmt.Sprintf(types.AssHeaderStyledPattern, width, height, strings.Join(styleLines, "\n"))
+}
+
+// assResolution 获取生成ass使用的分辨率，没有视频时使用默认分辨率
+func assResolution(stepParam *types.SubtitleTaskStepParam, isHorizontal bool) (int, int) {
+	if stepParam.InputVideoPath != "" {
+		if _, err := os.Stat(stepParam.InputVideoPath); err == nil {
+			if width, height, err := getResolution(stepParam.InputVideoPath); err == nil {
+				return width, height
+			}
+		}
+	}
+	if isHorizontal {
+		return 1920, 1080
+	}
+	return 720, 1280
+}
+
+// exportStyledAss 生成可单独下载的双语ass字幕，未指定样式时使用classic预设
+func exportStyledAss(stepParam *types.SubtitleTaskStepParam) {
+	assParam := *stepParam
+	if assParam.AssStyle == nil {
+		assParam.AssStyle = types.BuiltinAssStyles["classic"]
+	}
+	assPath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskStyledAssFileName)
+	sub, err := loadTaskSubtitle(stepParam)
+	if err != nil {
+		log.GetLogger().Warn("exportStyledAss loadTaskSubtitle err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		return
+	}
+	if err = subtitleToAss(sub, assPath, true, &assParam); err != nil {
+		log.GetLogger().Warn("exportStyledAss subtitleToAss err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		return
+	}
+	subtitleInfo := types.SubtitleFileInfo{
+		Path:               assPath,
+		LanguageIdentifier: "ass",
+	}
+	if stepParam.UserUILanguage == types.LanguageNameEnglish {
+		subtitleInfo.Name = "Styled Bilingual Subtitle (ASS)"
+	} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+		subtitleInfo.Name = "带样式双语字幕(ASS)"
+	}
+	if len(stepParam.TargetLanguages) > 1 {
+		subtitleInfo.LanguageIdentifier = "ass_" + string(stepParam.TargetLanguage)
+		subtitleInfo.Name = types.GetStandardLanguageName(stepParam.TargetLanguage) + " " + subtitleInfo.Name
+	}
+	stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+}
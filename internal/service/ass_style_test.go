@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"krillin-ai/internal/types"
+	"strings"
+	"testing"
+)
+
+func TestBuildStyledAssHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		style      string
+		resultType types.SubtitleResultType
+		width      int
+		height     int
+		wantStyles []string
+	}{
+		{
+			name:       "translation on top",
+			style:      "classic",
+			resultType: types.SubtitleResultTypeBilingualTranslationOnTop,
+			width:      1920,
+			height:     1080,
+			wantStyles: []string{
+				"Style: Major,Arial,52,&H0000BFFF,&H000000FF,&H00000000,&H64000000,-1,0,0,0,100,100,0,0,1,8.6,5.4,2,48,48,76,1",
+				"Style: Minor,Arial,38,&H0000BFFF,&H000000FF,&H00000000,&H64000000,-1,0,0,0,100,100,0,0,1,8.6,5.4,2,48,48,76,1",
+			},
+		},
+		{
+			name:       "origin on top of a vertical video",
+			style:      "split",
+			resultType: types.SubtitleResultTypeBilingualTranslationOnBottom,
+			width:      720,
+			height:     1280,
+			wantStyles: []string{
+				"Style: Major,Arial,45,&H00FFFFFF,&H000000FF,&H00000000,&H64000000,-1,0,0,0,100,100,0,0,1,6.4,3.8,8,36,36,64,1",
+				"Style: Minor,Arial,58,&H0066E0FF,&H000000FF,&H00000000,&H64000000,-1,0,0,0,100,100,0,0,1,6.4,3.8,2,36,36,64,1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stepParam := &types.SubtitleTaskStepParam{SubtitleResultType: tt.resultType}
+			got := buildStyledAssHeader(types.BuiltinAssStyles[tt.style], stepParam, tt.width, tt.height)
+			want := fmt.Sprintf(types.AssHeaderStyledPattern, tt.width, tt.height, strings.Join(tt.wantStyles, "\n"))
+			if got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
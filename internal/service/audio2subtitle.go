@@ -1,14 +1,13 @@
 package service
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"krillin-ai/config"
 	"krillin-ai/internal/types"
 	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
 	"krillin-ai/pkg/util"
 	"os"
 	"path/filepath"
@@ -24,8 +23,10 @@ import (
 
 // 翻译结果数据结构
 type TranslatedItem struct {
-	OriginText     string
-	TranslatedText string
+	OriginText         string
+	TranslatedText     string
+	GlossaryViolations []string // 译文中未遵守的术语
+	MemoryMatch        string   // 命中翻译记忆的类型，exact或fuzzy，未命中为空
 }
 
 func (s Service) audioToSubtitle(ctx context.Context, stepParam *types.SubtitleTaskStepParam) error {
@@ -89,7 +90,7 @@ func (s Service) audioToSubtitle(ctx context.Context, stepParam *types.SubtitleT
 //	return nil
 //}
 
-func (s Service) transcribeAudio(id int, audioFilePath string, language string, taskBasePath string) (transcriptionData *types.TranscriptionData, err error) {
+func (s Service) transcribeAudio(id int, audioFilePath string, language string, taskBasePath string, options types.TranscriptionOptions) (transcriptionData *types.TranscriptionData, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("audioToSubtitle transcribeAudio panic recovered: %v", r)
@@ -99,7 +100,7 @@ func (s Service) transcribeAudio(id int, audioFilePath string, language string,
 	if language == "zh_cn" {
 		language = "zh" // 切换一下
 	}
-	transcriptionData, err = s.Transcriber.Transcription(audioFilePath, language, taskBasePath)
+	transcriptionData, err = s.Transcriber.Transcription(audioFilePath, language, taskBasePath, options)
 
 	if err != nil {
 		return nil, fmt.Errorf("audioToSubtitle transcribeAudio Transcription err: %w", err)
@@ -122,10 +123,11 @@ func (s Service) IsSplitUseSpace(language types.StandardLanguageCode) bool {
 	return false
 }
 
-func (s Service) splitTextAndTranslateV2(basePath, inputText string, originLang, targetLang types.StandardLanguageCode, enableModalFilter bool, id int) ([]*TranslatedItem, error) {
+// splitTextSentences 把转录文本拆分为适合翻译的短句，多目标语言时只拆分一次
+func (s Service) splitTextSentences(inputText string, originLang types.StandardLanguageCode, prompts *types.PromptSet) []string {
 	sentences := util.SplitTextSentences(inputText, config.Conf.App.MaxSentenceLength)
 	if len(sentences) == 0 {
-		return []*TranslatedItem{}, nil
+		return []string{}
 	}
 	// 补丁：whisper转录中文的时候很多句子后面不输出符号，导致上面基于符号的切分失效
 	if s.IsSplitUseSpace(originLang) {
@@ -148,7 +150,7 @@ func (s Service) splitTextAndTranslateV2(basePath, inputText string, originLang,
 		}
 
 		// 递归拆分长句子直到满足长度要求，保持顺序
-		splitSentences, err := s.splitSentenceRecursively(sentence, 0, 5) // 最多5层递归
+		splitSentences, err := s.splitSentenceRecursively(sentence, 0, 5, prompts) // 最多5层递归
 		if err != nil {
 			log.GetLogger().Error("splitSentenceRecursively error", zap.Error(err), zap.Any("sentence", sentence))
 			// 如果拆分失败，直接添加原句子
@@ -158,76 +160,160 @@ func (s Service) splitTextAndTranslateV2(basePath, inputText string, originLang,
 		}
 	}
 
-	sentences = shortSentences
+	return shortSentences
+}
+
+// translateSplitSentences 把拆分好的句子翻译为目标语言
+func (s Service) translateSplitSentences(basePath string, sentences []string, originLang, targetLang types.StandardLanguageCode, id int, glossary *types.Glossary, enableGlossaryRetry bool, prompts *types.PromptSet) ([]*TranslatedItem, error) {
+	results := make([]*TranslatedItem, len(sentences))
+	// 先查询翻译记忆，精确命中的句子直接复用，模糊命中的句子把记忆中的译文作为参考交给大模型翻译
+	indexes := make([]int, 0, len(sentences))
+	references := make(map[int]string)
+	for i, sentence := range sentences {
+		if config.Conf.App.EnableTranslationMemory {
+			if entry, match := lookupTranslationMemory(sentence, originLang, targetLang); match == types.TranslationMemoryMatchExact {
+				// 记忆中的译文不符合当前术语表时重新翻译
+				if violations := checkGlossary(glossary, sentence, entry.Target); len(violations) == 0 {
+					results[i] = &TranslatedItem{OriginText: sentence, TranslatedText: entry.Target, MemoryMatch: match}
+					continue
+				}
+			} else if match == types.TranslationMemoryMatchFuzzy {
+				references[i] = translationMemoryReference(entry)
+			}
+		}
+		indexes = append(indexes, i)
+	}
+	if len(indexes) < len(sentences) || len(references) > 0 {
+		log.GetLogger().Info("translateSplitSentences translation memory hit", zap.Int("splitId", id), zap.Int("exact", len(sentences)-len(indexes)), zap.Int("fuzzy", len(references)), zap.Int("total", len(sentences)))
+	}
+
+	// 配置了机器翻译引擎且语言对在允许范围内时优先使用，失败的句子再交给大模型
+	if s.Translator != nil && len(indexes) > 0 && machineTranslateEnabled(originLang, targetLang) {
+		indexes = s.machineTranslate(sentences, indexes, results, originLang, targetLang, glossary, enableGlossaryRetry)
+	}
+
+	if config.Conf.App.TranslateMode == types.TranslateModeBatch {
+		s.batchTranslate(sentences, indexes, results, references, targetLang, glossary, enableGlossaryRetry, prompts)
+	} else {
+		s.translateSentences(sentences, indexes, results, references, targetLang, glossary, enableGlossaryRetry, prompts)
+	}
+	for i := range references {
+		if results[i] != nil {
+			results[i].MemoryMatch = types.TranslationMemoryMatchFuzzy
+		}
+	}
 
+	if err := writeGlossaryViolations(basePath, id, results); err != nil {
+		log.GetLogger().Warn("translateSplitSentences writeGlossaryViolations error", zap.Error(err))
+	}
+	return results, nil
+}
+
+// translateSentences 并发逐句翻译sentences中indexes指定的句子，结果写入results对应位置。references为句子下标对应的翻译记忆参考，可以为nil
+func (s Service) translateSentences(sentences []string, indexes []int, results []*TranslatedItem, references map[int]string, targetLang types.StandardLanguageCode, glossary *types.Glossary, enableGlossaryRetry bool, prompts *types.PromptSet) {
 	var (
-		signal  = make(chan struct{}, config.Conf.App.TranslateParallelNum) // 控制最大并发数
-		wg      sync.WaitGroup
-		results = make([]*TranslatedItem, len(sentences))
-		// errChan = make(chan error, 1)
-		// mutex   sync.Mutex
+		signal = make(chan struct{}, config.Conf.App.TranslateParallelNum) // 控制最大并发数
+		wg     sync.WaitGroup
 	)
-
-	for i, sentence := range sentences {
+	for _, i := range indexes {
 		wg.Add(1)
 		signal <- struct{}{}
 
-		go func(index int, originText string) {
+		go func(index int) {
 			defer wg.Done()
 			defer func() { <-signal }()
+			results[index] = s.translateSentenceWithContext(sentences, index, references[index], targetLang, glossary, enableGlossaryRetry, prompts)
+		}(i)
+	}
+	wg.Wait()
+}
 
-			contextSentenceNum := 3
-
-			// 生成前面3个句子的string
-			var previousSentences string
-			if index > 0 {
-				start := 0
-				if index-contextSentenceNum > 0 {
-					start = index - contextSentenceNum
-				}
-				for i := start; i < index; i++ {
-					previousSentences += sentences[i] + "\n"
-				}
-			}
+// translateSentenceWithContext 结合前后3句上下文翻译单个句子，reference为翻译记忆中相似句子的译文
+func (s Service) translateSentenceWithContext(sentences []string, index int, reference string, targetLang types.StandardLanguageCode, glossary *types.Glossary, enableGlossaryRetry bool, prompts *types.PromptSet) *TranslatedItem {
+	originText := sentences[index]
+	contextSentenceNum := 3
+
+	// 生成前面3个句子的string
+	var previousSentences string
+	if index > 0 {
+		start := 0
+		if index-contextSentenceNum > 0 {
+			start = index - contextSentenceNum
+		}
+		for i := start; i < index; i++ {
+			previousSentences += sentences[i] + "\n"
+		}
+	}
 
-			// 生成后面3个句子的string
-			var nextSentences string
-			if index < len(sentences)-1 {
-				end := len(sentences) - 1
-				if index+contextSentenceNum < end {
-					end = index + contextSentenceNum
-				}
-				for i := index + 1; i <= end; i++ {
-					if i > index+1 {
-						nextSentences += "\n"
-					}
-					nextSentences += sentences[i]
-				}
+	// 生成后面3个句子的string
+	var nextSentences string
+	if index < len(sentences)-1 {
+		end := len(sentences) - 1
+		if index+contextSentenceNum < end {
+			end = index + contextSentenceNum
+		}
+		for i := index + 1; i <= end; i++ {
+			if i > index+1 {
+				nextSentences += "\n"
 			}
+			nextSentences += sentences[i]
+		}
+	}
 
-			prompt := fmt.Sprintf(types.SplitTextWithContextPrompt, types.GetStandardLanguageName(targetLang), previousSentences, originText, nextSentences)
+	prompt, err := renderPrompt(prompts, types.PromptTranslate, types.PromptData{
+		TargetLanguage:    types.GetStandardLanguageName(targetLang),
+		Glossary:          glossaryPrompt(glossary, originText),
+		MemoryReference:   reference,
+		PreviousSentences: previousSentences,
+		Text:              originText,
+		NextSentences:     nextSentences,
+	})
+	if err != nil {
+		log.GetLogger().Error("splitTextAndTranslateV2 render prompt error", zap.Error(err), zap.Any("original text", originText))
+		return &TranslatedItem{
+			OriginText:     originText,
+			TranslatedText: originText,
+		}
+	}
 
-			translatedText, err := s.ChatCompleter.ChatCompletion(prompt)
-			if err != nil {
-				log.GetLogger().Error("splitTextAndTranslateV2 llm translate error", zap.Error(err), zap.Any("original text", originText))
-				results[index] = &TranslatedItem{
-					OriginText:     originText,
-					TranslatedText: originText,
-				}
-			} else {
-				translatedText = strings.TrimSpace(translatedText)
-				results[index] = &TranslatedItem{
-					OriginText:     originText,
-					TranslatedText: translatedText,
-				}
+	translatedText, err := s.chat(types.ChatPurposeTranslate, prompt)
+	if err != nil {
+		log.GetLogger().Error("splitTextAndTranslateV2 llm translate error", zap.Error(err), zap.Any("original text", originText))
+		return &TranslatedItem{
+			OriginText:     originText,
+			TranslatedText: originText,
+		}
+	}
+	translatedText = strings.TrimSpace(translatedText)
+	// 术语检查，不符合时带上违反的术语重新翻译一次
+	violations := checkGlossary(glossary, originText, translatedText)
+	if len(violations) > 0 && enableGlossaryRetry {
+		retryPrompt, retryErr := renderPrompt(prompts, types.PromptGlossaryRetry, types.PromptData{
+			PreviousPrompt: prompt,
+			PreviousOutput: translatedText,
+			Violations:     strings.Join(violations, ", "),
+		})
+		var retryText string
+		if retryErr == nil {
+			retryText, retryErr = s.chat(types.ChatPurposeTranslate, retryPrompt)
+		}
+		if retryErr == nil {
+			retryText = strings.TrimSpace(retryText)
+			if retryViolations := checkGlossary(glossary, originText, retryText); len(retryViolations) < len(violations) {
+				translatedText, violations = retryText, retryViolations
 			}
-		}(i, sentence)
+		} else {
+			log.GetLogger().Warn("splitTextAndTranslateV2 glossary retry error", zap.Error(retryErr), zap.Any("original text", originText))
+		}
+	}
+	if len(violations) > 0 {
+		log.GetLogger().Warn("splitTextAndTranslateV2 translation violates glossary", zap.Any("original text", originText), zap.Any("translated text", translatedText), zap.Strings("violations", violations))
+	}
+	return &TranslatedItem{
+		OriginText:         originText,
+		TranslatedText:     translatedText,
+		GlossaryViolations: violations,
 	}
-
-	wg.Wait()
-	// close(errChan)
-
-	return results, nil
 }
 
 func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskStepParam) (err error) {
@@ -239,16 +325,30 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 	}()
 
 	log.GetLogger().Info("audioToSubtitle.audioToSrt start", zap.Any("taskId", stepParam.TaskId))
-	timePoints, err := GetSplitPoints(stepParam.AudioFilePath, float64(config.Conf.App.SegmentDuration)*60)
-	if err != nil {
-		log.GetLogger().Error("audioToSubtitle audioToSrt GetSplitPoints err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle audioToSrt GetSplitPoints err: %w", err)
+	segmentDuration := float64(config.Conf.App.SegmentDuration) * 60
+	var segments [][2]float64
+	if config.Conf.Vad.Provider != "" {
+		segments, err = GetSpeechSegments(stepParam.AudioFilePath, segmentDuration)
+		if err != nil {
+			// 语音活动检测失败不影响任务，回退到固定时长切分
+			log.GetLogger().Warn("audioToSubtitle audioToSrt GetSpeechSegments err, fallback to fixed split", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		} else if len(segments) == 0 {
+			log.GetLogger().Warn("audioToSubtitle audioToSrt GetSpeechSegments found no speech, fallback to fixed split", zap.Any("taskId", stepParam.TaskId))
+		}
+	}
+	if len(segments) == 0 {
+		timePoints, err := GetSplitPoints(stepParam.AudioFilePath, segmentDuration)
+		if err != nil {
+			log.GetLogger().Error("audioToSubtitle audioToSrt GetSplitPoints err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+			return fmt.Errorf("audioToSubtitle audioToSrt GetSplitPoints err: %w", err)
+		}
+		segments = splitPointsToSegments(timePoints)
 	}
-	log.GetLogger().Info("audioToSubtitle audioToSrt GetSplitPoints completed", zap.Any("taskId", stepParam.TaskId), zap.Any("timePoints", timePoints))
+	log.GetLogger().Info("audioToSubtitle audioToSrt split audio completed", zap.Any("taskId", stepParam.TaskId), zap.Any("segments", segments))
 
 	// 更新字幕任务信息
 	stepParam.TaskPtr.ProcessPct = 15
-	segmentNum := len(timePoints) - 1
+	segmentNum := len(segments)
 
 	type DataWithId[T any] struct {
 		Data T
@@ -280,11 +380,13 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 		SrtNoTsFile       string
 	}
 	audioSegments := make([]AudioSegment, segmentNum)
+	// 每个片段的分句结果，只在翻译协程中写入
+	segmentSentences := make([][]string, segmentNum)
 
 	// 输入音频文件到分割队列
 	for i := range segmentNum {
 		pendingSplitQueue <- DataWithId[[2]float64]{
-			Data: [2]float64{timePoints[i], timePoints[i+1]},
+			Data: segments[i],
 			Id:   i,
 		}
 	}
@@ -319,7 +421,14 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 		})
 	}
 
+	// 说话人分离，对完整音频只做一次
+	var diarization *speakerDiarization
+	if stepParam.EnableDiarization && s.Diarizer != nil {
+		diarization = s.startDiarization(stepParam)
+	}
+
 	// 音频转录
+	transcriptionOptions := types.TranscriptionOptions{Hotwords: stepParam.Hotwords}
 	for range config.Conf.App.TranscribeParallelNum {
 		eg.Go(func() error {
 			for {
@@ -337,7 +446,7 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 					log.GetLogger().Info("Begin transcribe", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", audioFileItem.Id))
 					// 语音转文字
 					for range config.Conf.App.TranscribeMaxAttempts {
-						transcriptionData, err = s.transcribeAudio(audioFileItem.Id, audioFileItem.Data, string(stepParam.OriginLanguage), stepParam.TaskBasePath)
+						transcriptionData, err = s.transcribeAudio(audioFileItem.Id, audioFileItem.Data, string(stepParam.OriginLanguage), stepParam.TaskBasePath, transcriptionOptions)
 						if err == nil {
 							break
 						}
@@ -346,6 +455,28 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 						return fmt.Errorf("audioToSubtitle audioToSrt Transcription err: %w", err)
 					}
 					log.GetLogger().Info("Transcribe completed", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", audioFileItem.Id))
+					// 幻觉检测，失败时不中断，沿用原始转录结果
+					if config.Conf.App.EnableHallucinationFilter {
+						transcriptionData, err = s.filterHallucinations(audioFileItem.Id, audioFileItem.Data, string(stepParam.OriginLanguage), stepParam.TaskBasePath, transcriptionOptions, transcriptionData)
+						if err != nil {
+							log.GetLogger().Warn("audioToSubtitle audioToSrt filterHallucinations err", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", audioFileItem.Id), zap.Error(err))
+						}
+						_ = util.SaveToDisk(transcriptionData, filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskAudioTranscriptionDataPersistenceFileNamePattern, audioFileItem.Id)))
+					}
+					// 说话人分离，失败时不中断，只是字幕中没有说话人标注
+					if diarization != nil {
+						err = diarization.assign(ctx, transcriptionData.Words, segments[audioFileItem.Id][0])
+						if err != nil {
+							log.GetLogger().Warn("audioToSubtitle audioToSrt assign speakers err", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", audioFileItem.Id), zap.Error(err))
+						} else {
+							_ = util.SaveToDisk(transcriptionData, filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskAudioTranscriptionDataPersistenceFileNamePattern, audioFileItem.Id)))
+						}
+					}
+					// 逐词的文字替换，分句、翻译以及后续的配音和压制都使用替换后的文字
+					if stepParam.WordReplacer != nil {
+						replaceTranscriptionWords(stepParam.WordReplacer, transcriptionData)
+						_ = util.SaveToDisk(transcriptionData, filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskAudioTranscriptionDataPersistenceFileNamePattern, audioFileItem.Id)))
+					}
 
 					// 发送转录结果
 					transcribedQueue <- DataWithId[*types.TranscriptionData]{
@@ -369,10 +500,14 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 				}
 				var translatedResults []*TranslatedItem
 				var err error
-				// 翻译文本
+				// 分句，结果保留给其余目标语言复用
+				sentences := s.splitTextSentences(translateItem.Data, stepParam.OriginLanguage, stepParam.Prompts)
+				segmentSentences[translateItem.Id] = sentences
+				// 翻译文本，送去翻译的句子先应用其余替换规则
 				log.GetLogger().Info("Begin to translate", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", translateItem.Id))
+				translationSentences := replaceSentences(stepParam.PhraseReplacer, sentences)
 				for range config.Conf.App.TranslateMaxAttempts {
-					translatedResults, err = s.splitTextAndTranslateV2(stepParam.TaskBasePath, translateItem.Data, stepParam.OriginLanguage, stepParam.TargetLanguage, stepParam.EnableModalFilter, translateItem.Id)
+					translatedResults, err = s.translateSplitSentences(stepParam.TaskBasePath, translationSentences, stepParam.OriginLanguage, stepParam.TargetLanguage, translateItem.Id, stepParam.Glossary, stepParam.EnableGlossaryRetry, stepParam.Prompts)
 					if err == nil {
 						break
 					}
@@ -380,10 +515,21 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 				if err != nil {
 					return fmt.Errorf("audioToSubtitle audioToSrt splitTextAndTranslate err: %w", err)
 				}
+				// 统计翻译记忆命中情况，只有这一个协程写入
+				countTranslationMemoryHits(stepParam.TaskPtr, translatedResults)
+				// 审查译文质量并重翻有问题的句子，审查后再写入翻译记忆
+				var reviewItems []*types.QualityReviewItem
+				if stepParam.EnableQualityReview {
+					reviewItems = s.reviewTranslations(stepParam, translateItem.Id, translatedResults)
+					stepParam.QualityReviewItems = append(stepParam.QualityReviewItems, reviewItems...)
+				}
+				rememberTranslations(translatedResults, reviewItems, stepParam.OriginLanguage, stepParam.TargetLanguage)
+				// 后续二次分割和对齐时间戳使用替换前的原文
+				restoreOriginText(translatedResults, sentences)
 				_ = util.SaveToDisk(translatedResults, filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskTranslationDataPersistenceFileNamePattern, translateItem.Id)))
 				log.GetLogger().Info("Translate completed", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", translateItem.Id))
 				// 二次分割长句
-				splitResults, err := s.splitTranslateItem(translatedResults)
+				splitResults, err := s.splitTranslateItem(translatedResults, stepParam.Prompts)
 				if err != nil {
 					// 不中断
 					log.GetLogger().Error("audioToSubtitle audioToSrt splitTranslateItem err", zap.Any("taskId", stepParam.TaskId), zap.Any("splitId", translateItem.Id), zap.Error(err))
@@ -444,46 +590,12 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 				// 更新字幕任务信息
 				processPct += taskWeight * TRANSLATE_WEIGHT
 				stepParam.TaskPtr.ProcessPct = uint8(processPct)
-				// 处理翻译结果，保存不带时间戳的原始字幕
-				originNoTsSrtFileName := filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitSrtNoTimestampFileNamePattern, translatedItems.Id))
-				originNoTsSrtFile, err := os.Create(originNoTsSrtFileName)
-				if err != nil {
-					return fmt.Errorf("audioToSubtitle audioToSrt create srt file err: %w", err)
-				}
-				// 保存不带时间戳的原始字幕
-				for i, translatedItem := range translatedItems.Data {
-					// if util.IsAsianLanguage(stepParam.TargetLanguage) {
-					// 	translatedItem.TranslatedText = util.BeautifyAsianLanguageSentence(translatedItem.TranslatedText)
-					// }
-					// if util.IsAsianLanguage(stepParam.OriginLanguage) {
-					// 	translatedItem.OriginText = util.BeautifyAsianLanguageSentence(translatedItem.OriginText)
-					// }
-					_, _ = originNoTsSrtFile.WriteString(fmt.Sprintf("%d\n", i+1))
-					_, _ = originNoTsSrtFile.WriteString(fmt.Sprintf("%s\n", translatedItem.TranslatedText))
-					_, _ = originNoTsSrtFile.WriteString(fmt.Sprintf("%s\n\n", translatedItem.OriginText))
-				}
-
-				// 此处是为了修复一个未知原因的文件不创建的问题
-				originNoTsSrtFile.Sync()
-				originNoTsSrtFile.Close()
-				audioSegments[translatedItems.Id].SrtNoTsFile = originNoTsSrtFileName
-				// 生成时间戳
-				var srtBlocks []*util.SrtBlock
-				for i, translatedItem := range translatedItems.Data {
-					srtBlocks = append(srtBlocks, &util.SrtBlock{
-						Index:                  i + 1,
-						Timestamp:              "",
-						OriginLanguageSentence: translatedItem.OriginText,
-						TargetLanguageSentence: translatedItem.TranslatedText,
-					})
-				}
-
 				segmentIdx := translatedItems.Id
-
-				err = generateSrtWithTimestamps(srtBlocks, timePoints[segmentIdx], audioSegments[segmentIdx].TranscriptionData.Words, segmentIdx, stepParam)
+				srtNoTsFile, err := writeSegmentSubtitles(stepParam, segmentIdx, translatedItems.Data, segments[segmentIdx][0], audioSegments[segmentIdx].TranscriptionData.Words)
 				if err != nil {
-					return fmt.Errorf("audioToSubtitle audioToSrt generateTimestamps err: %w", err)
+					return fmt.Errorf("audioToSubtitle audioToSrt writeSegmentSubtitles err: %w", err)
 				}
+				audioSegments[segmentIdx].SrtNoTsFile = srtNoTsFile
 				completedTasks++
 				// 拆分、转录、翻译任务全部完成
 				if completedTasks >= segmentNum {
@@ -504,16 +616,79 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 		return fmt.Errorf("audioToSubtitle audioToSrt errgroup wait err: %w", err)
 	}
 
+	// 保存转录和分句结果，供其余目标语言复用
+	stepParam.SentenceSegments = make([]*types.SentenceSegment, segmentNum)
+	for i := range segmentNum {
+		stepParam.SentenceSegments[i] = &types.SentenceSegment{
+			Offset:    segments[i][0],
+			Words:     audioSegments[i].TranscriptionData.Words,
+			Sentences: segmentSentences[i],
+		}
+	}
+
+	if err = mergeSegmentSubtitles(stepParam, segmentNum); err != nil {
+		return err
+	}
+
+	// 生成低置信度审阅报告
+	if err = generateConfidenceOutputs(stepParam); err != nil {
+		log.GetLogger().Warn("audioToSubtitle audioToSrt generateConfidenceOutputs err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+	}
+
+	// 更新字幕任务信息
+	stepParam.TaskPtr.ProcessPct = 90
+
+	log.GetLogger().Info("audioToSubtitle.audioToSrt end", zap.Any("taskId", stepParam.TaskId))
+
+	return nil
+}
+
+// writeSegmentSubtitles 保存一个片段不带时间戳的字幕，并对齐时间戳生成该片段的各类字幕文件
+func writeSegmentSubtitles(stepParam *types.SubtitleTaskStepParam, segmentIdx int, translatedItems []*TranslatedItem, tsOffset float64, words []types.Word) (string, error) {
+	replaceTranslatedText(stepParam, translatedItems)
+	originNoTsSrtFileName := filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitSrtNoTimestampFileNamePattern, segmentIdx))
+	originNoTsSrtFile, err := os.Create(originNoTsSrtFileName)
+	if err != nil {
+		return "", fmt.Errorf("writeSegmentSubtitles create srt file err: %w", err)
+	}
+	// 保存不带时间戳的原始字幕
+	for i, translatedItem := range translatedItems {
+		_, _ = originNoTsSrtFile.WriteString(fmt.Sprintf("%d\n", i+1))
+		_, _ = originNoTsSrtFile.WriteString(fmt.Sprintf("%s\n", translatedItem.TranslatedText))
+		_, _ = originNoTsSrtFile.WriteString(fmt.Sprintf("%s\n\n", translatedItem.OriginText))
+	}
+
+	// 此处是为了修复一个未知原因的文件不创建的问题
+	originNoTsSrtFile.Sync()
+	originNoTsSrtFile.Close()
+	// 生成时间戳
+	var srtBlocks []*util.SrtBlock
+	for i, translatedItem := range translatedItems {
+		srtBlocks = append(srtBlocks, &util.SrtBlock{
+			Index:                  i + 1,
+			Timestamp:              "",
+			OriginLanguageSentence: translatedItem.OriginText,
+			TargetLanguageSentence: translatedItem.TranslatedText,
+		})
+	}
+
+	err = generateSrtWithTimestamps(srtBlocks, tsOffset, words, segmentIdx, stepParam)
+	if err != nil {
+		return "", fmt.Errorf("writeSegmentSubtitles generateTimestamps err: %w", err)
+	}
+	return originNoTsSrtFileName, nil
+}
+
+// mergeSegmentSubtitles 合并各片段的字幕文件
+func mergeSegmentSubtitles(stepParam *types.SubtitleTaskStepParam, segmentNum int) error {
+	var err error
 	// 合并文件
 	originNoTsFiles := make([]string, 0)
-	bilingualFiles := make([]string, 0)
 	shortOriginMixedFiles := make([]string, 0)
 	shortOriginFiles := make([]string, 0)
 	for i := range segmentNum {
 		splitOriginNoTsFile := fmt.Sprintf("%s/%s", stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitSrtNoTimestampFileNamePattern, i))
 		originNoTsFiles = append(originNoTsFiles, splitOriginNoTsFile)
-		splitBilingualFile := fmt.Sprintf("%s/%s", stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitBilingualSrtFileNamePattern, i))
-		bilingualFiles = append(bilingualFiles, splitBilingualFile)
 		shortOriginMixedFile := fmt.Sprintf("%s/%s", stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitShortOriginMixedSrtFileNamePattern, i))
 		shortOriginMixedFiles = append(shortOriginMixedFiles, shortOriginMixedFile)
 		shortOriginFile := fmt.Sprintf("%s/%s", stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitShortOriginSrtFileNamePattern, i))
@@ -529,13 +704,35 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 		return fmt.Errorf("audioToSubtitle audioToSrt merge originNoTsFile err: %w", err)
 	}
 
-	// 合并最终双语字幕
+	// 合并结构化字幕数据，并由此生成最终双语字幕
+	mergedSubtitle, err := mergeSegmentSubtitleData(stepParam, segmentNum)
+	if err != nil {
+		log.GetLogger().Error("audioToSubtitle audioToSrt mergeSegmentSubtitleData err",
+			zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		return fmt.Errorf("audioToSubtitle audioToSrt mergeSegmentSubtitleData err: %w", err)
+	}
+	// 调整换行和时间轴使字幕满足可读性约束
+	if config.Conf.Readability.Enable {
+		constraints := readabilityConstraints()
+		constraints.OriginLanguage, constraints.TargetLanguage = string(stepParam.OriginLanguage), string(stepParam.TargetLanguage)
+		violations := mergedSubtitle.ApplyReadability(constraints)
+		if err = writeReadabilityReport(stepParam, len(mergedSubtitle.Cues), violations); err != nil {
+			log.GetLogger().Warn("audioToSubtitle audioToSrt writeReadabilityReport err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		}
+	}
+	subtitleFile := filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskSubtitleDataFileName)
+	if err = subtitle.SaveJson(subtitleFile, mergedSubtitle); err != nil {
+		log.GetLogger().Error("audioToSubtitle audioToSrt save subtitle data err",
+			zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		return fmt.Errorf("audioToSubtitle audioToSrt save subtitle data err: %w", err)
+	}
+	stepParam.SubtitleFilePath = subtitleFile
 	bilingualFile := fmt.Sprintf("%s/%s", stepParam.TaskBasePath, types.SubtitleTaskBilingualSrtFileName)
-	err = util.MergeSrtFiles(bilingualFile, bilingualFiles...)
+	err = subtitle.WriteFile(bilingualFile, mergedSubtitle, subtitle.FormatSrt, srtOptions(stepParam, bilingualLayout(stepParam)))
 	if err != nil {
-		log.GetLogger().Error("audioToSubtitle audioToSrt merge BilingualFile err",
+		log.GetLogger().Error("audioToSubtitle audioToSrt write BilingualFile err",
 			zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle audioToSrt merge BilingualFile err: %w", err)
+		return fmt.Errorf("audioToSubtitle audioToSrt write BilingualFile err: %w", err)
 	}
 
 	//合并最终双语字幕 长中文+短英文
@@ -560,11 +757,6 @@ func (s Service) audioToSrt(ctx context.Context, stepParam *types.SubtitleTaskSt
 	// 供后续分割单语使用
 	stepParam.BilingualSrtFilePath = bilingualFile
 
-	// 更新字幕任务信息
-	stepParam.TaskPtr.ProcessPct = 90
-
-	log.GetLogger().Info("audioToSubtitle.audioToSrt end", zap.Any("taskId", stepParam.TaskId))
-
 	return nil
 }
 
@@ -575,80 +767,44 @@ func splitSrt(stepParam *types.SubtitleTaskStepParam) error {
 	originLanguageTextFilePath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskOriginLanguageTextFileName)
 	targetLanguageSrtFilePath := filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskTargetLanguageSrtFileName)
 	targetLanguageTextFilePath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskTargetLanguageTextFileName)
-	// 打开双语字幕文件
-	file, err := os.Open(stepParam.BilingualSrtFilePath)
-	if err != nil {
-		log.GetLogger().Error("audioToSubtitle splitSrt open bilingual srt file error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle splitSrt open bilingual srt file error: %w", err)
-	}
-	defer file.Close()
-
-	// 打开输出字幕和文稿文件
-	originLanguageSrtFile, err := os.Create(originLanguageSrtFilePath)
-	if err != nil {
-		log.GetLogger().Error("audioToSubtitle splitSrt create originLanguageSrtFile error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle splitSrt create originLanguageSrtFile error: %w", err)
-	}
-	defer originLanguageSrtFile.Close()
-
-	originLanguageTextFile, err := os.Create(originLanguageTextFilePath)
-	if err != nil {
-		log.GetLogger().Error("audioToSubtitle splitSrt create originLanguageTextFile error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle splitSrt create originLanguageTextFile error: %w", err)
-	}
-	defer originLanguageTextFile.Close()
-
-	targetLanguageSrtFile, err := os.Create(targetLanguageSrtFilePath)
+	// 由结构化字幕生成单语字幕和文稿
+	sub, err := loadTaskSubtitle(stepParam)
 	if err != nil {
-		log.GetLogger().Error("audioToSubtitle.splitSrt create targetLanguageSrtFile error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle.splitSrt create targetLanguageSrtFile error: %w", err)
-	}
-	defer targetLanguageSrtFile.Close()
-
-	targetLanguageTextFile, err := os.Create(targetLanguageTextFilePath)
-	if err != nil {
-		log.GetLogger().Error("audioToSubtitle.splitSrt create targetLanguageTextFile error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle.splitSrt create targetLanguageTextFile error: %w", err)
-	}
-	defer targetLanguageTextFile.Close()
-
-	isTargetOnTop := stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop
-
-	scanner := bufio.NewScanner(file)
-	var block []string
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		// 空行代表一个字幕块的结束
-		if line == "" {
-			if len(block) > 0 {
-				util.ProcessBlock(block, targetLanguageSrtFile, targetLanguageTextFile, originLanguageSrtFile, originLanguageTextFile, isTargetOnTop)
-				block = nil
-			}
-		} else {
-			block = append(block, line)
+		log.GetLogger().Error("audioToSubtitle splitSrt loadTaskSubtitle error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		return fmt.Errorf("audioToSubtitle splitSrt loadTaskSubtitle error: %w", err)
+	}
+	for _, output := range []struct {
+		layout   subtitle.Layout
+		srtPath  string
+		textPath string
+	}{
+		{subtitle.LayoutOrigin, originLanguageSrtFilePath, originLanguageTextFilePath},
+		{subtitle.LayoutTarget, targetLanguageSrtFilePath, targetLanguageTextFilePath},
+	} {
+		if err = subtitle.WriteFile(output.srtPath, sub, subtitle.FormatSrt, srtOptions(stepParam, output.layout)); err != nil {
+			log.GetLogger().Error("audioToSubtitle splitSrt write srt file error", zap.Any("taskId", stepParam.TaskId), zap.String("path", output.srtPath), zap.Error(err))
+			return fmt.Errorf("audioToSubtitle splitSrt write srt file error: %w", err)
+		}
+		if err = subtitle.WriteTranscriptFile(output.textPath, sub, subtitle.TranscriptFormatTxt, transcriptOptions(stepParam, output.layout)); err != nil {
+			log.GetLogger().Error("audioToSubtitle splitSrt write text file error", zap.Any("taskId", stepParam.TaskId), zap.String("path", output.textPath), zap.Error(err))
+			return fmt.Errorf("audioToSubtitle splitSrt write text file error: %w", err)
 		}
 	}
-	// 处理文件末尾的字幕块
-	if len(block) > 0 {
-		util.ProcessBlock(block, targetLanguageSrtFile, targetLanguageTextFile, originLanguageSrtFile, originLanguageTextFile, isTargetOnTop)
-	}
-
-	if err = scanner.Err(); err != nil {
-		log.GetLogger().Error("audioToSubtitle splitSrt scan bilingual srt file error", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
-		return fmt.Errorf("audioToSubtitle splitSrt scan bilingual srt file error: %w", err)
-	}
-	// 添加原语言单语字幕
-	subtitleInfo := types.SubtitleFileInfo{
-		Path:               originLanguageSrtFilePath,
-		LanguageIdentifier: string(stepParam.OriginLanguage),
-	}
-	if stepParam.UserUILanguage == types.LanguageNameEnglish {
-		subtitleInfo.Name = types.GetStandardLanguageName(stepParam.OriginLanguage) + " Subtitle"
-	} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
-		subtitleInfo.Name = types.GetStandardLanguageName(stepParam.OriginLanguage) + " 单语字幕"
+	// 添加原语言单语字幕，多目标语言时只在主目标语言中添加一次
+	var subtitleInfo types.SubtitleFileInfo
+	srtInfoStart := len(stepParam.SubtitleInfos)
+	if !isExtraTargetLanguage(stepParam) {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               originLanguageSrtFilePath,
+			LanguageIdentifier: string(stepParam.OriginLanguage),
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = types.GetStandardLanguageName(stepParam.OriginLanguage) + " Subtitle"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = types.GetStandardLanguageName(stepParam.OriginLanguage) + " 单语字幕"
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
 	}
-	stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
 	// 添加目标语言单语字幕
 	if stepParam.SubtitleResultType == types.SubtitleResultTypeTargetOnly || stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnBottom || stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop {
 		subtitleInfo = types.SubtitleFileInfo{
@@ -673,6 +829,120 @@ func splitSrt(stepParam *types.SubtitleTaskStepParam) error {
 		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
 			subtitleInfo.Name = "双语字幕"
 		}
+		// 多目标语言时区分各语言的双语字幕
+		if len(stepParam.TargetLanguages) > 1 {
+			subtitleInfo.LanguageIdentifier = "bilingual_" + string(stepParam.TargetLanguage)
+			subtitleInfo.Name = types.GetStandardLanguageName(stepParam.TargetLanguage) + " " + subtitleInfo.Name
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+	}
+
+	// 导出任务指定的其他字幕格式
+	if len(stepParam.SubtitleFormats) > 0 {
+		exportSubtitleFormats(stepParam, append([]types.SubtitleFileInfo{}, stepParam.SubtitleInfos[srtInfoStart:]...))
+	}
+
+	// 导出任务指定的文稿格式
+	if len(stepParam.TranscriptFormats) > 0 {
+		exportTranscripts(stepParam, sub, originLanguageTextFilePath, targetLanguageTextFilePath)
+	}
+
+	// 双语字幕同时导出带样式的ass
+	if stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop || stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnBottom {
+		exportStyledAss(stepParam)
+	}
+
+	// 添加审阅报告和置信度文件
+	if stepParam.ReviewReportFilePath != "" {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               stepParam.ReviewReportFilePath,
+			LanguageIdentifier: "review",
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = "Low Confidence Review Report"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = "低置信度审阅报告"
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+	}
+	if stepParam.PromptTemplatesFilePath != "" {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               stepParam.PromptTemplatesFilePath,
+			LanguageIdentifier: "prompts",
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = "Prompt Templates (JSON)"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = "提示词模板(JSON)"
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+	}
+	if stepParam.EnableQualityReview {
+		if err := writeQualityReport(stepParam); err != nil {
+			log.GetLogger().Warn("splitSrt writeQualityReport err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		}
+	}
+	if stepParam.QualityReportFilePath != "" {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               stepParam.QualityReportFilePath,
+			LanguageIdentifier: "quality",
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = "Translation Quality Report (JSON)"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = "译文质量报告(JSON)"
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+	}
+	if stepParam.ReadabilityReportFilePath != "" {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               stepParam.ReadabilityReportFilePath,
+			LanguageIdentifier: "readability",
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = "Subtitle Readability Report (JSON)"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = "字幕可读性报告(JSON)"
+		}
+		// 多目标语言时区分各语言的报告
+		if len(stepParam.TargetLanguages) > 1 {
+			subtitleInfo.LanguageIdentifier = "readability_" + string(stepParam.TargetLanguage)
+			subtitleInfo.Name = types.GetStandardLanguageName(stepParam.TargetLanguage) + " " + subtitleInfo.Name
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+	}
+	if stepParam.SubtitleSpec != "" {
+		if err := validateSubtitleSpecs(stepParam); err != nil {
+			log.GetLogger().Warn("splitSrt validateSubtitleSpecs err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		}
+	}
+	if stepParam.SpecReportFilePath != "" {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               stepParam.SpecReportFilePath,
+			LanguageIdentifier: "spec",
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = "Subtitle Spec Report (JSON)"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = "字幕规范检查报告(JSON)"
+		}
+		// 多目标语言时区分各语言的报告
+		if len(stepParam.TargetLanguages) > 1 {
+			subtitleInfo.LanguageIdentifier = "spec_" + string(stepParam.TargetLanguage)
+			subtitleInfo.Name = types.GetStandardLanguageName(stepParam.TargetLanguage) + " " + subtitleInfo.Name
+		}
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+	}
+	if stepParam.ConfidenceSidecarFilePath != "" {
+		subtitleInfo = types.SubtitleFileInfo{
+			Path:               stepParam.ConfidenceSidecarFilePath,
+			LanguageIdentifier: "confidence",
+		}
+		if stepParam.UserUILanguage == types.LanguageNameEnglish {
+			subtitleInfo.Name = "Subtitle Confidence (JSON)"
+		} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+			subtitleInfo.Name = "字幕置信度(JSON)"
+		}
 		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
 	}
 
@@ -1067,26 +1337,13 @@ func generateSrtWithTimestamps(srtBlocks []*util.SrtBlock, tsOffset float64, wor
 		lastTs = ts
 	}
 
-	// 保存带时间戳的原始字幕
-	finalBilingualSrtFileName := fmt.Sprintf("%s/%s", stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitBilingualSrtFileNamePattern, segmentIdx))
-	finalBilingualSrtFile, err := os.Create(finalBilingualSrtFileName)
-	if err != nil {
-		return fmt.Errorf("audioToSubtitle generateTimestamps create bilingual srt file error: %w", err)
-	}
-	defer finalBilingualSrtFile.Close()
+	// 时间戳已对齐，再应用会改变分词的替换规则
+	replaceAlignedOrigin(stepParam.PhraseReplacer, srtBlocks, shortOriginSrtMap)
 
-	// 写入字幕文件
-	for _, srtBlock := range newSrtBlocks {
-		_, _ = finalBilingualSrtFile.WriteString(fmt.Sprintf("%d\n", srtBlock.Index))
-		_, _ = finalBilingualSrtFile.WriteString(srtBlock.Timestamp + "\n")
-		if stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop {
-			_, _ = finalBilingualSrtFile.WriteString(srtBlock.TargetLanguageSentence + "\n")
-			_, _ = finalBilingualSrtFile.WriteString(srtBlock.OriginLanguageSentence + "\n\n")
-		} else {
-			// on bottom 或者单语类型，都用on bottom
-			_, _ = finalBilingualSrtFile.WriteString(srtBlock.OriginLanguageSentence + "\n")
-			_, _ = finalBilingualSrtFile.WriteString(srtBlock.TargetLanguageSentence + "\n\n")
-		}
+	// 保存带时间戳的结构化字幕数据，合并后再生成双语字幕
+	segmentSubtitle := srtBlocksToSubtitle(newSrtBlocks, words, tsOffset, stepParam)
+	if err = subtitle.SaveJson(filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitSubtitleDataPersistenceFileNamePattern, segmentIdx)), segmentSubtitle); err != nil {
+		return fmt.Errorf("audioToSubtitle generateTimestamps save subtitle data error: %w", err)
 	}
 
 	// 保存带时间戳的字幕,长中文+短英文（示意，也支持其他语言）
@@ -1103,7 +1360,7 @@ func generateSrtWithTimestamps(srtBlocks []*util.SrtBlock, tsOffset float64, wor
 	if err != nil {
 		return fmt.Errorf("audioToSubtitle generateTimestamps create srtShortOriginFile err: %w", err)
 	}
-	defer srtShortOriginMixedFile.Close()
+	defer srtShortOriginFile.Close()
 
 	mixedSrtNum := 1
 	shortSrtNum := 1
@@ -1241,7 +1498,7 @@ func calcLength(text string) float64 {
 }
 
 // splitTranslateItem 根据字符权重和最大长度分割长句
-func (s Service) splitTranslateItem(items []*TranslatedItem) ([]*TranslatedItem, error) {
+func (s Service) splitTranslateItem(items []*TranslatedItem, prompts *types.PromptSet) ([]*TranslatedItem, error) {
 	var result []*TranslatedItem
 	maxLength := config.Conf.App.MaxSentenceLength + 30
 
@@ -1254,7 +1511,7 @@ func (s Service) splitTranslateItem(items []*TranslatedItem) ([]*TranslatedItem,
 
 		// 调用大模型进行分割
 		log.GetLogger().Info("splitTranslateItem long sentence detected, need split", zap.Any("item", item))
-		splitItems, err := s.splitLongSentence(item)
+		splitItems, err := s.splitLongSentence(item, prompts)
 		if err != nil {
 			log.GetLogger().Error("splitTranslateItem splitLongSentence error", zap.Error(err), zap.Any("item", item))
 			return nil, fmt.Errorf("split long sentence error: %w", err)
@@ -1266,12 +1523,13 @@ func (s Service) splitTranslateItem(items []*TranslatedItem) ([]*TranslatedItem,
 }
 
 // splitLongSentence 使用大模型分割长句并保持原文和译文对齐
-func (s Service) splitLongSentence(item *TranslatedItem) ([]*TranslatedItem, error) {
-	prompt := fmt.Sprintf(types.SplitLongSentencePrompt, item.OriginText, item.TranslatedText)
-
-	response, err := s.ChatCompleter.ChatCompletion(prompt)
+func (s Service) splitLongSentence(item *TranslatedItem, prompts *types.PromptSet) ([]*TranslatedItem, error) {
+	prompt, err := renderPrompt(prompts, types.PromptSplitLongSentence, types.PromptData{
+		Text:           item.OriginText,
+		TranslatedText: item.TranslatedText,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("chat completion error: %w", err)
+		return nil, fmt.Errorf("split long sentence render prompt error: %w", err)
 	}
 
 	var splitResult struct {
@@ -1280,9 +1538,12 @@ func (s Service) splitLongSentence(item *TranslatedItem) ([]*TranslatedItem, err
 			TranslatedPart string `json:"translated_part"`
 		} `json:"align"`
 	}
-	if err := json.Unmarshal([]byte(util.CleanMarkdownCodeBlock(response)), &splitResult); err != nil {
-		log.GetLogger().Error("splitLongSentence parse split result error", zap.Error(err), zap.Any("response", response))
-		return nil, fmt.Errorf("parse split result error: %w", err)
+	if err = s.chatJson(types.ChatPurposeAlign, prompts, prompt, types.SplitLongSentenceJsonSchema, &splitResult); err != nil {
+		log.GetLogger().Error("splitLongSentence chat error", zap.Error(err), zap.Any("item", item))
+		return nil, fmt.Errorf("split long sentence chat error: %w", err)
+	}
+	if len(splitResult.Align) == 0 {
+		return nil, fmt.Errorf("split long sentence empty result")
 	}
 
 	// 转换为TranslatedItem切片
@@ -1297,31 +1558,26 @@ func (s Service) splitLongSentence(item *TranslatedItem) ([]*TranslatedItem, err
 	return splitItems, nil
 }
 
-func (s Service) splitOriginLongSentence(sentence string) ([]string, error) {
-	prompt := fmt.Sprintf(types.SplitOriginLongSentencePrompt, sentence)
+func (s Service) splitOriginLongSentence(sentence string, prompts *types.PromptSet) ([]string, error) {
+	promptName := types.PromptSplitOriginLongSentence
 	if len(sentence) > 200 {
-		prompt = fmt.Sprintf(types.SplitLongTextByMeaningPrompt, sentence)
+		promptName = types.PromptSplitLongTextByMeaning
+	}
+	prompt, err := renderPrompt(prompts, promptName, types.PromptData{Text: sentence})
+	if err != nil {
+		return nil, fmt.Errorf("split origin long sentence render prompt error: %w", err)
 	}
 
-	var response string
-	var err error
 	shortSentences := make([]string, 0)
 	// 尝试调用3次
 	for i := range 3 {
-		response, err = s.ChatCompleter.ChatCompletion(prompt)
-		if err != nil {
-			log.GetLogger().Error("splitOriginLongSentence chat completion error", zap.Error(err), zap.String("sentence", sentence), zap.Any("time", i))
-			continue
-		}
 		var splitResult struct {
 			ShortSentences []struct {
 				Text string `json:"text"`
 			} `json:"short_sentences"`
 		}
-
-		cleanResponse := util.CleanMarkdownCodeBlock(response)
-		if err = json.Unmarshal([]byte(cleanResponse), &splitResult); err != nil {
-			log.GetLogger().Error("splitOriginLongSentence parse split result error", zap.Error(err), zap.Any("response", response))
+		if err = s.chatJson(types.ChatPurposeSplit, prompts, prompt, types.SplitOriginLongSentenceJsonSchema, &splitResult); err != nil {
+			log.GetLogger().Error("splitOriginLongSentence chat error", zap.Error(err), zap.String("sentence", sentence), zap.Any("time", i))
 			continue
 		}
 
@@ -1339,7 +1595,7 @@ func (s Service) splitOriginLongSentence(sentence string) ([]string, error) {
 }
 
 // splitSentenceRecursively 递归拆分句子，保持顺序
-func (s Service) splitSentenceRecursively(sentence string, depth int, maxDepth int) ([]string, error) {
+func (s Service) splitSentenceRecursively(sentence string, depth int, maxDepth int, prompts *types.PromptSet) ([]string, error) {
 	// 防止无限递归
 	if depth >= maxDepth {
 		log.GetLogger().Warn("reached max split depth", zap.Any("sentence", sentence), zap.Int("depth", depth))
@@ -1353,7 +1609,7 @@ func (s Service) splitSentenceRecursively(sentence string, depth int, maxDepth i
 
 	// 调用大模型进行分割
 	log.GetLogger().Info("use llm split origin long sentence", zap.Any("sentence", sentence), zap.Int("depth", depth))
-	splitItems, err := s.splitOriginLongSentence(sentence)
+	splitItems, err := s.splitOriginLongSentence(sentence, prompts)
 	if err != nil {
 		log.GetLogger().Error("splitSentenceRecursively splitLongSentence error", zap.Error(err), zap.Any("sentence", sentence), zap.Int("depth", depth))
 		return []string{sentence}, nil // 返回原句子而不是错误
@@ -1367,7 +1623,7 @@ func (s Service) splitSentenceRecursively(sentence string, depth int, maxDepth i
 	// 递归处理每个拆分结果，保持顺序
 	var result []string
 	for _, item := range splitItems {
-		subResults, err := s.splitSentenceRecursively(item, depth+1, maxDepth)
+		subResults, err := s.splitSentenceRecursively(item, depth+1, maxDepth, prompts)
 		if err != nil {
 			log.GetLogger().Error("splitSentenceRecursively recursive error", zap.Error(err), zap.Any("item", item), zap.Int("depth", depth))
 			result = append(result, item) // 如果递归失败，添加原项
@@ -61,7 +61,7 @@ func Test_splitOriginLongSentence(t *testing.T) {
 	testText := "then one more thing is search for file count file explorer note count is the name of the plug in install it and once enabled you can see that now I can see how many files are in each are inside each individual folder even the nested folders are showing properly now how many files are in them"
 	s := initService()
 	// 执行测试
-	splitTextSentences, err := s.splitOriginLongSentence(testText)
+	splitTextSentences, err := s.splitOriginLongSentence(testText, nil)
 	if err != nil {
 		t.Errorf("splitOriginLongSentence() error = %v, want nil", err)
 	}
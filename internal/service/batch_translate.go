@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const batchPreviousTranslationNum = 3 // 批量翻译时带上前一个窗口的几句译文作为上下文
+
+type batchSentence struct {
+	Id   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+type batchTranslateResult struct {
+	Translations []batchSentence `json:"translations"`
+	Summary      string          `json:"summary"`
+}
+
+// batchTranslate 对indexes指定的句子按窗口批量翻译，窗口之间传递滚动摘要，校验失败的窗口回退到逐句翻译
+func (s Service) batchTranslate(sentences []string, indexes []int, results []*TranslatedItem, references map[int]string, targetLang types.StandardLanguageCode, glossary *types.Glossary, enableGlossaryRetry bool, prompts *types.PromptSet) {
+	batchSize := config.Conf.App.TranslateBatchSize
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	var summary string
+	for start := 0; start < len(indexes); start += batchSize {
+		end := min(start+batchSize, len(indexes))
+		windowIndexes := indexes[start:end]
+		window := make([]string, len(windowIndexes))
+		var windowReferences []string
+		for i, index := range windowIndexes {
+			window[i] = sentences[index]
+			if reference, ok := references[index]; ok {
+				windowReferences = append(windowReferences, reference)
+			}
+		}
+
+		translations, newSummary, err := s.translateWindow(window, windowReferences, results[:windowIndexes[0]], summary, targetLang, glossary, prompts)
+		if err != nil {
+			log.GetLogger().Warn("batchTranslate window failed, fallback to sentence mode", zap.Int("start", windowIndexes[0]), zap.Int("end", windowIndexes[len(windowIndexes)-1]+1), zap.Error(err))
+			s.translateSentences(sentences, windowIndexes, results, references, targetLang, glossary, enableGlossaryRetry, prompts)
+			continue
+		}
+		if newSummary != "" {
+			summary = newSummary
+		}
+
+		// 术语检查，违反术语的句子按需逐句重新翻译
+		var retryIndexes []int
+		for i, translation := range translations {
+			index := windowIndexes[i]
+			violations := checkGlossary(glossary, sentences[index], translation)
+			results[index] = &TranslatedItem{
+				OriginText:         sentences[index],
+				TranslatedText:     translation,
+				GlossaryViolations: violations,
+			}
+			if len(violations) > 0 && enableGlossaryRetry {
+				retryIndexes = append(retryIndexes, index)
+			}
+		}
+		if len(retryIndexes) > 0 {
+			batchResults := make(map[int]*TranslatedItem, len(retryIndexes))
+			for _, index := range retryIndexes {
+				batchResults[index] = results[index]
+			}
+			s.translateSentences(sentences, retryIndexes, results, references, targetLang, glossary, enableGlossaryRetry, prompts)
+			for index, batchResult := range batchResults {
+				// 逐句翻译后仍然更差时保留批量翻译的结果
+				if len(results[index].GlossaryViolations) > len(batchResult.GlossaryViolations) || results[index].TranslatedText == results[index].OriginText {
+					results[index] = batchResult
+				}
+			}
+		}
+	}
+}
+
+// translateWindow 翻译一个窗口的句子，返回与输入一一对应的译文和更新后的摘要，references为窗口内句子的翻译记忆参考
+func (s Service) translateWindow(window []string, references []string, previous []*TranslatedItem, summary string, targetLang types.StandardLanguageCode, glossary *types.Glossary, prompts *types.PromptSet) ([]string, string, error) {
+	input := make([]batchSentence, len(window))
+	for i, sentence := range window {
+		input[i] = batchSentence{Id: i + 1, Text: sentence}
+	}
+	inputJson, err := json.Marshal(input)
+	if err != nil {
+		return nil, "", fmt.Errorf("translateWindow marshal input err: %w", err)
+	}
+
+	var previousTranslations []string
+	for i := max(len(previous)-batchPreviousTranslationNum, 0); i < len(previous); i++ {
+		if previous[i] != nil {
+			previousTranslations = append(previousTranslations, previous[i].OriginText+" => "+previous[i].TranslatedText)
+		}
+	}
+	if summary == "" {
+		summary = "(none)"
+	}
+	previousText := strings.Join(previousTranslations, "\n")
+	if previousText == "" {
+		previousText = "(none)"
+	}
+
+	prompt, err := renderPrompt(prompts, types.PromptBatchTranslate, types.PromptData{
+		TargetLanguage:       types.GetStandardLanguageName(targetLang),
+		Glossary:             glossaryPrompt(glossary, strings.Join(window, "\n")),
+		MemoryReference:      strings.Join(references, "\n"),
+		Summary:              summary,
+		PreviousTranslations: previousText,
+		Sentences:            string(inputJson),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("translateWindow render prompt err: %w", err)
+	}
+	var result batchTranslateResult
+	if err = s.chatJson(types.ChatPurposeTranslate, prompts, prompt, types.BatchTranslateJsonSchema, &result); err != nil {
+		return nil, "", fmt.Errorf("translateWindow llm err: %w", err)
+	}
+	// 校验数量和顺序
+	if len(result.Translations) != len(window) {
+		return nil, "", fmt.Errorf("translateWindow translation count mismatch, expect %d, got %d", len(window), len(result.Translations))
+	}
+	translations := make([]string, len(window))
+	for i, item := range result.Translations {
+		if item.Id != i+1 {
+			return nil, "", fmt.Errorf("translateWindow translation order mismatch, expect id %d, got %d", i+1, item.Id)
+		}
+		text := strings.TrimSpace(item.Text)
+		if text == "" {
+			return nil, "", fmt.Errorf("translateWindow empty translation for id %d", item.Id)
+		}
+		translations[i] = text
+	}
+	return translations, strings.TrimSpace(result.Summary), nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const batchTranslateMark = "[BATCH TRANSLATION TASK]"
+
+func TestTranslateWindow(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name     string
+		response string
+		err      error
+		want     []string
+		wantErr  bool
+	}{
+		{"ok", `{"translations":[{"id":1,"text":" 你好 "},{"id":2,"text":"世界"}],"summary":"问候"}`, nil, []string{"你好", "世界"}, false},
+		{"wrong count", `{"translations":[{"id":1,"text":"你好"}],"summary":""}`, nil, nil, true},
+		{"out of order", `{"translations":[{"id":2,"text":"世界"},{"id":1,"text":"你好"}],"summary":""}`, nil, nil, true},
+		{"empty item", `{"translations":[{"id":1,"text":"你好"},{"id":2,"text":"  "}],"summary":""}`, nil, nil, true},
+		{"llm error", "", errors.New("timeout"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Service{ChatCompleter: fakeChatCompleter(func(prompt string) (string, error) {
+				return tt.response, tt.err
+			})}
+			got, summary, err := s.translateWindow([]string{"Hello", "World"}, nil, nil, "", types.LanguageNameSimplifiedChinese, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("translateWindow() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("translateWindow() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr && summary != "问候" {
+				t.Errorf("summary = %q", summary)
+			}
+		})
+	}
+}
+
+func TestBatchTranslateFallback(t *testing.T) {
+	log.Logger = zap.NewNop()
+	oldBatchSize, oldParallel := config.Conf.App.TranslateBatchSize, config.Conf.App.TranslateParallelNum
+	config.Conf.App.TranslateBatchSize, config.Conf.App.TranslateParallelNum = 2, 2
+	defer func() {
+		config.Conf.App.TranslateBatchSize, config.Conf.App.TranslateParallelNum = oldBatchSize, oldParallel
+	}()
+
+	var (
+		mu         sync.Mutex
+		batchCalls int
+	)
+	s := Service{ChatCompleter: fakeChatCompleter(func(prompt string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Contains(prompt, batchTranslateMark) {
+			batchCalls++
+			// 每个窗口都少返回一句，校验失败
+			return `{"translations":[{"id":1,"text":"你好"}],"summary":""}`, nil
+		}
+		return "逐句", nil
+	})}
+	sentences := []string{"one", "two", "three", "four"}
+	results := make([]*TranslatedItem, len(sentences))
+	s.batchTranslate(sentences, []int{0, 1, 2, 3}, results, nil, types.LanguageNameSimplifiedChinese, nil, false, nil)
+
+	if batchCalls != 2 {
+		t.Errorf("batch calls = %d, want one per window", batchCalls)
+	}
+	for i, item := range results {
+		if item == nil || item.OriginText != sentences[i] || item.TranslatedText != "逐句" {
+			t.Errorf("results[%d] = %+v, want sentence mode translation", i, item)
+		}
+	}
+}
+
+func TestBatchTranslateGlossaryRetry(t *testing.T) {
+	log.Logger = zap.NewNop()
+	oldBatchSize, oldParallel := config.Conf.App.TranslateBatchSize, config.Conf.App.TranslateParallelNum
+	config.Conf.App.TranslateBatchSize, config.Conf.App.TranslateParallelNum = 20, 1
+	defer func() {
+		config.Conf.App.TranslateBatchSize, config.Conf.App.TranslateParallelNum = oldBatchSize, oldParallel
+	}()
+	glossary := &types.Glossary{Terms: []types.GlossaryTerm{{Source: "Krillin", Target: "克林"}}}
+
+	tests := []struct {
+		name          string
+		sentenceReply string
+		wantText      string
+		wantViolation bool
+	}{
+		{"retry fixes the term", "我是克林", "我是克林", false},
+		{"retry returns the origin", "I am Krillin", "我是库林", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Service{ChatCompleter: fakeChatCompleter(func(prompt string) (string, error) {
+				if strings.Contains(prompt, batchTranslateMark) {
+					return `{"translations":[{"id":1,"text":"我是库林"},{"id":2,"text":"你好"}],"summary":""}`, nil
+				}
+				return tt.sentenceReply, nil
+			})}
+			sentences := []string{"I am Krillin", "Hello"}
+			results := make([]*TranslatedItem, len(sentences))
+			s.batchTranslate(sentences, []int{0, 1}, results, nil, types.LanguageNameSimplifiedChinese, glossary, true, nil)
+
+			if results[0].TranslatedText != tt.wantText || (len(results[0].GlossaryViolations) > 0) != tt.wantViolation {
+				t.Errorf("results[0] = %+v", results[0])
+			}
+			if results[1].TranslatedText != "你好" {
+				t.Errorf("sentence without violations should keep the batch translation, got %q", results[1].TranslatedText)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
+	"krillin-ai/pkg/util"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// blockConfidence 计算时间范围内词的平均置信度，并找出低于阈值的词
+func blockConfidence(words []types.Word, start, end float64) (float64, []string) {
+	var (
+		sum      float64
+		count    int
+		lowWords []string
+	)
+	for _, word := range words {
+		if word.Confidence <= 0 || word.End <= start || word.Start >= end {
+			continue
+		}
+		sum += word.Confidence
+		count++
+		if word.Confidence < config.Conf.App.LowConfidenceThreshold {
+			lowWords = append(lowWords, word.Text)
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return sum / float64(count), lowWords
+}
+
+// generateConfidenceOutputs 根据结构化字幕的置信度生成审阅报告和可选的置信度json文件，序号与最终字幕一致
+func generateConfidenceOutputs(stepParam *types.SubtitleTaskStepParam) error {
+	sub, err := loadTaskSubtitle(stepParam)
+	if err != nil {
+		return fmt.Errorf("generateConfidenceOutputs loadTaskSubtitle err: %w", err)
+	}
+	all := make([]types.SubtitleConfidence, 0, len(sub.Cues))
+	hasConfidence := false
+	for _, cue := range sub.Cues {
+		all = append(all, types.SubtitleConfidence{
+			Index:              cue.Index,
+			Timestamp:          subtitle.FormatSrtTime(cue.Start) + " --> " + subtitle.FormatSrtTime(cue.End),
+			OriginText:         cue.OriginText,
+			TargetText:         cue.TranslatedText,
+			Confidence:         cue.Confidence,
+			LowConfidenceWords: cue.LowConfidenceWords,
+		})
+		if cue.Confidence > 0 {
+			hasConfidence = true
+		}
+	}
+	if !hasConfidence {
+		log.GetLogger().Info("generateConfidenceOutputs no confidence data, skip", zap.Any("taskId", stepParam.TaskId))
+		return nil
+	}
+
+	if config.Conf.App.EnableConfidenceSidecar {
+		sidecarFile := filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskConfidenceSidecarFileName)
+		if err := util.SaveToDisk(all, sidecarFile); err != nil {
+			return fmt.Errorf("generateConfidenceOutputs save sidecar err: %w", err)
+		}
+		stepParam.ConfidenceSidecarFilePath = sidecarFile
+	}
+
+	var report strings.Builder
+	lowCount := 0
+	for _, item := range all {
+		if item.Confidence <= 0 || (item.Confidence >= config.Conf.App.LowConfidenceThreshold && len(item.LowConfidenceWords) == 0) {
+			continue
+		}
+		lowCount++
+		report.WriteString(fmt.Sprintf("#%d  %s  confidence: %.2f\n", item.Index, item.Timestamp, item.Confidence))
+		report.WriteString(item.OriginText + "\n")
+		if item.TargetText != "" {
+			report.WriteString(item.TargetText + "\n")
+		}
+		if len(item.LowConfidenceWords) > 0 {
+			report.WriteString("low confidence words: " + strings.Join(item.LowConfidenceWords, ", ") + "\n")
+		}
+		report.WriteString("\n")
+	}
+	header := fmt.Sprintf("Low confidence lines: %d / %d (threshold %.2f)\n\n", lowCount, len(all), config.Conf.App.LowConfidenceThreshold)
+	reportFile := filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskReviewReportFileName)
+	if err := os.WriteFile(reportFile, []byte(header+report.String()), 0644); err != nil {
+		return fmt.Errorf("generateConfidenceOutputs write report err: %w", err)
+	}
+	stepParam.ReviewReportFilePath = reportFile
+	return nil
+}
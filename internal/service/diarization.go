@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/pkg/util"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// speakerDiarization 对完整音频只做一次说话人分离，各音频片段共用结果，保证同一说话人在不同片段中的id一致
+type speakerDiarization struct {
+	done     chan struct{}
+	segments []types.SpeakerSegment
+	err      error
+}
+
+// startDiarization 在后台对完整音频进行说话人分离，与切分和转录同时进行
+func (s Service) startDiarization(stepParam *types.SubtitleTaskStepParam) *speakerDiarization {
+	diarization := &speakerDiarization{done: make(chan struct{})}
+	go func() {
+		defer close(diarization.done)
+		diarization.segments, diarization.err = s.Diarizer.Diarization(stepParam.AudioFilePath, stepParam.TaskBasePath)
+		if diarization.err == nil {
+			_ = util.SaveToDisk(diarization.segments, filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskDiarizationFileName))
+		}
+	}()
+	return diarization
+}
+
+// assign 等待说话人分离完成，按音频片段在完整音频中的起始时间把说话人标注到该片段的每个词上
+func (d *speakerDiarization) assign(ctx context.Context, words []types.Word, offset float64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.done:
+	}
+	if d.err != nil {
+		return fmt.Errorf("speakerDiarization Diarization err: %w", d.err)
+	}
+	assignSpeakers(words, d.segments, offset)
+	return nil
+}
+
+// assignSpeakers 把每个词分配给与其时间重叠最多的说话人片段，没有重叠时取最近的片段
+// 词的时间相对于音频片段，offset为音频片段在完整音频中的起始时间
+func assignSpeakers(words []types.Word, segments []types.SpeakerSegment, offset float64) {
+	if len(segments) == 0 {
+		return
+	}
+	for i := range words {
+		var (
+			bestSpeaker  string
+			bestOverlap  float64
+			nearestDist  = math.MaxFloat64
+			nearestLabel string
+		)
+		start, end := words[i].Start+offset, words[i].End+offset
+		mid := (start + end) / 2
+		for _, segment := range segments {
+			overlap := math.Min(end, segment.End) - math.Max(start, segment.Start)
+			if overlap > bestOverlap {
+				bestOverlap = overlap
+				bestSpeaker = segment.Speaker
+			}
+			dist := math.Min(math.Abs(mid-segment.Start), math.Abs(mid-segment.End))
+			if mid >= segment.Start && mid <= segment.End {
+				dist = 0
+			}
+			if dist < nearestDist {
+				nearestDist = dist
+				nearestLabel = segment.Speaker
+			}
+		}
+		if bestSpeaker == "" {
+			bestSpeaker = nearestLabel
+		}
+		words[i].Speaker = bestSpeaker
+	}
+}
+
+// dominantSpeaker 返回时间范围内说话时长最多的说话人
+func dominantSpeaker(words []types.Word, start, end float64) string {
+	durations := make(map[string]float64)
+	for _, word := range words {
+		if word.Speaker == "" || word.End <= start || word.Start >= end {
+			continue
+		}
+		durations[word.Speaker] += math.Min(word.End, end) - math.Max(word.Start, start)
+	}
+	var (
+		speaker string
+		maxDur  = -1.0
+	)
+	for s, d := range durations {
+		// 时长相同时取id较小的，保证结果稳定
+		if d > maxDur || (d == maxDur && s < speaker) {
+			speaker = s
+			maxDur = d
+		}
+	}
+	return speaker
+}
+
+// speakerDisplayName 根据任务配置的名称映射得到说话人显示名称
+func speakerDisplayName(speaker string, nameMap map[string]string) string {
+	if name, ok := nameMap[speaker]; ok && name != "" {
+		return name
+	}
+	return speaker
+}
+
+func addSpeakerPrefix(text, name string) string {
+	if name == "" || text == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s] %s", name, text)
+}
+
+// buildSpeakerAssHeader 在ass头部为每个说话人复制一份Major/Minor样式，只修改主颜色
+func buildSpeakerAssHeader(header string, speakers []string) string {
+	if len(speakers) == 0 {
+		return header
+	}
+	var styleLines []string
+	for _, line := range strings.Split(header, "\n") {
+		if strings.HasPrefix(line, "Style: Major,") || strings.HasPrefix(line, "Style: Minor,") {
+			styleLines = append(styleLines, line)
+		}
+	}
+
+	var extraStyles strings.Builder
+	for i := range speakers {
+		color := types.SpeakerAssColors[i%len(types.SpeakerAssColors)]
+		for _, line := range styleLines {
+			fields := strings.Split(strings.TrimPrefix(line, "Style: "), ",")
+			fields[0] = fmt.Sprintf("%s%d", fields[0], i+1)
+			fields[3] = color
+			extraStyles.WriteString("Style: " + strings.Join(fields, ",") + "\n")
+		}
+	}
+
+	lastStyle := styleLines[len(styleLines)-1]
+	return strings.Replace(header, lastStyle+"\n", lastStyle+"\n"+extraStyles.String(), 1)
+}
+
+// speakerAssStyleSuffix 返回说话人对应的样式后缀，如Major1中的1
+func speakerAssStyleSuffix(speaker string, speakers []string) string {
+	for i, s := range speakers {
+		if s == speaker {
+			return fmt.Sprintf("%d", i+1)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"testing"
+)
+
+func TestAssignSpeakers(t *testing.T) {
+	segments := []types.SpeakerSegment{
+		{Speaker: "SPEAKER_00", Start: 0, End: 65},
+		{Speaker: "SPEAKER_01", Start: 65, End: 130},
+	}
+	// 第二个音频片段从60秒开始，词的时间相对于片段
+	words := []types.Word{
+		{Text: "hello", Start: 1, End: 2},
+		{Text: "world", Start: 10, End: 11},
+		{Text: "gap", Start: 80, End: 81},
+	}
+	assignSpeakers(words, segments, 60)
+	want := []string{"SPEAKER_00", "SPEAKER_01", "SPEAKER_01"}
+	for i, word := range words {
+		if word.Speaker != want[i] {
+			t.Errorf("word %s: got %s, want %s", word.Text, word.Speaker, want[i])
+		}
+	}
+}
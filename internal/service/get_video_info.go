@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"go.uber.org/zap"
 	"krillin-ai/config"
 	"krillin-ai/internal/storage"
@@ -50,8 +49,14 @@ func (s Service) getVideoInfo(ctx context.Context, stepParam *types.SubtitleTask
 		description = string(output)
 		log.GetLogger().Debug("getVideoInfo title and description", zap.String("title", title), zap.String("description", description))
 		// 翻译
-		var result string
-		result, err = s.ChatCompleter.ChatCompletion(fmt.Sprintf(types.TranslateVideoTitleAndDescriptionPrompt, types.GetStandardLanguageName(stepParam.TargetLanguage), title+"####"+description))
+		var result, prompt string
+		prompt, err = renderPrompt(stepParam.Prompts, types.PromptTranslateTitle, types.PromptData{
+			TargetLanguage: types.GetStandardLanguageName(stepParam.TargetLanguage),
+			Text:           title + "####" + description,
+		})
+		if err == nil {
+			result, err = s.chat(types.ChatPurposeTitle, prompt)
+		}
 		if err != nil {
 			log.GetLogger().Error("getVideoInfo openai chat completion error", zap.Any("stepParam", stepParam), zap.Error(err))
 		}
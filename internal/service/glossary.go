@@ -0,0 +1,241 @@
+package service
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"krillin-ai/internal/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const glossaryDir = "./glossaries"
+
+// ParseGlossaryFile 解析csv/tsv术语表，每行为 原文术语,译文术语。译文为空或与原文相同的视为不翻译的词
+func ParseGlossaryFile(path string) (*types.Glossary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ParseGlossaryFile open file err: %w", err)
+	}
+	defer file.Close()
+	return parseGlossary(file, strings.EqualFold(filepath.Ext(path), ".tsv"))
+}
+
+func parseGlossary(r io.Reader, tsv bool) (*types.Glossary, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if tsv {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	glossary := &types.Glossary{}
+	for lineNum := 1; ; lineNum++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parseGlossary read line %d err: %w", lineNum, err)
+		}
+		if len(record) == 0 {
+			continue
+		}
+		source := strings.TrimPrefix(strings.TrimSpace(record[0]), "\ufeff")
+		var target string
+		if len(record) > 1 {
+			target = strings.TrimSpace(record[1])
+		}
+		// 跳过空行、注释和表头
+		if source == "" || strings.HasPrefix(source, "#") || (lineNum == 1 && strings.EqualFold(source, "source")) {
+			continue
+		}
+		if target == "" || target == source {
+			glossary.DoNotTranslate = append(glossary.DoNotTranslate, source)
+		} else {
+			glossary.Terms = append(glossary.Terms, types.GlossaryTerm{Source: source, Target: target})
+		}
+	}
+	return glossary, nil
+}
+
+// mergeGlossaries 合并多个术语表，同一原文术语以后出现的为准
+func mergeGlossaries(glossaries ...*types.Glossary) *types.Glossary {
+	merged := &types.Glossary{}
+	termIndex := make(map[string]int)
+	dntSet := make(map[string]struct{})
+	for _, g := range glossaries {
+		if g == nil {
+			continue
+		}
+		for _, term := range g.Terms {
+			key := strings.ToLower(term.Source)
+			if i, ok := termIndex[key]; ok {
+				merged.Terms[i] = term
+				continue
+			}
+			termIndex[key] = len(merged.Terms)
+			merged.Terms = append(merged.Terms, term)
+		}
+		for _, word := range g.DoNotTranslate {
+			if _, ok := dntSet[strings.ToLower(word)]; ok {
+				continue
+			}
+			dntSet[strings.ToLower(word)] = struct{}{}
+			merged.DoNotTranslate = append(merged.DoNotTranslate, word)
+		}
+	}
+	return merged
+}
+
+var glossaryStore = namedStore[types.Glossary]{
+	dir:    glossaryDir,
+	label:  "术语表",
+	nameOf: func(glossary *types.Glossary) string { return glossary.Name },
+}
+
+func (s Service) SaveGlossary(glossary *types.Glossary) error {
+	return glossaryStore.save(glossary)
+}
+
+func (s Service) LoadGlossary(name string) (*types.Glossary, error) {
+	return glossaryStore.load(name)
+}
+
+func (s Service) ListGlossaries() ([]*types.Glossary, error) {
+	return glossaryStore.list()
+}
+
+func (s Service) DeleteGlossary(name string) error {
+	return glossaryStore.delete(name)
+}
+
+// termPatterns 按术语缓存编译好的整词匹配正则，每个术语只编译一次
+var termPatterns sync.Map
+
+// containsTerm 判断文本中是否包含术语，字母类术语按整词匹配，忽略大小写
+func containsTerm(text, term string) bool {
+	if term == "" {
+		return false
+	}
+	runes := []rune(term)
+	if isWordRune(runes[0]) || isWordRune(runes[len(runes)-1]) {
+		if pattern, ok := termPatterns.Load(term); ok {
+			return pattern.(*regexp.Regexp).MatchString(text)
+		}
+		pattern := regexp.QuoteMeta(term)
+		if isWordRune(runes[0]) {
+			pattern = `\b` + pattern
+		}
+		if isWordRune(runes[len(runes)-1]) {
+			pattern += `\b`
+		}
+		re := regexp.MustCompile(`(?i)` + pattern)
+		termPatterns.Store(term, re)
+		return re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(term))
+}
+
+// 只有ASCII字母数字才需要按整词匹配，中文等没有词边界
+func isWordRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// matchGlossary 找出句子中出现的术语和不翻译的词
+func matchGlossary(glossary *types.Glossary, sentence string) ([]types.GlossaryTerm, []string) {
+	if glossary.IsEmpty() {
+		return nil, nil
+	}
+	var (
+		terms []types.GlossaryTerm
+		dnt   []string
+	)
+	for _, term := range glossary.Terms {
+		if containsTerm(sentence, term.Source) {
+			terms = append(terms, term)
+		}
+	}
+	for _, word := range glossary.DoNotTranslate {
+		if containsTerm(sentence, word) {
+			dnt = append(dnt, word)
+		}
+	}
+	return terms, dnt
+}
+
+// glossaryPrompt 生成注入翻译提示词的术语部分，句子中没有术语时返回空
+func glossaryPrompt(glossary *types.Glossary, sentence string) string {
+	terms, dnt := matchGlossary(glossary, sentence)
+	if len(terms) == 0 && len(dnt) == 0 {
+		return ""
+	}
+	var lines []string
+	for _, term := range terms {
+		lines = append(lines, fmt.Sprintf(`- "%s" MUST be translated as "%s"`, term.Source, term.Target))
+	}
+	for _, word := range dnt {
+		lines = append(lines, fmt.Sprintf(`- "%s" MUST be kept as is, do NOT translate it`, word))
+	}
+	return fmt.Sprintf(types.GlossaryPromptSection, strings.Join(lines, "\n")) + "\n"
+}
+
+// checkGlossary 检查译文是否遵守了术语表，返回违反的条目描述
+func checkGlossary(glossary *types.Glossary, originText, translatedText string) []string {
+	terms, dnt := matchGlossary(glossary, originText)
+	var violations []string
+	for _, term := range terms {
+		if !strings.Contains(strings.ToLower(translatedText), strings.ToLower(term.Target)) {
+			violations = append(violations, fmt.Sprintf(`"%s" -> "%s"`, term.Source, term.Target))
+		}
+	}
+	for _, word := range dnt {
+		if !strings.Contains(strings.ToLower(translatedText), strings.ToLower(word)) {
+			violations = append(violations, fmt.Sprintf(`"%s" (do not translate)`, word))
+		}
+	}
+	return violations
+}
+
+// 把片段中仍然违反术语表的翻译写入该片段的检查文件，便于人工复核，重新翻译时覆盖之前的结果
+func writeGlossaryViolations(basePath string, segmentIdx int, items []*TranslatedItem) error {
+	var builder strings.Builder
+	for _, item := range items {
+		if item == nil || len(item.GlossaryViolations) == 0 {
+			continue
+		}
+		builder.WriteString(item.OriginText + "\n")
+		builder.WriteString(item.TranslatedText + "\n")
+		builder.WriteString("violations: " + strings.Join(item.GlossaryViolations, ", ") + "\n\n")
+	}
+	filePath := filepath.Join(basePath, fmt.Sprintf(types.SubtitleTaskGlossaryCheckFileNamePattern, segmentIdx))
+	if builder.Len() == 0 {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(filePath, []byte(builder.String()), 0644)
+}
+
+// uploadedFilePath 把上传接口返回的local:./uploads/xxx转换为本地路径，只允许uploads目录下的文件
+func uploadedFilePath(path string) (string, error) {
+	path = filepath.Clean(strings.TrimPrefix(path, "local:"))
+	uploadDir, err := filepath.Abs(types.UploadDir)
+	if err != nil {
+		return "", fmt.Errorf("uploadedFilePath abs upload dir err: %w", err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("uploadedFilePath abs err: %w", err)
+	}
+	rel, err := filepath.Rel(uploadDir, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("只能使用上传接口返回的文件: %s", path)
+	}
+	return absPath, nil
+}
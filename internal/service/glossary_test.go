@@ -0,0 +1,105 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGlossary(t *testing.T) {
+	csvData := "\ufeffsource,target\n# comment\nKrillin, 克林\n\nGitHub,GitHub\n\"Open, AI\",OpenAI公司\nSora\n"
+	glossary, err := parseGlossary(strings.NewReader(csvData), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTerms := []types.GlossaryTerm{{Source: "Krillin", Target: "克林"}, {Source: "Open, AI", Target: "OpenAI公司"}}
+	if !reflect.DeepEqual(glossary.Terms, wantTerms) {
+		t.Errorf("terms: got %v, want %v", glossary.Terms, wantTerms)
+	}
+	if !reflect.DeepEqual(glossary.DoNotTranslate, []string{"GitHub", "Sora"}) {
+		t.Errorf("do not translate: got %v", glossary.DoNotTranslate)
+	}
+
+	glossary, err = parseGlossary(strings.NewReader("llm\t大模型\n\"quoted\tterm\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(glossary.Terms) != 1 || glossary.Terms[0].Target != "大模型" || len(glossary.DoNotTranslate) != 1 {
+		t.Errorf("unexpected tsv glossary %+v", glossary)
+	}
+}
+
+func TestMergeGlossaries(t *testing.T) {
+	merged := mergeGlossaries(
+		&types.Glossary{Terms: []types.GlossaryTerm{{Source: "AI", Target: "人工智能"}}, DoNotTranslate: []string{"GitHub"}},
+		nil,
+		&types.Glossary{Terms: []types.GlossaryTerm{{Source: "ai", Target: "AI"}}, DoNotTranslate: []string{"github"}},
+	)
+	if len(merged.Terms) != 1 || merged.Terms[0].Target != "AI" || len(merged.DoNotTranslate) != 1 {
+		t.Errorf("unexpected merged glossary %+v", merged)
+	}
+}
+
+func TestCheckGlossary(t *testing.T) {
+	glossary := &types.Glossary{
+		Terms:          []types.GlossaryTerm{{Source: "cat", Target: "猫"}, {Source: "C++", Target: "C++语言"}, {Source: "大模型", Target: "LLM"}},
+		DoNotTranslate: []string{"Krillin"},
+	}
+	tests := []struct {
+		name       string
+		origin     string
+		translated string
+		want       []string
+	}{
+		{"followed", "The cat likes Krillin", "这只猫喜欢Krillin", nil},
+		{"whole word only", "category of concat", "类别", nil},
+		{"term violated", "A CAT is here", "这里有一只狗", []string{`"cat" -> "猫"`}},
+		{"do not translate violated", "krillin is here", "克林在这里", []string{`"Krillin" (do not translate)`}},
+		{"symbol term", "I write C++ daily", "我每天写C++语言", nil},
+		{"cjk term", "这个大模型很好", "This model is good", []string{`"大模型" -> "LLM"`}},
+	}
+	for _, tt := range tests {
+		if got := checkGlossary(glossary, tt.origin, tt.translated); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteGlossaryViolations(t *testing.T) {
+	dir := t.TempDir()
+	items := []*TranslatedItem{{OriginText: "cat", TranslatedText: "狗", GlossaryViolations: []string{`"cat" -> "猫"`}}}
+	// 重复写入同一片段时覆盖而不是追加
+	for range 2 {
+		if err := writeGlossaryViolations(dir, 0, items); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePath := filepath.Join(dir, "glossary_check_0.txt")
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "violations:") != 1 {
+		t.Errorf("expected one violation entry, got %q", data)
+	}
+	if err = writeGlossaryViolations(dir, 0, []*TranslatedItem{{OriginText: "cat", TranslatedText: "猫"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("expected stale check file to be removed")
+	}
+}
+
+func TestUploadedFilePath(t *testing.T) {
+	if _, err := uploadedFilePath("local:./uploads/terms.csv"); err != nil {
+		t.Errorf("expected upload path to be accepted: %v", err)
+	}
+	for _, path := range []string{"local:./uploads/../config/config.toml", "/etc/passwd", "local:./uploads", "local:uploads_other/a.csv"} {
+		if _, err := uploadedFilePath(path); err == nil {
+			t.Errorf("expected %s to be rejected", path)
+		}
+	}
+}
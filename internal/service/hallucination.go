@@ -0,0 +1,293 @@
+package service
+
+import (
+	"fmt"
+	"krillin-ai/internal/storage"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/util"
+	"math"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const (
+	REPETITION_MAX_NGRAM       = 8   // 检测的最长重复短语词数
+	REPETITION_MIN_REPEATS     = 4   // 短语连续重复达到该次数视为循环
+	REPETITION_MIN_WORD_REPEAT = 8   // 单个词连续重复达到该次数视为循环
+	MAX_WORD_DURATION          = 4.0 // 单个词的最大合理时长，单位秒
+	IMPLAUSIBLE_MIN_RUN        = 3   // 连续多少个时长异常的词视为幻觉
+	SILENT_MIN_RUN             = 3   // 连续多少个落在静音上的词视为幻觉
+	RETRY_MIN_KEPT_RATIO       = 0.9 // 重新转录保留的文字长度不低于原结果的该比例才采用，避免漏掉内容的结果胜出
+)
+
+const (
+	HallucinationReasonRepetition = "repetition"
+	HallucinationReasonDuration   = "duration"
+	HallucinationReasonSilence    = "silence"
+)
+
+// 幻觉区间，对应Words的下标[Begin, End)
+type hallucinationSpan struct {
+	Begin  int
+	End    int
+	Reason string
+}
+
+// 检测转录结果中的幻觉：短语循环重复、时长不合理的词、静音上的文本
+func detectHallucinations(audioFile string, data *types.TranscriptionData) ([]hallucinationSpan, error) {
+	if data == nil || len(data.Words) == 0 {
+		return nil, nil
+	}
+	spans := detectRepetitionLoops(data.Words)
+	spans = append(spans, detectImplausibleDurations(data.Words)...)
+
+	energies, _, err := computeFrameFeatures(audioFile)
+	if err != nil {
+		return nil, fmt.Errorf("detectHallucinations computeFrameFeatures err: %w", err)
+	}
+	spans = append(spans, detectTextOnSilence(data.Words, energies)...)
+	return spans, nil
+}
+
+func normalizeWord(text string) string {
+	return strings.ToLower(util.CleanPunction(strings.TrimSpace(text)))
+}
+
+// 检测连续重复的n-gram，保留第一次出现，其余重复部分视为幻觉
+func detectRepetitionLoops(words []types.Word) []hallucinationSpan {
+	normalized := make([]string, len(words))
+	for i, w := range words {
+		normalized[i] = normalizeWord(w.Text)
+	}
+	equal := func(a, b, n int) bool {
+		for k := range n {
+			if normalized[a+k] != normalized[b+k] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var spans []hallucinationSpan
+	for i := 0; i < len(words); {
+		// 同一起点取覆盖范围最长的重复
+		bestN, bestEnd := 0, 0
+		for n := 1; n <= REPETITION_MAX_NGRAM && i+n <= len(words); n++ {
+			repeats := 1
+			for i+(repeats+1)*n <= len(words) && equal(i, i+repeats*n, n) {
+				repeats++
+			}
+			minRepeats := REPETITION_MIN_REPEATS
+			if n == 1 {
+				minRepeats = REPETITION_MIN_WORD_REPEAT
+			}
+			if repeats >= minRepeats && i+repeats*n > bestEnd {
+				bestN, bestEnd = n, i+repeats*n
+			}
+		}
+		if bestN == 0 {
+			i++
+			continue
+		}
+		spans = append(spans, hallucinationSpan{Begin: i + bestN, End: bestEnd, Reason: HallucinationReasonRepetition})
+		i = bestEnd
+	}
+	return spans
+}
+
+// 检测连续出现的时长异常的词，例如时长为0或者一个词持续好几秒
+func detectImplausibleDurations(words []types.Word) []hallucinationSpan {
+	implausible := func(w types.Word) bool {
+		duration := w.End - w.Start
+		return duration <= 0 || duration > MAX_WORD_DURATION
+	}
+	return collectRuns(len(words), IMPLAUSIBLE_MIN_RUN, func(i int) bool { return implausible(words[i]) }, HallucinationReasonDuration)
+}
+
+// 检测落在近乎静音音频上的文本
+func detectTextOnSilence(words []types.Word, energies []float64) []hallucinationSpan {
+	if len(energies) == 0 {
+		return nil
+	}
+	silenceThreshold := math.Max(VAD_MIN_THRESHOLD_DB, estimateNoiseFloor(energies)+VAD_UNVOICED_MARGIN_DB)
+	silent := func(i int) bool {
+		from := max(int(words[i].Start/VAD_FRAME_DURATION), 0)
+		to := min(int(math.Ceil(words[i].End/VAD_FRAME_DURATION)), len(energies))
+		if from >= to {
+			return false
+		}
+		peak := math.Inf(-1)
+		for _, e := range energies[from:to] {
+			peak = math.Max(peak, e)
+		}
+		return peak < silenceThreshold
+	}
+	return collectRuns(len(words), SILENT_MIN_RUN, silent, HallucinationReasonSilence)
+}
+
+// 把连续满足条件且长度不小于minRun的词区间收集起来
+func collectRuns(n, minRun int, match func(i int) bool, reason string) []hallucinationSpan {
+	var spans []hallucinationSpan
+	runStart := -1
+	for i := 0; i <= n; i++ {
+		if i < n && match(i) {
+			if runStart < 0 {
+				runStart = i
+			}
+			continue
+		}
+		if runStart >= 0 && i-runStart >= minRun {
+			spans = append(spans, hallucinationSpan{Begin: runStart, End: i, Reason: reason})
+		}
+		runStart = -1
+	}
+	return spans
+}
+
+func hallucinatedWordCount(spans []hallucinationSpan, wordNum int) int {
+	marked := make([]bool, wordNum)
+	count := 0
+	for _, span := range spans {
+		for i := span.Begin; i < span.End; i++ {
+			if !marked[i] {
+				marked[i] = true
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// keptTextLength 删除幻觉区间后剩余的词的字符数
+func keptTextLength(words []types.Word, spans []hallucinationSpan) int {
+	drop := make([]bool, len(words))
+	for _, span := range spans {
+		for i := span.Begin; i < span.End; i++ {
+			drop[i] = true
+		}
+	}
+	length := 0
+	for i, w := range words {
+		if !drop[i] {
+			length += utf8.RuneCountInString(normalizeWord(w.Text))
+		}
+	}
+	return length
+}
+
+// preferRetryTranscription 重新转录的幻觉词更少，且删除幻觉后保留的文字没有明显变少时才采用
+func preferRetryTranscription(words []types.Word, spans []hallucinationSpan, retryWords []types.Word, retrySpans []hallucinationSpan) bool {
+	if hallucinatedWordCount(retrySpans, len(retryWords)) >= hallucinatedWordCount(spans, len(words)) {
+		return false
+	}
+	return float64(keptTextLength(retryWords, retrySpans)) >= float64(keptTextLength(words, spans))*RETRY_MIN_KEPT_RATIO
+}
+
+// 删除幻觉区间内的词，并同步删除Text中对应的内容
+func dropHallucinatedWords(data *types.TranscriptionData, spans []hallucinationSpan) {
+	if len(spans) == 0 {
+		return
+	}
+	drop := make([]bool, len(data.Words))
+	for _, span := range spans {
+		for i := span.Begin; i < span.End; i++ {
+			drop[i] = true
+		}
+	}
+
+	// 定位每个词在Text中的位置，定位失败时退回到用剩余的词重新拼接Text
+	offsets := make([]int, len(data.Words)+1)
+	lowerText := strings.ToLower(data.Text)
+	located := len(lowerText) == len(data.Text)
+	cursor := 0
+	for i, w := range data.Words {
+		if !located {
+			break
+		}
+		word := strings.ToLower(strings.TrimSpace(w.Text))
+		idx := strings.Index(lowerText[cursor:], word)
+		if word == "" || idx < 0 {
+			located = false
+			break
+		}
+		offsets[i] = cursor + idx
+		cursor += idx + len(word)
+	}
+	offsets[len(data.Words)] = len(data.Text)
+
+	var (
+		textBuilder strings.Builder
+		keptWords   []types.Word
+		textWords   []string
+	)
+	for i, w := range data.Words {
+		if drop[i] {
+			continue
+		}
+		if located {
+			textBuilder.WriteString(data.Text[offsets[i]:offsets[i+1]])
+		}
+		w.Num = len(keptWords)
+		keptWords = append(keptWords, w)
+		textWords = append(textWords, w.Text)
+	}
+	if located {
+		// 保留第一个词之前的内容，比如开头的空格
+		data.Text = data.Text[:offsets[0]] + textBuilder.String()
+	} else if util.ContainsAlphabetic(data.Text) {
+		data.Text = strings.Join(textWords, " ")
+	} else {
+		data.Text = strings.Join(textWords, "")
+	}
+	data.Words = keptWords
+}
+
+// 对音频做降噪后生成新的文件，用于幻觉出现时重新转录
+func denoiseAudio(audioFile string) (string, error) {
+	output := util.AddSuffixToFileName(audioFile, "_denoise")
+	cmd := exec.Command(storage.FfmpegPath, "-y", "-i", audioFile, "-af", "highpass=f=80,afftdn=nf=-25", output)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.GetLogger().Error("denoiseAudio ffmpeg err", zap.String("output", string(out)), zap.Error(err))
+		return "", fmt.Errorf("denoiseAudio ffmpeg err: %w", err)
+	}
+	return output, nil
+}
+
+// 检测并处理转录幻觉：先用降噪后的音频重新转录一次，取幻觉更少的结果，再删除仍然存在的幻觉内容
+func (s Service) filterHallucinations(id int, audioFile, language, taskBasePath string, options types.TranscriptionOptions, data *types.TranscriptionData) (*types.TranscriptionData, error) {
+	spans, err := detectHallucinations(audioFile, data)
+	if err != nil {
+		return data, err
+	}
+	if len(spans) == 0 {
+		return data, nil
+	}
+	count := hallucinatedWordCount(spans, len(data.Words))
+	log.GetLogger().Warn("filterHallucinations hallucination detected", zap.Int("splitId", id), zap.Int("hallucinatedWords", count), zap.Any("spans", spans))
+
+	denoisedFile, err := denoiseAudio(audioFile)
+	if err == nil {
+		retryData, retryErr := s.transcribeAudio(id, denoisedFile, language, taskBasePath, options)
+		if retryErr == nil {
+			// 时间轴不变，仍以原音频的能量判断静音
+			retrySpans, detectErr := detectHallucinations(audioFile, retryData)
+			if detectErr == nil && len(retryData.Words) > 0 {
+				log.GetLogger().Info("filterHallucinations retranscribed", zap.Int("splitId", id), zap.Int("hallucinatedWords", hallucinatedWordCount(retrySpans, len(retryData.Words))))
+				if preferRetryTranscription(data.Words, spans, retryData.Words, retrySpans) {
+					data, spans = retryData, retrySpans
+				}
+			}
+		} else {
+			log.GetLogger().Warn("filterHallucinations retranscribe err", zap.Int("splitId", id), zap.Error(retryErr))
+		}
+	} else {
+		log.GetLogger().Warn("filterHallucinations denoiseAudio err", zap.Int("splitId", id), zap.Error(err))
+	}
+
+	dropHallucinatedWords(data, spans)
+	return data, nil
+}
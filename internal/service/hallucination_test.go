@@ -0,0 +1,112 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 按空格拆分的词，每个词0.5秒
+func testWords(text string) []types.Word {
+	var words []types.Word
+	for i, w := range strings.Fields(text) {
+		words = append(words, types.Word{Num: i, Text: w, Start: float64(i) * 0.5, End: float64(i)*0.5 + 0.4})
+	}
+	return words
+}
+
+func TestDetectRepetitionLoops(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []hallucinationSpan
+	}{
+		{"normal speech", "I think that that is fine and we should go", nil},
+		{"phrase loop", "hello thank you thank you thank you thank you bye", []hallucinationSpan{{3, 9, HallucinationReasonRepetition}}},
+		{"phrase repeated below threshold", "thank you thank you thank you", nil},
+		{"word loop", "no no no no no no no no no", []hallucinationSpan{{1, 9, HallucinationReasonRepetition}}},
+		{"case and punctuation ignored", "Go. go go, GO go go go go!", []hallucinationSpan{{1, 8, HallucinationReasonRepetition}}},
+	}
+	for _, tt := range tests {
+		if got := detectRepetitionLoops(testWords(tt.text)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectImplausibleDurations(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []float64
+		want      []hallucinationSpan
+	}{
+		{"normal", []float64{0.3, 0.5, 1, 0.2}, nil},
+		{"short run ignored", []float64{0.3, 0, 0, 0.4}, nil},
+		{"zero duration run", []float64{0.3, 0, 0, 0, 0.4}, []hallucinationSpan{{1, 4, HallucinationReasonDuration}}},
+		{"long words", []float64{5, 6, 10}, []hallucinationSpan{{0, 3, HallucinationReasonDuration}}},
+	}
+	for _, tt := range tests {
+		var words []types.Word
+		for i, d := range tt.durations {
+			words = append(words, types.Word{Text: "w", Start: float64(i), End: float64(i) + d})
+		}
+		if got := detectImplausibleDurations(words); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectTextOnSilence(t *testing.T) {
+	// 前3秒为语音，之后为静音
+	energies := make([]float64, int(6/VAD_FRAME_DURATION))
+	for i := range energies {
+		if float64(i)*VAD_FRAME_DURATION < 3 {
+			energies[i] = -20
+		} else {
+			energies[i] = -80
+		}
+	}
+	words := testWords("one two three four five six seven eight nine ten")
+	got := detectTextOnSilence(words, energies)
+	// 从3秒开始的第7到10个词落在静音上
+	want := []hallucinationSpan{{6, 10, HallucinationReasonSilence}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got = detectTextOnSilence(words, nil); got != nil {
+		t.Errorf("expected no spans without energies, got %v", got)
+	}
+}
+
+func TestDropHallucinatedWords(t *testing.T) {
+	data := &types.TranscriptionData{Text: " Hello, thank you thank you. Bye!", Words: testWords("Hello thank you thank you Bye")}
+	dropHallucinatedWords(data, []hallucinationSpan{{3, 5, HallucinationReasonRepetition}})
+	if data.Text != " Hello, thank you Bye!" {
+		t.Errorf("unexpected text %q", data.Text)
+	}
+	if len(data.Words) != 4 || data.Words[3].Text != "Bye" || data.Words[3].Num != 3 {
+		t.Errorf("unexpected words %v", data.Words)
+	}
+}
+
+func TestPreferRetryTranscription(t *testing.T) {
+	words := testWords("a b c d e f g h i j")
+	tests := []struct {
+		name       string
+		retryWords []types.Word
+		retrySpans []hallucinationSpan
+		want       bool
+	}{
+		{"fewer hallucinations same text", testWords("a b c d e f g h"), nil, true},
+		{"more hallucinations", testWords("a b c d e f g h"), []hallucinationSpan{{0, 4, HallucinationReasonRepetition}}, false},
+		{"fewer hallucinations but much less text", testWords("a b"), nil, false},
+	}
+	// 原结果有3个幻觉词，保留7个字符
+	spans := []hallucinationSpan{{7, 10, HallucinationReasonSilence}}
+	for _, tt := range tests {
+		if got := preferRetryTranscription(words, spans, tt.retryWords, tt.retrySpans); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -5,13 +5,21 @@ import (
 	"krillin-ai/internal/types"
 	"krillin-ai/log"
 	"krillin-ai/pkg/aliyun"
+	"krillin-ai/pkg/anthropic"
+	"krillin-ai/pkg/deepl"
+	"krillin-ai/pkg/diarizehttp"
 	"krillin-ai/pkg/fasterwhisper"
+	"krillin-ai/pkg/gemini"
+	"krillin-ai/pkg/googletranslate"
+	"krillin-ai/pkg/libretranslate"
+	"krillin-ai/pkg/localtts"
+	"krillin-ai/pkg/ollama"
 	"krillin-ai/pkg/openai"
+	"krillin-ai/pkg/pyannote"
 	"krillin-ai/pkg/whisper"
 	"krillin-ai/pkg/whispercpp"
 	"krillin-ai/pkg/whisperkit"
 	"krillin-ai/pkg/whisperx"
-	"krillin-ai/pkg/localtts"
 
 	"go.uber.org/zap"
 )
@@ -20,6 +28,8 @@ type Service struct {
 	Transcriber      types.Transcriber
 	ChatCompleter    types.ChatCompleter
 	TtsClient        types.Ttser
+	Diarizer         types.Diarizer
+	Translator       types.Translator
 	OssClient        *aliyun.OssClient
 	VoiceCloneClient *aliyun.VoiceCloneClient
 }
@@ -28,6 +38,8 @@ func NewService() *Service {
 	var transcriber types.Transcriber
 	var chatCompleter types.ChatCompleter
 	var ttsClient types.Ttser
+	var diarizer types.Diarizer
+	var translator types.Translator
 
 	switch config.Conf.Transcribe.Provider {
 	case "openai":
@@ -50,7 +62,19 @@ func NewService() *Service {
 	}
 	log.GetLogger().Info("当前选择的转录源： ", zap.String("transcriber", config.Conf.Transcribe.Provider))
 
-	chatCompleter = openai.NewClient(config.Conf.Llm.BaseUrl, config.Conf.Llm.ApiKey, config.Conf.App.Proxy)
+	switch config.Conf.Llm.Provider {
+	case "anthropic":
+		chatCompleter = anthropic.NewClient(config.Conf.Llm.BaseUrl, config.Conf.Llm.ApiKey, config.Conf.Llm.Model, config.Conf.App.Proxy)
+	case "gemini":
+		chatCompleter = gemini.NewClient(config.Conf.Llm.BaseUrl, config.Conf.Llm.ApiKey, config.Conf.Llm.Model, config.Conf.App.Proxy)
+	case "ollama":
+		chatCompleter = ollama.NewClient(config.Conf.Llm.BaseUrl, config.Conf.Llm.ApiKey, config.Conf.Llm.Model, config.Conf.App.Proxy)
+	case "azure":
+		chatCompleter = openai.NewAzureClient(config.Conf.Llm.BaseUrl, config.Conf.Llm.ApiKey, config.Conf.Llm.ApiVersion, config.Conf.App.Proxy)
+	default:
+		chatCompleter = openai.NewClient(config.Conf.Llm.BaseUrl, config.Conf.Llm.ApiKey, config.Conf.App.Proxy)
+	}
+	log.GetLogger().Info("当前选择的大模型源： ", zap.String("llm", config.Conf.Llm.Provider))
 
 	switch config.Conf.Tts.Provider {
 	case "openai":
@@ -61,10 +85,32 @@ func NewService() *Service {
 		ttsClient = localtts.NewEdgeTtsClient()
 	}
 
+	// 说话人分离对完整音频只做一次，whisperx单独运行一次带说话人分离的转录
+	switch config.Conf.Diarize.Provider {
+	case "whisperx":
+		diarizer = whisperx.NewWhisperXDiarizer(config.Conf.Transcribe.Whisperx.Model, config.Conf.Diarize.Whisperx.HfToken, config.Conf.Diarize.Whisperx.Model)
+	case "pyannote":
+		diarizer = pyannote.NewPyannoteProcessor(config.Conf.Diarize.Pyannote.Model, config.Conf.Diarize.Pyannote.HfToken)
+	case "http":
+		diarizer = diarizehttp.NewClient(config.Conf.Diarize.Http.BaseUrl, config.Conf.Diarize.Http.ApiKey, config.Conf.App.Proxy)
+	}
+
+	// 未配置时句子翻译使用大模型
+	switch config.Conf.Translator.Provider {
+	case "deepl":
+		translator = deepl.NewClient(config.Conf.Translator.Deepl.BaseUrl, config.Conf.Translator.Deepl.ApiKey, config.Conf.App.Proxy)
+	case "google":
+		translator = googletranslate.NewClient(config.Conf.Translator.Google.BaseUrl, config.Conf.Translator.Google.ApiKey, config.Conf.App.Proxy)
+	case "libretranslate":
+		translator = libretranslate.NewClient(config.Conf.Translator.Libretranslate.BaseUrl, config.Conf.Translator.Libretranslate.ApiKey, config.Conf.App.Proxy)
+	}
+
 	return &Service{
 		Transcriber:      transcriber,
 		ChatCompleter:    chatCompleter,
 		TtsClient:        ttsClient,
+		Diarizer:         diarizer,
+		Translator:       translator,
 		OssClient:        aliyun.NewOssClient(config.Conf.Transcribe.Aliyun.Oss.AccessKeyId, config.Conf.Transcribe.Aliyun.Oss.AccessKeySecret, config.Conf.Transcribe.Aliyun.Oss.Bucket),
 		VoiceCloneClient: aliyun.NewVoiceCloneClient(config.Conf.Tts.Aliyun.Speech.AccessKeyId, config.Conf.Tts.Aliyun.Speech.AccessKeySecret, config.Conf.Tts.Aliyun.Speech.AppKey),
 	}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/util"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const jsonRepairMaxAttempts = 2 // json不合格时让大模型修复的最大次数
+
+// chatOptions 读取对应用途配置的请求参数
+func chatOptions(purpose string) types.ChatOptions {
+	var purposeConfig config.LlmPurposeConfig
+	switch purpose {
+	case types.ChatPurposeTranslate:
+		purposeConfig = config.Conf.Llm.Translate
+	case types.ChatPurposeSplit:
+		purposeConfig = config.Conf.Llm.Split
+	case types.ChatPurposeAlign:
+		purposeConfig = config.Conf.Llm.Align
+	case types.ChatPurposeTitle:
+		purposeConfig = config.Conf.Llm.Title
+	case types.ChatPurposeReview:
+		purposeConfig = config.Conf.Llm.Review
+	default:
+		purposeConfig = config.Conf.Llm.Translate
+	}
+	systemPrompt := purposeConfig.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = config.Conf.Llm.SystemPrompt
+	}
+	return types.ChatOptions{
+		Model:        purposeConfig.Model,
+		SystemPrompt: systemPrompt,
+		Temperature:  purposeConfig.Temperature,
+		MaxTokens:    purposeConfig.MaxTokens,
+		Timeout:      time.Duration(purposeConfig.Timeout) * time.Second,
+	}
+}
+
+// chat 按用途的参数请求大模型，返回文本
+func (s Service) chat(purpose, prompt string) (string, error) {
+	return s.ChatCompleter.ChatCompletionWithOptions(prompt, chatOptions(purpose))
+}
+
+// chatJson 按用途的参数请求大模型返回json，按schema校验后解析到result，不合格时带上错误信息让大模型修复
+func (s Service) chatJson(purpose string, prompts *types.PromptSet, prompt string, schema *types.ChatJsonSchema, result any) error {
+	options := chatOptions(purpose)
+	if config.Conf.Llm.Json {
+		options.JsonSchema = schema
+	}
+	response, err := s.ChatCompleter.ChatCompletionWithOptions(prompt, options)
+	if err != nil {
+		return fmt.Errorf("chatJson llm err: %w", err)
+	}
+	for i := 0; ; i++ {
+		cleanResponse := util.CleanMarkdownCodeBlock(response)
+		err = util.ValidateJsonSchema(schema.Schema, []byte(cleanResponse))
+		if err == nil {
+			if err = json.Unmarshal([]byte(cleanResponse), result); err != nil {
+				return fmt.Errorf("chatJson unmarshal response err: %w", err)
+			}
+			return nil
+		}
+		if i >= jsonRepairMaxAttempts {
+			return fmt.Errorf("chatJson invalid response err: %w", err)
+		}
+		log.GetLogger().Warn("chatJson invalid response, try to repair", zap.String("schema", schema.Name), zap.String("response", response), zap.Error(err))
+		repairPrompt, renderErr := renderPrompt(prompts, types.PromptJsonRepair, types.PromptData{
+			PreviousPrompt: prompt,
+			PreviousOutput: response,
+			Error:          err.Error(),
+			Schema:         string(schema.Schema),
+		})
+		if renderErr != nil {
+			return fmt.Errorf("chatJson render repair prompt err: %w", renderErr)
+		}
+		response, err = s.ChatCompleter.ChatCompletionWithOptions(repairPrompt, options)
+		if err != nil {
+			return fmt.Errorf("chatJson repair llm err: %w", err)
+		}
+	}
+}
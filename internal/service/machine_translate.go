@@ -0,0 +1,68 @@
+package service
+
+import (
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const machineTranslateBatchSize = 50 // 机器翻译每次请求的句子数
+
+// machineTranslateEnabled 语言对是否使用机器翻译，未配置语言对时都使用，*匹配任意语言
+func machineTranslateEnabled(originLang, targetLang types.StandardLanguageCode) bool {
+	pairs := config.Conf.Translator.LanguagePairs
+	if len(pairs) == 0 {
+		return true
+	}
+	matches := func(pattern string, lang types.StandardLanguageCode) bool {
+		pattern = strings.TrimSpace(pattern)
+		return pattern == "*" || tmLangMatches(normalizeTmLang(pattern), normalizeTmLang(string(lang)))
+	}
+	for _, pair := range pairs {
+		source, target, _ := strings.Cut(pair, "->")
+		if matches(source, originLang) && matches(target, targetLang) {
+			return true
+		}
+	}
+	return false
+}
+
+// machineTranslate 使用机器翻译引擎分批翻译indexes指定的句子，返回需要交给大模型翻译的句子下标：请求失败的批次，以及开启术语重试时违反术语的句子
+func (s Service) machineTranslate(sentences []string, indexes []int, results []*TranslatedItem, originLang, targetLang types.StandardLanguageCode, glossary *types.Glossary, enableGlossaryRetry bool) []int {
+	var fallbackIndexes []int
+	for start := 0; start < len(indexes); start += machineTranslateBatchSize {
+		batchIndexes := indexes[start:min(start+machineTranslateBatchSize, len(indexes))]
+		texts := make([]string, len(batchIndexes))
+		for i, index := range batchIndexes {
+			texts[i] = sentences[index]
+		}
+		translations, err := s.Translator.Translate(texts, string(originLang), string(targetLang))
+		if err != nil {
+			log.GetLogger().Warn("machineTranslate failed, fallback to llm", zap.Int("start", batchIndexes[0]), zap.Int("num", len(batchIndexes)), zap.Error(err))
+			fallbackIndexes = append(fallbackIndexes, batchIndexes...)
+			continue
+		}
+		for i, index := range batchIndexes {
+			translation := strings.TrimSpace(translations[i])
+			if translation == "" {
+				fallbackIndexes = append(fallbackIndexes, index)
+				continue
+			}
+			violations := checkGlossary(glossary, sentences[index], translation)
+			if len(violations) > 0 && enableGlossaryRetry {
+				// 机器翻译无法注入术语，违反术语的句子交给大模型重新翻译
+				fallbackIndexes = append(fallbackIndexes, index)
+				continue
+			}
+			results[index] = &TranslatedItem{
+				OriginText:         sentences[index],
+				TranslatedText:     translation,
+				GlossaryViolations: violations,
+			}
+		}
+	}
+	return fallbackIndexes
+}
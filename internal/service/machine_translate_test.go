@@ -0,0 +1,35 @@
+package service
+
+import (
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"testing"
+)
+
+func TestMachineTranslateEnabled(t *testing.T) {
+	oldPairs := config.Conf.Translator.LanguagePairs
+	defer func() { config.Conf.Translator.LanguagePairs = oldPairs }()
+
+	tests := []struct {
+		name       string
+		pairs      []string
+		originLang types.StandardLanguageCode
+		targetLang types.StandardLanguageCode
+		want       bool
+	}{
+		{"no pairs", nil, "en", "zh_cn", true},
+		{"listed pair", []string{"en->de"}, "en", "de", true},
+		{"other pair", []string{"en->de"}, "en", "zh_cn", false},
+		{"wildcard source", []string{"*->ja"}, "ko", "ja", true},
+		{"region falls back to language", []string{"EN->zh-CN"}, "en_us", "zh_cn", true},
+		{"different region", []string{"en->zh_tw"}, "en", "zh_cn", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Conf.Translator.LanguagePairs = tt.pairs
+			if got := machineTranslateEnabled(tt.originLang, tt.targetLang); got != tt.want {
+				t.Errorf("machineTranslateEnabled(%s, %s) = %v, want %v", tt.originLang, tt.targetLang, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"krillin-ai/config"
+	"krillin-ai/internal/dto"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/util"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+// isExtraTargetLanguage 判断当前处理的是否为主目标语言之外的目标语言
+func isExtraTargetLanguage(stepParam *types.SubtitleTaskStepParam) bool {
+	return len(stepParam.TargetLanguages) > 1 && stepParam.TargetLanguage != stepParam.TargetLanguages[0]
+}
+
+// ttsVoiceCodes 确定每种目标语言的配音音色：请求中为该语言指定的优先，其次主目标语言使用tts_voice_code，最后使用配置中该语言的默认音色
+func ttsVoiceCodes(req dto.StartVideoSubtitleTaskReq, targetLanguages []types.StandardLanguageCode) map[types.StandardLanguageCode]string {
+	voiceCodes := make(map[types.StandardLanguageCode]string, len(targetLanguages))
+	for i, lang := range targetLanguages {
+		voiceCode := req.TtsVoiceCodes[string(lang)]
+		if voiceCode == "" && i == 0 {
+			voiceCode = req.TtsVoiceCode
+		}
+		if voiceCode == "" {
+			voiceCode = config.Conf.Tts.VoiceCodes[string(lang)]
+		}
+		if voiceCode != "" {
+			voiceCodes[lang] = voiceCode
+		}
+	}
+	return voiceCodes
+}
+
+// processExtraTargetLanguages 复用主目标语言的转录和分句结果，为其余目标语言分别翻译、生成字幕、配音和合成视频，结果放在任务目录下以语言命名的子目录中
+func (s Service) processExtraTargetLanguages(ctx context.Context, stepParam *types.SubtitleTaskStepParam) error {
+	if len(stepParam.TargetLanguages) <= 1 {
+		return nil
+	}
+	for _, lang := range stepParam.TargetLanguages[1:] {
+		log.GetLogger().Info("processExtraTargetLanguages start", zap.Any("taskId", stepParam.TaskId), zap.Any("language", lang))
+		langParam, err := s.subtitlesForLanguage(stepParam, lang)
+		if err != nil {
+			return fmt.Errorf("processExtraTargetLanguages subtitlesForLanguage %s err: %w", lang, err)
+		}
+		if err = s.srtFileToSpeech(ctx, langParam); err != nil {
+			return fmt.Errorf("processExtraTargetLanguages srtFileToSpeech %s err: %w", lang, err)
+		}
+		if err = s.embedSubtitles(ctx, langParam); err != nil {
+			return fmt.Errorf("processExtraTargetLanguages embedSubtitles %s err: %w", lang, err)
+		}
+		if err = saveStepParam(langParam); err != nil {
+			log.GetLogger().Warn("processExtraTargetLanguages saveStepParam err", zap.Any("taskId", stepParam.TaskId), zap.Any("language", lang), zap.Error(err))
+		}
+
+		stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, langParam.SubtitleInfos...)
+		languageName := types.GetStandardLanguageName(lang)
+		// 配音
+		if langParam.TtsResultFilePath != "" {
+			subtitleInfo := types.SubtitleFileInfo{
+				Path:               langParam.TtsResultFilePath,
+				LanguageIdentifier: "tts_" + string(lang),
+			}
+			if stepParam.UserUILanguage == types.LanguageNameEnglish {
+				subtitleInfo.Name = languageName + " Dubbing"
+			} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+				subtitleInfo.Name = languageName + " 配音"
+			}
+			stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+		}
+		// 嵌入字幕的视频
+		for _, video := range []struct {
+			fileName, enName, zhName string
+		}{
+			{types.SubtitleTaskHorizontalEmbedVideoFileName, " Horizontal Video", " 横屏视频"},
+			{types.SubtitleTaskVerticalEmbedVideoFileName, " Vertical Video", " 竖屏视频"},
+		} {
+			videoPath := filepath.Join(langParam.TaskBasePath, "output", video.fileName)
+			if _, err = os.Stat(videoPath); err != nil {
+				continue
+			}
+			subtitleInfo := types.SubtitleFileInfo{
+				Path:               videoPath,
+				LanguageIdentifier: "video_" + string(lang),
+			}
+			if stepParam.UserUILanguage == types.LanguageNameEnglish {
+				subtitleInfo.Name = languageName + video.enName
+			} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+				subtitleInfo.Name = languageName + video.zhName
+			}
+			stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+		}
+		log.GetLogger().Info("processExtraTargetLanguages end", zap.Any("taskId", stepParam.TaskId), zap.Any("language", lang))
+	}
+	return nil
+}
+
+// subtitlesForLanguage 把已经分好的句子翻译为指定语言，生成该语言的各类字幕文件
+func (s Service) subtitlesForLanguage(stepParam *types.SubtitleTaskStepParam, lang types.StandardLanguageCode) (*types.SubtitleTaskStepParam, error) {
+	langParam := *stepParam
+	langParam.TargetLanguage = lang
+	langParam.TaskBasePath = filepath.Join(stepParam.TaskBasePath, string(lang))
+	// 配音使用该语言的音色，没有可用音色且不克隆音色时不为该语言配音，避免用其他语言的音色朗读
+	langParam.TtsVoiceCode = stepParam.TtsVoiceCodes[lang]
+	if langParam.EnableTts && langParam.TtsVoiceCode == "" && langParam.VoiceCloneAudioUrl == "" {
+		log.GetLogger().Warn("subtitlesForLanguage no tts voice for language, skip tts", zap.Any("taskId", stepParam.TaskId), zap.Any("language", lang))
+		langParam.EnableTts = false
+	}
+	// 各步骤会更新进度，使用副本避免主任务进度回退
+	progressTask := *stepParam.TaskPtr
+	langParam.TaskPtr = &progressTask
+	langParam.SubtitleInfos = nil
+	langParam.BilingualSrtFilePath = ""
+	langParam.SubtitleFilePath = ""
+	langParam.ShortOriginMixedSrtFilePath = ""
+	langParam.TtsSourceFilePath = ""
+	langParam.TtsResultFilePath = ""
+	langParam.VideoWithTtsFilePath = ""
+	langParam.ReviewReportFilePath = ""
+	langParam.ConfidenceSidecarFilePath = ""
+	langParam.PromptTemplatesFilePath = ""
+	langParam.QualityReviewItems = nil
+	langParam.QualityReportFilePath = ""
+	langParam.ReadabilityReportFilePath = ""
+	langParam.SpecReportFilePath = ""
+	if err := os.MkdirAll(filepath.Join(langParam.TaskBasePath, "output"), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("subtitlesForLanguage MkdirAll err: %w", err)
+	}
+
+	for i, segment := range stepParam.SentenceSegments {
+		var (
+			translatedResults []*TranslatedItem
+			err               error
+		)
+		translationSentences := replaceSentences(langParam.PhraseReplacer, segment.Sentences)
+		for range config.Conf.App.TranslateMaxAttempts {
+			translatedResults, err = s.translateSplitSentences(langParam.TaskBasePath, translationSentences, langParam.OriginLanguage, lang, i, langParam.Glossary, langParam.EnableGlossaryRetry, langParam.Prompts)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("subtitlesForLanguage translateSplitSentences err: %w", err)
+		}
+		countTranslationMemoryHits(stepParam.TaskPtr, translatedResults)
+		var reviewItems []*types.QualityReviewItem
+		if langParam.EnableQualityReview {
+			reviewItems = s.reviewTranslations(&langParam, i, translatedResults)
+			langParam.QualityReviewItems = append(langParam.QualityReviewItems, reviewItems...)
+		}
+		rememberTranslations(translatedResults, reviewItems, langParam.OriginLanguage, lang)
+		restoreOriginText(translatedResults, segment.Sentences)
+		_ = util.SaveToDisk(translatedResults, filepath.Join(langParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskTranslationDataPersistenceFileNamePattern, i)))
+		// 二次分割长句，失败时不中断
+		splitResults, err := s.splitTranslateItem(translatedResults, langParam.Prompts)
+		if err != nil {
+			log.GetLogger().Error("subtitlesForLanguage splitTranslateItem err", zap.Any("taskId", stepParam.TaskId), zap.Any("language", lang), zap.Any("splitId", i), zap.Error(err))
+			splitResults = translatedResults
+		}
+		if _, err = writeSegmentSubtitles(&langParam, i, splitResults, segment.Offset, segment.Words); err != nil {
+			return nil, fmt.Errorf("subtitlesForLanguage writeSegmentSubtitles err: %w", err)
+		}
+	}
+
+	if err := mergeSegmentSubtitles(&langParam, len(stepParam.SentenceSegments)); err != nil {
+		return nil, err
+	}
+	if err := splitSrt(&langParam); err != nil {
+		return nil, fmt.Errorf("subtitlesForLanguage splitSrt err: %w", err)
+	}
+	return &langParam, nil
+}
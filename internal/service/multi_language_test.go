@@ -0,0 +1,29 @@
+package service
+
+import (
+	"krillin-ai/config"
+	"krillin-ai/internal/dto"
+	"krillin-ai/internal/types"
+	"reflect"
+	"testing"
+)
+
+func TestTtsVoiceCodes(t *testing.T) {
+	oldVoiceCodes := config.Conf.Tts.VoiceCodes
+	config.Conf.Tts.VoiceCodes = map[string]string{"ja": "ja-JP-NanamiNeural", "zh_cn": "default-zh"}
+	defer func() { config.Conf.Tts.VoiceCodes = oldVoiceCodes }()
+
+	req := dto.StartVideoSubtitleTaskReq{
+		TtsVoiceCode:  "zh-CN-XiaoxiaoNeural",
+		TtsVoiceCodes: map[string]string{"en": "en-US-AriaNeural"},
+	}
+	got := ttsVoiceCodes(req, []types.StandardLanguageCode{"zh_cn", "en", "ja", "fr"})
+	want := map[types.StandardLanguageCode]string{
+		"zh_cn": "zh-CN-XiaoxiaoNeural",
+		"en":    "en-US-AriaNeural",
+		"ja":    "ja-JP-NanamiNeural",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// presetNameRegex 已保存配置的名称，同时作为文件名
+var presetNameRegex = regexp.MustCompile(`^[\w\-\p{Han}]+$`)
+
+// namedStore 以 目录/名称.json 保存的命名配置，如术语表、风格配置、字幕样式和字幕规范。已保存的配置优先于同名的内置配置
+type namedStore[T any] struct {
+	dir      string
+	label    string          // 配置的名称，用于错误提示，如风格配置
+	builtins map[string]*T   // 内置配置，可以为nil
+	nameOf   func(*T) string // 返回配置的名称
+	validate func(*T) error  // 保存前的校验，可以为nil
+}
+
+func (st namedStore[T]) filePath(name string) string {
+	return filepath.Join(st.dir, name+".json")
+}
+
+func (st namedStore[T]) checkName(name string) error {
+	if !presetNameRegex.MatchString(name) {
+		return fmt.Errorf("%s名称不合法，只能包含字母、数字、中文、下划线和中划线: %s", st.label, name)
+	}
+	return nil
+}
+
+func (st namedStore[T]) save(item *T) error {
+	if err := st.checkName(st.nameOf(item)); err != nil {
+		return err
+	}
+	if st.validate != nil {
+		if err := st.validate(item); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(st.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("namedStore save %s MkdirAll err: %w", st.dir, err)
+	}
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("namedStore save %s marshal err: %w", st.dir, err)
+	}
+	return os.WriteFile(st.filePath(st.nameOf(item)), data, 0644)
+}
+
+// load 读取已保存的配置，不存在时使用同名的内置配置
+func (st namedStore[T]) load(name string) (*T, error) {
+	if err := st.checkName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(st.filePath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			if item, ok := st.builtins[name]; ok {
+				return item, nil
+			}
+			return nil, fmt.Errorf("%s不存在: %s", st.label, name)
+		}
+		return nil, fmt.Errorf("namedStore load %s read file err: %w", name, err)
+	}
+	var item T
+	if err = json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("namedStore load %s unmarshal err: %w", name, err)
+	}
+	return &item, nil
+}
+
+// list 列出内置和已保存的配置，按名称排序
+func (st namedStore[T]) list() ([]*T, error) {
+	files, err := filepath.Glob(filepath.Join(st.dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("namedStore list %s glob err: %w", st.dir, err)
+	}
+	names := make(map[string]struct{}, len(files)+len(st.builtins))
+	for name := range st.builtins {
+		names[name] = struct{}{}
+	}
+	for _, file := range files {
+		names[strings.TrimSuffix(filepath.Base(file), ".json")] = struct{}{}
+	}
+	items := make([]*T, 0, len(names))
+	for name := range names {
+		item, err := st.load(name)
+		if err != nil {
+			log.GetLogger().Warn("namedStore list load err", zap.String("dir", st.dir), zap.String("name", name), zap.Error(err))
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return st.nameOf(items[i]) < st.nameOf(items[j])
+	})
+	return items, nil
+}
+
+// delete 删除已保存的配置。内置配置无法删除，删除同名的已保存配置后恢复为内置配置
+func (st namedStore[T]) delete(name string) error {
+	if err := st.checkName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(st.filePath(name)); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("namedStore delete %s remove err: %w", name, err)
+		}
+		if _, ok := st.builtins[name]; ok {
+			return fmt.Errorf("内置%s无法删除: %s", st.label, name)
+		}
+		return fmt.Errorf("%s不存在: %s", st.label, name)
+	}
+	return nil
+}
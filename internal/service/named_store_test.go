@@ -0,0 +1,67 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"path/filepath"
+	"testing"
+)
+
+func TestNamedStore(t *testing.T) {
+	store := namedStore[types.StyleProfile]{
+		dir:      filepath.Join(t.TempDir(), "profiles"),
+		label:    "风格配置",
+		builtins: map[string]*types.StyleProfile{"gaming": {Name: "gaming", Formality: types.StyleFormalityCasual}},
+		nameOf:   func(profile *types.StyleProfile) string { return profile.Name },
+		validate: (*types.StyleProfile).Validate,
+	}
+
+	for _, name := range []string{"", "../escape", "a b"} {
+		if err := store.save(&types.StyleProfile{Name: name}); err == nil {
+			t.Errorf("save %q: expected name error", name)
+		}
+		if _, err := store.load(name); err == nil {
+			t.Errorf("load %q: expected name error", name)
+		}
+	}
+	if err := store.save(&types.StyleProfile{Name: "bad", Formality: "rude"}); err == nil {
+		t.Error("save invalid profile: expected error")
+	}
+
+	if err := store.save(&types.StyleProfile{Name: "教育", Formality: types.StyleFormalityFormal}); err != nil {
+		t.Fatal(err)
+	}
+	// 已保存的配置覆盖同名的内置配置
+	if err := store.save(&types.StyleProfile{Name: "gaming", Formality: types.StyleFormalityNeutral}); err != nil {
+		t.Fatal(err)
+	}
+	if profile, err := store.load("gaming"); err != nil || profile.Formality != types.StyleFormalityNeutral {
+		t.Errorf("load saved gaming: got %+v, %v", profile, err)
+	}
+	items, err := store.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Name != "gaming" || items[1].Name != "教育" {
+		t.Errorf("list: got %+v", items)
+	}
+
+	// 删除覆盖的配置后恢复为内置配置，内置配置本身无法删除
+	if err = store.delete("gaming"); err != nil {
+		t.Fatal(err)
+	}
+	if profile, err := store.load("gaming"); err != nil || profile.Formality != types.StyleFormalityCasual {
+		t.Errorf("load builtin gaming: got %+v, %v", profile, err)
+	}
+	if err = store.delete("gaming"); err == nil {
+		t.Error("delete builtin: expected error")
+	}
+	if err = store.delete("教育"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.delete("教育"); err == nil {
+		t.Error("delete missing: expected error")
+	}
+	if _, err = store.load("教育"); err == nil {
+		t.Error("load deleted: expected error")
+	}
+}
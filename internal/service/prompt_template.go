@@ -0,0 +1,209 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"go.uber.org/zap"
+)
+
+const promptTemplateFileExt = ".tmpl"
+
+var (
+	promptSetMutex sync.RWMutex
+	// 启动时加载的全局模板，未加载时使用内置模板
+	globalPromptSet *types.PromptSet
+)
+
+// 校验模板时使用的示例数据，所有变量都不为空，确保条件分支也会被执行
+var samplePromptData = types.PromptData{
+	SourceLanguage:       "English",
+	TargetLanguage:       "简体中文",
+	Glossary:             "glossary",
+	StyleGuide:           "style guide",
+	MemoryReference:      "memory reference",
+	Text:                 "text",
+	TranslatedText:       "translated text",
+	PreviousSentences:    "previous sentences",
+	NextSentences:        "next sentences",
+	Summary:              "summary",
+	PreviousTranslations: "previous translations",
+	Sentences:            `[{"id":1,"text":"text"}]`,
+	PreviousPrompt:       "previous prompt",
+	PreviousOutput:       "previous output",
+	Violations:           "violations",
+	Error:                "error",
+	Schema:               "{}",
+}
+
+// parsePromptTemplate 解析模板并用示例数据试渲染，提前发现语法错误和不存在的变量
+func parsePromptTemplate(name, source, text string) (*types.PromptTemplate, error) {
+	if _, ok := types.DefaultPromptTemplates[name]; !ok {
+		return nil, fmt.Errorf("未知的提示词模板: %s", name)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("提示词模板%s解析失败: %w", name, err)
+	}
+	if err = tmpl.Execute(io.Discard, samplePromptData); err != nil {
+		return nil, fmt.Errorf("提示词模板%s渲染失败: %w", name, err)
+	}
+	sum := sha256.Sum256([]byte(text))
+	return &types.PromptTemplate{
+		Name:     name,
+		Source:   source,
+		Version:  hex.EncodeToString(sum[:])[:12],
+		Text:     text,
+		Template: tmpl,
+	}, nil
+}
+
+// loadPromptSet 加载内置模板，再用模板目录中的同名模板覆盖
+func loadPromptSet(dir string) (*types.PromptSet, error) {
+	set := &types.PromptSet{Templates: make(map[string]*types.PromptTemplate, len(types.DefaultPromptTemplates))}
+	for name, text := range types.DefaultPromptTemplates {
+		promptTemplate, err := parsePromptTemplate(name, types.PromptSourceBuiltin, text)
+		if err != nil {
+			return nil, err
+		}
+		set.Templates[name] = promptTemplate
+	}
+	if dir == "" {
+		return set, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return set, nil
+		}
+		return nil, fmt.Errorf("读取提示词模板目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != promptTemplateFileExt {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取提示词模板%s失败: %w", entry.Name(), err)
+		}
+		promptTemplate, err := parsePromptTemplate(strings.TrimSuffix(entry.Name(), promptTemplateFileExt), types.PromptSourceFile, string(data))
+		if err != nil {
+			return nil, err
+		}
+		set.Templates[promptTemplate.Name] = promptTemplate
+	}
+	return set, nil
+}
+
+// LoadPromptTemplates 加载并校验提示词模板，服务启动时调用
+func LoadPromptTemplates() error {
+	set, err := loadPromptSet(config.Conf.App.PromptTemplateDir)
+	if err != nil {
+		return err
+	}
+	promptSetMutex.Lock()
+	globalPromptSet = set
+	promptSetMutex.Unlock()
+	for name, promptTemplate := range set.Templates {
+		if promptTemplate.Source != types.PromptSourceBuiltin {
+			log.GetLogger().Info("使用自定义提示词模板", zap.String("name", name), zap.String("version", promptTemplate.Version))
+		}
+	}
+	return nil
+}
+
+func getGlobalPromptSet() *types.PromptSet {
+	promptSetMutex.RLock()
+	set := globalPromptSet
+	promptSetMutex.RUnlock()
+	if set != nil {
+		return set
+	}
+	// 未调用LoadPromptTemplates时只使用内置模板，内置模板在测试中保证可以解析
+	set, err := loadPromptSet("")
+	if err != nil {
+		panic(err)
+	}
+	promptSetMutex.Lock()
+	globalPromptSet = set
+	promptSetMutex.Unlock()
+	return set
+}
+
+// newTaskPromptSet 在全局模板的基础上应用任务覆盖的模板和风格要求
+func newTaskPromptSet(overrides map[string]string, styleGuide string) (*types.PromptSet, error) {
+	global := getGlobalPromptSet()
+	set := &types.PromptSet{
+		Templates:  make(map[string]*types.PromptTemplate, len(global.Templates)),
+		StyleGuide: strings.TrimSpace(styleGuide),
+	}
+	for name, promptTemplate := range global.Templates {
+		set.Templates[name] = promptTemplate
+	}
+	for name, text := range overrides {
+		promptTemplate, err := parsePromptTemplate(name, types.PromptSourceTask, text)
+		if err != nil {
+			return nil, err
+		}
+		set.Templates[name] = promptTemplate
+	}
+	return set, nil
+}
+
+// renderPrompt 渲染提示词，prompts为空时使用全局模板
+func renderPrompt(prompts *types.PromptSet, name string, data types.PromptData) (string, error) {
+	if prompts == nil {
+		prompts = getGlobalPromptSet()
+	}
+	promptTemplate, ok := prompts.Templates[name]
+	if !ok {
+		return "", fmt.Errorf("renderPrompt unknown template: %s", name)
+	}
+	if data.StyleGuide == "" {
+		data.StyleGuide = prompts.StyleGuide
+	}
+	var builder strings.Builder
+	if err := promptTemplate.Template.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("renderPrompt %s err: %w", name, err)
+	}
+	return builder.String(), nil
+}
+
+// writePromptTemplates 把任务使用的模板内容和版本写入输出目录，便于复现结果
+func writePromptTemplates(stepParam *types.SubtitleTaskStepParam) error {
+	if stepParam.Prompts == nil {
+		return nil
+	}
+	templates := make([]*types.PromptTemplate, 0, len(stepParam.Prompts.Templates))
+	for _, promptTemplate := range stepParam.Prompts.Templates {
+		templates = append(templates, promptTemplate)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	data, err := json.MarshalIndent(struct {
+		StyleGuide string                  `json:"style_guide"`
+		Templates  []*types.PromptTemplate `json:"templates"`
+	}{stepParam.Prompts.StyleGuide, templates}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writePromptTemplates marshal err: %w", err)
+	}
+	filePath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskPromptTemplatesFileName)
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("writePromptTemplates write file err: %w", err)
+	}
+	stepParam.PromptTemplatesFilePath = filePath
+	return nil
+}
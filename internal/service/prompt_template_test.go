@@ -0,0 +1,51 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPromptSet(t *testing.T) {
+	dir := t.TempDir()
+	set, err := loadPromptSet(dir)
+	if err != nil {
+		t.Fatalf("loadPromptSet builtin err: %v", err)
+	}
+	if len(set.Templates) != len(types.DefaultPromptTemplates) {
+		t.Fatalf("expect %d templates, got %d", len(types.DefaultPromptTemplates), len(set.Templates))
+	}
+
+	custom := "Translate into {{.TargetLanguage}}: {{.Text}}{{.StyleGuideSection}}"
+	if err = os.WriteFile(filepath.Join(dir, types.PromptTranslate+promptTemplateFileExt), []byte(custom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set, err = loadPromptSet(dir)
+	if err != nil {
+		t.Fatalf("loadPromptSet custom err: %v", err)
+	}
+	if set.Templates[types.PromptTranslate].Source != types.PromptSourceFile {
+		t.Errorf("expect template from file, got %s", set.Templates[types.PromptTranslate].Source)
+	}
+	set.StyleGuide = "casual"
+	prompt, err := renderPrompt(set, types.PromptTranslate, types.PromptData{TargetLanguage: "English", Text: "你好"})
+	if err != nil {
+		t.Fatalf("renderPrompt err: %v", err)
+	}
+	if !strings.HasPrefix(prompt, "Translate into English: 你好") || !strings.Contains(prompt, "casual") {
+		t.Errorf("unexpected prompt %q", prompt)
+	}
+
+	// 引用不存在的变量和未知的模板名都在加载时报错
+	if err = os.WriteFile(filepath.Join(dir, types.PromptTranslate+promptTemplateFileExt), []byte("{{.Unknown}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = loadPromptSet(dir); err == nil {
+		t.Error("expect error for unknown variable")
+	}
+	if _, err = newTaskPromptSet(map[string]string{"unknown": "text"}, ""); err == nil {
+		t.Error("expect error for unknown template name")
+	}
+}
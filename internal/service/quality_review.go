@@ -0,0 +1,322 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+const qualityReviewBatchSize = 20 // 大模型审查时每次请求的句子数
+
+var latinScript = []*unicode.RangeTable{unicode.Latin}
+
+// 目标语言使用的文字，用于判断译文是否为目标语言，未列出的语言不做判断
+var languageScripts = map[types.StandardLanguageCode][]*unicode.RangeTable{
+	types.LanguageNameSimplifiedChinese:  {unicode.Han},
+	types.LanguageNameTraditionalChinese: {unicode.Han},
+	types.LanguageNameJapanese:           {unicode.Han, unicode.Hiragana, unicode.Katakana},
+	types.LanguageNameKorean:             {unicode.Hangul},
+	types.LanguageNameRussian:            {unicode.Cyrillic},
+	types.LanguageNameUkrainian:          {unicode.Cyrillic},
+	types.LanguageNameBulgarian:          {unicode.Cyrillic},
+	types.LanguageNameMacedonian:         {unicode.Cyrillic},
+	types.LanguageNameKazakh:             {unicode.Cyrillic},
+	types.LanguageNameKyrgyz:             {unicode.Cyrillic},
+	types.LanguageNameTajik:              {unicode.Cyrillic},
+	types.LanguageNameArabic:             {unicode.Arabic},
+	types.LanguageNamePersian:            {unicode.Arabic},
+	types.LanguageNameUrdu:               {unicode.Arabic},
+	types.LanguageNamePashto:             {unicode.Arabic},
+	types.LanguageNameThai:               {unicode.Thai},
+	types.LanguageNameHindi:              {unicode.Devanagari},
+	types.LanguageNameMarathi:            {unicode.Devanagari},
+	types.LanguageNameBengali:            {unicode.Bengali},
+	types.LanguageNameHebrew:             {unicode.Hebrew},
+	types.LanguageNameGreek:              {unicode.Greek},
+	types.LanguageNameTamil:              {unicode.Tamil},
+	types.LanguageNameTelugu:             {unicode.Telugu},
+	types.LanguageNameMalayalam:          {unicode.Malayalam},
+	types.LanguageNameKannada:            {unicode.Kannada},
+	types.LanguageNamePunjabi:            {unicode.Gurmukhi},
+	types.LanguageNameOdia:               {unicode.Oriya},
+	types.LanguageNameGeorgian:           {unicode.Georgian},
+	types.LanguageNameArmenian:           {unicode.Armenian},
+	types.LanguageNameKhmer:              {unicode.Khmer},
+	types.LanguageNameLao:                {unicode.Lao},
+	types.LanguageNameAmharic:            {unicode.Ethiopic},
+	types.LanguageNameEnglish:            latinScript,
+	types.LanguageNameFrench:             latinScript,
+	types.LanguageNameGerman:             latinScript,
+	types.LanguageNameItalian:            latinScript,
+	types.LanguageNameSpanish:            latinScript,
+	types.LanguageNamePortuguese:         latinScript,
+	types.LanguageNameIndonesian:         latinScript,
+	types.LanguageNameMalaysian:          latinScript,
+	types.LanguageNameVietnamese:         latinScript,
+	types.LanguageNameFilipino:           latinScript,
+	types.LanguageNameDutch:              latinScript,
+	types.LanguageNameSwedish:            latinScript,
+	types.LanguageNameDanish:             latinScript,
+	types.LanguageNameNorwegian:          latinScript,
+	types.LanguageNameFinnish:            latinScript,
+	types.LanguageNamePolish:             latinScript,
+	types.LanguageNameCzech:              latinScript,
+	types.LanguageNameSlovak:             latinScript,
+	types.LanguageNameHungarian:          latinScript,
+	types.LanguageNameRomanian:           latinScript,
+	types.LanguageNameTurkish:            latinScript,
+	types.LanguageNameCroatian:           latinScript,
+	types.LanguageNameCatalan:            latinScript,
+	types.LanguageNamePinyin:             latinScript,
+}
+
+// 中日韩泰等不以空格分词的文字按字计数，其余文字按词计数
+func isCharCountedRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai, unicode.Lao, unicode.Khmer)
+}
+
+// matchesTargetScript 判断译文的文字是否以目标语言的文字为主，无法判断时返回true
+func matchesTargetScript(text string, targetLang types.StandardLanguageCode) bool {
+	scripts, ok := languageScripts[targetLang]
+	if !ok {
+		return true
+	}
+	var total, matched int
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(word)
+		if isCharCountedRune(runes[0]) {
+			for _, r := range runes {
+				total++
+				if unicode.In(r, scripts...) {
+					matched++
+				}
+			}
+			continue
+		}
+		total++
+		if unicode.In(runes[0], scripts...) {
+			matched++
+		}
+	}
+	return total == 0 || float64(matched)/float64(total) >= 0.5
+}
+
+func endsWithEllipsis(text string) bool {
+	return strings.HasSuffix(text, "...") || strings.HasSuffix(text, "…")
+}
+
+// checkTranslationQuality 用规则检查译文：空译文、未翻译、非目标语言和截断
+func checkTranslationQuality(originText, translatedText string, targetLang types.StandardLanguageCode) []string {
+	translatedText = strings.TrimSpace(translatedText)
+	if translatedText == "" {
+		return []string{types.QualityIssueEmpty}
+	}
+	var issues []string
+	normalizedOrigin := normalizeTmSource(originText)
+	// 很短的句子(如人名、OK)原样保留是正常的
+	if normalizeTmSource(translatedText) == normalizedOrigin {
+		if len([]rune(normalizedOrigin)) >= 8 {
+			issues = append(issues, types.QualityIssueUntranslated)
+		}
+	} else if !matchesTargetScript(translatedText, targetLang) {
+		issues = append(issues, types.QualityIssueWrongLanguage)
+	}
+	originLength := calcLength(originText)
+	if (originLength >= 20 && calcLength(translatedText) < originLength*0.25) || (endsWithEllipsis(translatedText) && !endsWithEllipsis(strings.TrimSpace(originText))) {
+		issues = append(issues, types.QualityIssueTruncated)
+	}
+	return issues
+}
+
+type qualityReviewLine struct {
+	Id          int    `json:"id"`
+	Source      string `json:"source"`
+	Translation string `json:"translation"`
+}
+
+type qualityReviewResult struct {
+	Issues []struct {
+		Id     int    `json:"id"`
+		Issue  string `json:"issue"`
+		Reason string `json:"reason"`
+	} `json:"issues"`
+}
+
+// judgeTranslations 让大模型审查indexes指定的句子，结果写入reviewItems，返回大模型成功审查过的句子。失败的窗口只保留规则检查的结果
+func (s Service) judgeTranslations(items []*TranslatedItem, indexes []int, reviewItems []*types.QualityReviewItem, targetLang types.StandardLanguageCode, prompts *types.PromptSet) map[int]bool {
+	judged := make(map[int]bool, len(indexes))
+	for start := 0; start < len(indexes); start += qualityReviewBatchSize {
+		windowIndexes := indexes[start:min(start+qualityReviewBatchSize, len(indexes))]
+		lines := make([]qualityReviewLine, len(windowIndexes))
+		for i, index := range windowIndexes {
+			lines[i] = qualityReviewLine{Id: i + 1, Source: items[index].OriginText, Translation: items[index].TranslatedText}
+		}
+		linesJson, err := json.Marshal(lines)
+		if err != nil {
+			log.GetLogger().Warn("judgeTranslations marshal lines err", zap.Error(err))
+			continue
+		}
+		prompt, err := renderPrompt(prompts, types.PromptQualityReview, types.PromptData{
+			TargetLanguage: types.GetStandardLanguageName(targetLang),
+			Sentences:      string(linesJson),
+		})
+		if err != nil {
+			log.GetLogger().Warn("judgeTranslations render prompt err", zap.Error(err))
+			return judged
+		}
+		var result qualityReviewResult
+		if err = s.chatJson(types.ChatPurposeReview, prompts, prompt, types.QualityReviewJsonSchema, &result); err != nil {
+			log.GetLogger().Warn("judgeTranslations llm review failed, skip window", zap.Int("start", windowIndexes[0]), zap.Error(err))
+			continue
+		}
+		for _, index := range windowIndexes {
+			judged[index] = true
+		}
+		for _, issue := range result.Issues {
+			if issue.Id < 1 || issue.Id > len(windowIndexes) {
+				continue
+			}
+			switch issue.Issue {
+			case types.QualityIssueUntranslated, types.QualityIssueWrongLanguage, types.QualityIssueTruncated, types.QualityIssueMistranslation:
+			default:
+				issue.Issue = types.QualityIssueMistranslation
+			}
+			reviewItem := reviewItems[windowIndexes[issue.Id-1]]
+			reviewItem.Issues = append(reviewItem.Issues, issue.Issue)
+			reviewItem.Reason = issue.Reason
+		}
+	}
+	return judged
+}
+
+// reviewTranslations 审查一个音频片段的译文，重新翻译有问题的句子并直接修改items，返回每行的审查记录
+func (s Service) reviewTranslations(stepParam *types.SubtitleTaskStepParam, segmentId int, items []*TranslatedItem) []*types.QualityReviewItem {
+	targetLang := stepParam.TargetLanguage
+	reviewItems := make([]*types.QualityReviewItem, len(items))
+	var judgeIndexes []int
+	for i, item := range items {
+		reviewItems[i] = &types.QualityReviewItem{
+			SegmentId:      segmentId,
+			Index:          i,
+			OriginText:     item.OriginText,
+			TranslatedText: item.TranslatedText,
+			Issues:         checkTranslationQuality(item.OriginText, item.TranslatedText, targetLang),
+		}
+		// 规则已经发现问题的句子不需要再让大模型审查
+		if len(reviewItems[i].Issues) == 0 {
+			judgeIndexes = append(judgeIndexes, i)
+		}
+	}
+	s.judgeTranslations(items, judgeIndexes, reviewItems, targetLang, stepParam.Prompts)
+
+	// 重新翻译有问题的句子，结合上下文逐句翻译
+	var retryIndexes []int
+	sentences := make([]string, len(items))
+	for i, item := range items {
+		sentences[i] = item.OriginText
+		if len(reviewItems[i].Issues) > 0 {
+			retryIndexes = append(retryIndexes, i)
+		}
+	}
+	if len(retryIndexes) > 0 {
+		log.GetLogger().Info("reviewTranslations retranslate lines with issues", zap.Any("taskId", stepParam.TaskId), zap.Int("splitId", segmentId), zap.Int("num", len(retryIndexes)))
+		results := make([]*TranslatedItem, len(items))
+		s.translateSentences(sentences, retryIndexes, results, nil, targetLang, stepParam.Glossary, stepParam.EnableGlossaryRetry, stepParam.Prompts)
+		// 通过规则检查的新译文再交给大模型复核，只有复核通过的才算修正
+		var candidateIndexes []int
+		recheckItems := make([]*types.QualityReviewItem, len(items))
+		for _, index := range retryIndexes {
+			result := results[index]
+			if result == nil || strings.TrimSpace(result.TranslatedText) == "" {
+				continue
+			}
+			if len(checkTranslationQuality(result.OriginText, result.TranslatedText, targetLang)) == 0 && result.TranslatedText != items[index].TranslatedText {
+				candidateIndexes = append(candidateIndexes, index)
+				recheckItems[index] = &types.QualityReviewItem{}
+			} else if strings.TrimSpace(items[index].TranslatedText) == "" {
+				// 原译文为空时，有问题的新译文也比空行好
+				items[index].TranslatedText = result.TranslatedText
+			}
+		}
+		judged := s.judgeTranslations(results, candidateIndexes, recheckItems, targetLang, stepParam.Prompts)
+		for _, index := range candidateIndexes {
+			if len(recheckItems[index].Issues) > 0 {
+				continue
+			}
+			result, reviewItem := results[index], reviewItems[index]
+			items[index].TranslatedText = result.TranslatedText
+			items[index].GlossaryViolations = result.GlossaryViolations
+			items[index].MemoryMatch = ""
+			if judged[index] {
+				reviewItem.Status = types.QualityStatusFixed
+			} else {
+				// 复核失败时使用通过规则检查的新译文，但不写入翻译记忆
+				reviewItem.Status = types.QualityStatusUnverified
+			}
+		}
+	}
+
+	unresolved := 0
+	for i, reviewItem := range reviewItems {
+		reviewItem.FinalText = items[i].TranslatedText
+		if reviewItem.Status != "" {
+			continue
+		}
+		if len(reviewItem.Issues) == 0 {
+			reviewItem.Status = types.QualityStatusOk
+		} else {
+			reviewItem.Status = types.QualityStatusUnresolved
+			unresolved++
+		}
+	}
+	if unresolved > 0 {
+		log.GetLogger().Warn("reviewTranslations lines still have issues after retranslation", zap.Any("taskId", stepParam.TaskId), zap.Int("splitId", segmentId), zap.Int("num", unresolved))
+	}
+	return reviewItems
+}
+
+// writeQualityReport 把全部音频片段的审查记录写入输出目录
+func writeQualityReport(stepParam *types.SubtitleTaskStepParam) error {
+	lines := append([]*types.QualityReviewItem{}, stepParam.QualityReviewItems...)
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].SegmentId != lines[j].SegmentId {
+			return lines[i].SegmentId < lines[j].SegmentId
+		}
+		return lines[i].Index < lines[j].Index
+	})
+	report := types.QualityReport{
+		TargetLanguage: string(stepParam.TargetLanguage),
+		Lines:          lines,
+	}
+	for _, line := range lines {
+		report.Summary.Total++
+		switch line.Status {
+		case types.QualityStatusOk:
+			report.Summary.Ok++
+		case types.QualityStatusFixed:
+			report.Summary.Fixed++
+		case types.QualityStatusUnverified:
+			report.Summary.Unverified++
+		case types.QualityStatusUnresolved:
+			report.Summary.Unresolved++
+		}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writeQualityReport marshal err: %w", err)
+	}
+	filePath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskQualityReportFileName)
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("writeQualityReport write file err: %w", err)
+	}
+	stepParam.QualityReportFilePath = filePath
+	return nil
+}
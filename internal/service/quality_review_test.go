@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMatchesTargetScript(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		targetLang types.StandardLanguageCode
+		want       bool
+	}{
+		{"chinese", "欢迎回到频道", types.LanguageNameSimplifiedChinese, true},
+		{"english for chinese", "Welcome back to the channel", types.LanguageNameSimplifiedChinese, false},
+		{"chinese with a brand name", "我们用 iPhone 拍的", types.LanguageNameSimplifiedChinese, true},
+		{"mostly english for chinese", "Welcome back to the channel 欢迎", types.LanguageNameSimplifiedChinese, false},
+		{"japanese kana", "ありがとうございます", types.LanguageNameJapanese, true},
+		{"russian", "Добро пожаловать", types.LanguageNameRussian, true},
+		{"latin for russian", "Dobro pozhalovat", types.LanguageNameRussian, false},
+		{"only digits", "2024", types.LanguageNameSimplifiedChinese, true},
+		{"unknown language", "Welcome", types.StandardLanguageCode("xx"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesTargetScript(tt.text, tt.targetLang); got != tt.want {
+				t.Errorf("matchesTargetScript(%q, %s) = %v, want %v", tt.text, tt.targetLang, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTranslationQuality(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		translated string
+		want       []string
+	}{
+		{"ok", "Welcome back to the channel", "欢迎回到频道", nil},
+		{"empty", "Welcome back to the channel", "  ", []string{types.QualityIssueEmpty}},
+		{"untranslated", "Welcome back to the channel", "Welcome back to the channel.", []string{types.QualityIssueUntranslated}},
+		{"short name kept", "Krillin", "Krillin", nil},
+		{"wrong language", "Welcome back to the channel", "Bienvenue sur la chaîne", []string{types.QualityIssueWrongLanguage}},
+		{"too short", "Today we are going to talk about the new features of this release", "今天", []string{types.QualityIssueTruncated}},
+		{"ellipsis", "Welcome back to the channel", "欢迎回到…", []string{types.QualityIssueTruncated}},
+		{"ellipsis in origin", "Well...", "嗯……", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkTranslationQuality(tt.origin, tt.translated, types.LanguageNameSimplifiedChinese)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("checkTranslationQuality(%q, %q) = %v, want %v", tt.origin, tt.translated, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeChatCompleter func(prompt string) (string, error)
+
+func (f fakeChatCompleter) ChatCompletionWithOptions(prompt string, options types.ChatOptions) (string, error) {
+	return f(prompt)
+}
+
+func TestReviewTranslationsRejudgesRetranslation(t *testing.T) {
+	log.Logger = zap.NewNop()
+	oldParallel := config.Conf.App.TranslateParallelNum
+	config.Conf.App.TranslateParallelNum = 1
+	defer func() { config.Conf.App.TranslateParallelNum = oldParallel }()
+
+	tests := []struct {
+		name       string
+		review     func() (string, error)
+		wantStatus string
+		wantText   string
+	}{
+		{"fixed", func() (string, error) { return `{"issues":[]}`, nil }, types.QualityStatusFixed, "欢迎回到频道"},
+		{"unverified", func() (string, error) { return "", errors.New("timeout") }, types.QualityStatusUnverified, "欢迎回到频道"},
+		{"still wrong", func() (string, error) {
+			return `{"issues":[{"id":1,"issue":"mistranslation","reason":"wrong meaning"}]}`, nil
+		}, types.QualityStatusUnresolved, "Welcome back to the channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Service{ChatCompleter: fakeChatCompleter(func(prompt string) (string, error) {
+				if strings.Contains(prompt, "TRANSLATION REVIEW TASK") {
+					return tt.review()
+				}
+				return "欢迎回到频道", nil
+			})}
+			items := []*TranslatedItem{{OriginText: "Welcome back to the channel", TranslatedText: "Welcome back to the channel"}}
+			stepParam := &types.SubtitleTaskStepParam{TargetLanguage: types.LanguageNameSimplifiedChinese}
+
+			reviewItems := s.reviewTranslations(stepParam, 0, items)
+			if reviewItems[0].Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", reviewItems[0].Status, tt.wantStatus)
+			}
+			if items[0].TranslatedText != tt.wantText || reviewItems[0].FinalText != tt.wantText {
+				t.Errorf("text = %q, final = %q, want %q", items[0].TranslatedText, reviewItems[0].FinalText, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/pkg/subtitle"
+	"os"
+	"path/filepath"
+)
+
+// readabilityReport 调整后仍然违反可读性约束的字幕
+type readabilityReport struct {
+	TargetLanguage string               `json:"target_language"`
+	CueNum         int                  `json:"cue_num"`
+	Summary        map[string]int       `json:"summary"` // 约束类型 -> 违反次数
+	Violations     []subtitle.Violation `json:"violations"`
+}
+
+// readabilityConstraints 配置中的字幕可读性约束
+func readabilityConstraints() subtitle.Readability {
+	conf := config.Conf.Readability
+	return subtitle.Readability{
+		MaxCps:           conf.MaxCps,
+		MaxCpsCjk:        conf.MaxCpsCjk,
+		MaxLineLength:    conf.MaxLineLength,
+		MaxLineLengthCjk: conf.MaxLineLengthCjk,
+		MaxLines:         conf.MaxLines,
+		MinDuration:      secondsToDuration(conf.MinDuration),
+		MaxDuration:      secondsToDuration(conf.MaxDuration),
+		MinGap:           secondsToDuration(conf.MinGap),
+		MaxMergeGap:      secondsToDuration(conf.MaxMergeGap),
+	}
+}
+
+// writeReadabilityReport 写出可读性报告，没有违反约束时也写出便于确认
+func writeReadabilityReport(stepParam *types.SubtitleTaskStepParam, cueNum int, violations []subtitle.Violation) error {
+	report := readabilityReport{
+		TargetLanguage: string(stepParam.TargetLanguage),
+		CueNum:         cueNum,
+		Summary:        make(map[string]int),
+		Violations:     violations,
+	}
+	if report.Violations == nil {
+		report.Violations = []subtitle.Violation{}
+	}
+	for _, violation := range violations {
+		report.Summary[violation.Rule]++
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writeReadabilityReport marshal err: %w", err)
+	}
+	filePath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskReadabilityReportFileName)
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("writeReadabilityReport write file err: %w", err)
+	}
+	stepParam.ReadabilityReportFilePath = filePath
+	return nil
+}
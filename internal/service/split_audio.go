@@ -70,6 +70,7 @@ func getQuietestTimePoint(input string, start, end float64) (second float64, err
 			break
 		}
 		if err != nil {
+			_ = cmd.Wait()
 			return 0, fmt.Errorf("error reading from stdout: [%s] %w", cmd.String(), err)
 		}
 		for i := range n {
@@ -6,11 +6,11 @@ import (
 	"krillin-ai/internal/storage"
 	"krillin-ai/internal/types"
 	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
 	"krillin-ai/pkg/util"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -23,13 +23,17 @@ func (s Service) srtFileToSpeech(ctx context.Context, stepParam *types.SubtitleT
 	if !stepParam.EnableTts {
 		return nil
 	}
-	// Step 1: 解析字幕文件
-	subtitles, err := parseSRT(stepParam.TtsSourceFilePath)
+	// Step 1: 读取需要配音的字幕
+	subtitles, err := ttsSentences(stepParam)
 	if err != nil {
-		log.GetLogger().Error("srtFileToSpeech parseSRT error", zap.Any("stepParam", stepParam), zap.Error(err))
-		return fmt.Errorf("srtFileToSpeech parseSRT error: %w", err)
+		log.GetLogger().Error("srtFileToSpeech ttsSentences error", zap.Any("stepParam", stepParam), zap.Error(err))
+		return fmt.Errorf("srtFileToSpeech ttsSentences error: %w", err)
 	}
+	return s.sentencesToSpeech(subtitles, nil, stepParam)
+}
 
+// sentencesToSpeech 为字幕生成配音并替换视频音轨，regenerate为nil时全部重新合成，否则只合成其中的句子，其余沿用已有的音频
+func (s Service) sentencesToSpeech(subtitles []types.SrtSentenceWithStrTime, regenerate map[int]bool, stepParam *types.SubtitleTaskStepParam) error {
 	var audioFiles []string
 	var currentTime time.Time
 
@@ -44,7 +48,7 @@ func (s Service) srtFileToSpeech(ctx context.Context, stepParam *types.SubtitleT
 	// Step 2: 使用 阿里云TTS
 	// 判断是否使用音色克隆
 	voiceCode := stepParam.TtsVoiceCode
-	if stepParam.VoiceCloneAudioUrl != "" {
+	if stepParam.VoiceCloneAudioUrl != "" && (regenerate == nil || len(regenerate) > 0) {
 		var code string
 		code, err = s.VoiceCloneClient.CosyVoiceClone("krillinai", stepParam.VoiceCloneAudioUrl)
 		if err != nil {
@@ -55,7 +59,7 @@ func (s Service) srtFileToSpeech(ctx context.Context, stepParam *types.SubtitleT
 	}
 
 	// 并发处理TTS转换
-	err = s.processSubtitlesConcurrently(subtitles, voiceCode, stepParam)
+	err = s.processSubtitlesConcurrently(subtitles, voiceCode, regenerate, stepParam)
 	if err != nil {
 		log.GetLogger().Error("srtFileToSpeech processSubtitlesConcurrently error", zap.Any("stepParam", stepParam), zap.Error(err))
 		return fmt.Errorf("srtFileToSpeech processSubtitlesConcurrently error: %w", err)
@@ -152,7 +156,7 @@ func (s Service) srtFileToSpeech(ctx context.Context, stepParam *types.SubtitleT
 	return nil
 }
 
-func (s Service) processSubtitlesConcurrently(subtitles []types.SrtSentenceWithStrTime, voiceCode string, stepParam *types.SubtitleTaskStepParam) error {
+func (s Service) processSubtitlesConcurrently(subtitles []types.SrtSentenceWithStrTime, voiceCode string, regenerate map[int]bool, stepParam *types.SubtitleTaskStepParam) error {
 	// 创建一个结果数组来存储每个字幕的处理结果
 	type processingResult struct {
 		index int
@@ -175,6 +179,13 @@ func (s Service) processSubtitlesConcurrently(subtitles []types.SrtSentenceWithS
 			defer func() { <-semaphore }()
 
 			outputFile := filepath.Join(stepParam.TaskBasePath, fmt.Sprintf("subtitle_%d.wav", index+1))
+			// 未修改的句子沿用已有音频
+			if regenerate != nil && !regenerate[index] {
+				if _, err := os.Stat(outputFile); err == nil {
+					resultCh <- processingResult{index: index, err: nil}
+					return
+				}
+			}
 			err := s.TtsClient.Text2Speech(subtitle.Text, voiceCode, outputFile)
 			if err != nil {
 				log.GetLogger().Error("processSubtitlesConcurrently Text2Speech error",
@@ -260,24 +271,42 @@ func (s Service) processSubtitlesConcurrently(subtitles []types.SrtSentenceWithS
 	return nil
 }
 
-func parseSRT(filePath string) ([]types.SrtSentenceWithStrTime, error) {
-	data, err := os.ReadFile(filePath)
+// ttsSentences 读取需要配音的字幕，每条取上方的文字，配音源为双语字幕时直接使用结构化字幕
+func ttsSentences(stepParam *types.SubtitleTaskStepParam) ([]types.SrtSentenceWithStrTime, error) {
+	var (
+		sub    *subtitle.Subtitle
+		layout subtitle.Layout
+		err    error
+	)
+	if stepParam.SubtitleFilePath != "" && stepParam.TtsSourceFilePath == stepParam.BilingualSrtFilePath {
+		sub, err = subtitle.LoadJson(stepParam.SubtitleFilePath)
+		layout = bilingualLayout(stepParam)
+	} else {
+		// 说话人前缀不需要读出来
+		sub, err = subtitle.ReadFile(stepParam.TtsSourceFilePath, subtitle.FormatSrt, subtitle.Options{Layout: subtitle.LayoutOrigin, SpeakerPrefix: stepParam.EnableDiarization})
+		layout = subtitle.LayoutOrigin
+	}
 	if err != nil {
-		return nil, fmt.Errorf("parseSRT read file error: %w", err)
+		return nil, fmt.Errorf("ttsSentences read subtitle error: %w", err)
 	}
 
 	var subtitles []types.SrtSentenceWithStrTime
-	re := regexp.MustCompile(`(\d{2}:\d{2}:\d{2},\d{3}) --> (\d{2}:\d{2}:\d{2},\d{3})\s+(.+?)\n`)
-	matches := re.FindAllStringSubmatch(string(data), -1)
-
-	for _, match := range matches {
+	for _, cue := range sub.Cues {
+		parts := cue.Parts(layout)
+		if len(parts) == 0 {
+			continue
+		}
+		// 纯文本srt无法区分语言，只取第一行
+		text := parts[0]
+		if layout == subtitle.LayoutOrigin {
+			text, _, _ = strings.Cut(text, "\n")
+		}
 		subtitles = append(subtitles, types.SrtSentenceWithStrTime{
-			Start: match[1],
-			End:   match[2],
-			Text:  strings.Replace(match[3], "\n", " ", -1), // 去除换行
+			Start: subtitle.FormatSrtTime(cue.Start),
+			End:   subtitle.FormatSrtTime(cue.End),
+			Text:  subtitle.JoinLines(text), // 去除换行
 		})
 	}
-
 	return subtitles, nil
 }
 
@@ -1,13 +1,13 @@
 package service
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
 	"krillin-ai/internal/storage"
 	"krillin-ai/internal/types"
 	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
 	"krillin-ai/pkg/util"
 	"os"
 	"os/exec"
@@ -45,6 +45,8 @@ func (s Service) embedSubtitles(ctx context.Context, stepParam *types.SubtitleTa
 			}
 		}
 		if stepParam.EmbedSubtitleVideoType == "vertical" || stepParam.EmbedSubtitleVideoType == "all" {
+			// 使用副本，保留源视频路径供修改字幕后重新合成
+			verticalParam := *stepParam
 			if width > height {
 				// 生成竖屏视频
 				transferredVerticalVideoPath := filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskTransferredVerticalVideoFileName)
@@ -53,10 +55,10 @@ func (s Service) embedSubtitles(ctx context.Context, stepParam *types.SubtitleTa
 					log.GetLogger().Error("embedSubtitles convertToVertical error", zap.Any("step param", stepParam), zap.Error(err))
 					return fmt.Errorf("embedSubtitles convertToVertical error: %w", err)
 				}
-				stepParam.InputVideoPath = transferredVerticalVideoPath
+				verticalParam.InputVideoPath = transferredVerticalVideoPath
 			}
 			log.GetLogger().Info("合成视频：竖屏")
-			err = embedSubtitles(stepParam, false, stepParam.EnableTts)
+			err = embedSubtitles(&verticalParam, false, stepParam.EnableTts)
 			if err != nil {
 				log.GetLogger().Error("embedSubtitles embedSubtitles error", zap.Any("step param", stepParam), zap.Error(err))
 				return fmt.Errorf("embedSubtitles embedSubtitles error: %w", err)
@@ -69,100 +71,6 @@ func (s Service) embedSubtitles(ctx context.Context, stepParam *types.SubtitleTa
 	return nil
 }
 
-func splitMajorTextInHorizontal(text string, language types.StandardLanguageCode, maxWordOneLine int) []string {
-	// 按语言情况分割
-	var (
-		segments []string
-		sep      string
-	)
-	if language == types.LanguageNameSimplifiedChinese || language == types.LanguageNameTraditionalChinese ||
-		language == types.LanguageNameJapanese || language == types.LanguageNameKorean || language == types.LanguageNameThai {
-		segments = regexp.MustCompile(`.`).FindAllString(text, -1)
-		sep = ""
-	} else {
-		segments = strings.Split(text, " ")
-		sep = " "
-	}
-
-	totalWidth := len(segments)
-
-	// 直接返回原句子
-	if totalWidth <= maxWordOneLine {
-		return []string{text}
-	}
-
-	// 确定拆分点，按2/5和3/5的比例拆分
-	line1MaxWidth := int(float64(totalWidth) * 2 / 5)
-	currentWidth := 0
-	splitIndex := 0
-
-	for i := range segments {
-		currentWidth++
-
-		// 当达到 2/5 宽度时，设置拆分点
-		if currentWidth >= line1MaxWidth {
-			splitIndex = i + 1
-			break
-		}
-	}
-
-	// 分割文本，保留原有句子格式
-
-	line1 := util.CleanPunction(strings.Join(segments[:splitIndex], sep))
-	line2 := util.CleanPunction(strings.Join(segments[splitIndex:], sep))
-
-	return []string{line1, line2}
-}
-
-func splitChineseText(text string, maxWordLine int) []string {
-	var lines []string
-	words := []rune(text)
-	for i := 0; i < len(words); i += maxWordLine {
-		end := i + maxWordLine
-		if end > len(words) {
-			end = len(words)
-		}
-		lines = append(lines, string(words[i:end]))
-	}
-	return lines
-}
-
-func parseSrtTime(timeStr string) (time.Duration, error) {
-	timeStr = strings.Replace(timeStr, ",", ".", 1)
-	parts := strings.Split(timeStr, ":")
-	if len(parts) != 3 {
-		return 0, fmt.Errorf("parseSrtTime invalid time format: %s", timeStr)
-	}
-
-	hours, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, err
-	}
-	minutes, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, err
-	}
-	secondsAndMilliseconds := strings.Split(parts[2], ".")
-	if len(secondsAndMilliseconds) != 2 {
-		return 0, fmt.Errorf("invalid time format: %s", timeStr)
-	}
-	seconds, err := strconv.Atoi(secondsAndMilliseconds[0])
-	if err != nil {
-		return 0, err
-	}
-	milliseconds, err := strconv.Atoi(secondsAndMilliseconds[1])
-	if err != nil {
-		return 0, err
-	}
-
-	duration := time.Duration(hours)*time.Hour +
-		time.Duration(minutes)*time.Minute +
-		time.Duration(seconds)*time.Second +
-		time.Duration(milliseconds)*time.Millisecond
-
-	return duration, nil
-}
-
 func formatTimestamp(t time.Duration) string {
 	hours := int(t.Hours())
 	minutes := int(t.Minutes()) % 60
@@ -171,139 +79,116 @@ func formatTimestamp(t time.Duration) string {
 	return fmt.Sprintf("%02d:%02d:%02d.%02d", hours, minutes, seconds, milliseconds)
 }
 
-func srtToAss(inputSRT, outputASS string, isHorizontal bool, stepParam *types.SubtitleTaskStepParam) error {
-	file, err := os.Open(inputSRT)
-	if err != nil {
-		log.GetLogger().Error("srtToAss Open input srt error", zap.Error(err))
-		return fmt.Errorf("srtToAss Open input srt error: %w", err)
-	}
-	defer file.Close()
-
+// subtitleToAss 把结构化字幕写为用于压制的ass，横屏为双语两行，竖屏为单语并按长度拆分
+func subtitleToAss(sub *subtitle.Subtitle, outputASS string, isHorizontal bool, stepParam *types.SubtitleTaskStepParam) error {
 	assFile, err := os.Create(outputASS)
 	if err != nil {
-		log.GetLogger().Error("srtToAss Create output ass error", zap.Error(err))
-		return fmt.Errorf("srtToAss Create output ass error: %w", err)
+		log.GetLogger().Error("subtitleToAss Create output ass error", zap.Error(err))
+		return fmt.Errorf("subtitleToAss Create output ass error: %w", err)
 	}
 	defer assFile.Close()
-	scanner := bufio.NewScanner(file)
 
-	if isHorizontal {
-		_, _ = assFile.WriteString(types.AssHeaderHorizontal)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
-				continue
-			}
-			// 读取时间戳行
-			if !scanner.Scan() {
-				break
-			}
-			timestampLine := scanner.Text()
-			parts := strings.Split(timestampLine, " --> ")
-			if len(parts) != 2 {
-				continue // 无效时间戳格式
-			}
-
-			startTimeStr := strings.TrimSpace(parts[0])
-			endTimeStr := strings.TrimSpace(parts[1])
-			startTime, err := parseSrtTime(startTimeStr)
-			if err != nil {
-				log.GetLogger().Error("srtToAss parseSrtTime error", zap.Error(err))
-				return fmt.Errorf("srtToAss parseSrtTime error: %w", err)
-			}
-			endTime, err := parseSrtTime(endTimeStr)
-			if err != nil {
-				log.GetLogger().Error("srtToAss parseSrtTime error", zap.Error(err))
-				return fmt.Errorf("srtToAss parseSrtTime error: %w", err)
-			}
+	// 按说话人区分样式时，先收集所有说话人用于生成样式
+	var speakers []string
+	useSpeakerStyle := stepParam.EnableDiarization && stepParam.SpeakerLabelMode == types.SpeakerLabelModeStyle
+	if useSpeakerStyle {
+		speakers = collectSubtitleSpeakers(sub)
+	}
 
-			var subtitleLines []string
-			for scanner.Scan() {
-				textLine := scanner.Text()
-				if textLine == "" {
-					break // 字幕块结束
-				}
-				subtitleLines = append(subtitleLines, textLine)
+	// 指定了字幕样式时按样式和视频分辨率生成头部
+	header := types.AssHeaderHorizontal
+	if !isHorizontal {
+		header = types.AssHeaderVertical
+	}
+	var majorStyle, minorStyle types.AssLineStyle
+	if stepParam.AssStyle != nil {
+		width, height := assResolution(stepParam, isHorizontal)
+		header = buildStyledAssHeader(stepParam.AssStyle, stepParam, width, height)
+		majorStyle, minorStyle = assLineStyles(stepParam.AssStyle, stepParam)
+	}
+	_, _ = assFile.WriteString(buildSpeakerAssHeader(header, speakers))
+
+	layout := bilingualLayout(stepParam)
+	lineBreaker := readabilityConstraints()
+	for _, cue := range sub.Cues {
+		// 上方文字为第一部分，双语时下方文字为第二部分
+		var majorText, minorText string
+		majorLanguage, minorLanguage := stepParam.OriginLanguage, stepParam.TargetLanguage
+		if layout == subtitle.LayoutTargetFirst {
+			majorText, minorText = cue.TranslatedText, cue.OriginText
+			majorLanguage, minorLanguage = stepParam.TargetLanguage, stepParam.OriginLanguage
+		} else {
+			majorText, minorText = cue.OriginText, cue.TranslatedText
+		}
+		// 竖屏按自己的规则拆分，使用合并成一行的文字
+		verticalText := subtitle.JoinLines(majorText)
+		// 横屏按配置的每行字符数换行，已经换好行且不超长的文字保持不变
+		if isHorizontal {
+			majorText = lineBreaker.Wrap(majorText, string(majorLanguage))
+			minorText = lineBreaker.Wrap(minorText, string(minorLanguage))
+		}
+		majorText = strings.ReplaceAll(majorText, "\n", "\\N")
+		minorText = strings.ReplaceAll(minorText, "\n", "\\N")
+		startFormatted := formatTimestamp(cue.Start)
+		endFormatted := formatTimestamp(cue.End)
+
+		// 说话人前缀只在上方保留一次，样式模式下改用说话人样式
+		var speaker, styleSuffix string
+		if stepParam.EnableDiarization {
+			speaker = cue.Speaker
+			if useSpeakerStyle {
+				styleSuffix = speakerAssStyleSuffix(speaker, speakers)
+				speaker = ""
 			}
+		}
 
-			if len(subtitleLines) < 2 {
+		if isHorizontal {
+			if majorText == "" || minorText == "" {
 				continue
 			}
-			//var majorTextLanguage types.StandardLanguageCode
-			//if stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop { // 一定是bilingual
-			//	majorTextLanguage = stepParam.TargetLanguage
-			//} else {
-			//	majorTextLanguage = stepParam.OriginLanguage
-			//}
-
-			//majorLine := strings.Join(splitMajorTextInHorizontal(subtitleLines[0], majorTextLanguage, stepParam.MaxWordOneLine), "      \\N")
-
-			// ASS条目
-			startFormatted := formatTimestamp(startTime)
-			endFormatted := formatTimestamp(endTime)
-			combinedText := fmt.Sprintf("{\\an2}{\\rMajor}%s\\N{\\rMinor}%s", subtitleLines[0], util.CleanPunction(subtitleLines[1]))
-			_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Major,,0,0,0,,%s\n", startFormatted, endFormatted, combinedText))
-		}
-	} else {
-		// TODO 竖屏拆分调优
-		_, _ = assFile.WriteString(types.AssHeaderVertical)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if line == "" {
+			majorText = addSpeakerPrefix(majorText, speaker)
+			if stepParam.AssStyle != nil && majorStyle.Position != minorStyle.Position {
+				// 两种语言分别显示在画面上方和下方，使用各自样式的对齐方式
+				_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Major%s,,0,0,0,,%s\n", startFormatted, endFormatted, styleSuffix, majorText))
+				_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Minor%s,,0,0,0,,%s\n", startFormatted, endFormatted, styleSuffix, util.CleanPunction(minorText)))
 				continue
 			}
-			if !scanner.Scan() {
-				break
-			}
-			timestampLine := scanner.Text()
-			parts := strings.Split(timestampLine, " --> ")
-			if len(parts) != 2 {
-				continue // 无效时间戳格式
-			}
-
-			startTimeStr := strings.TrimSpace(parts[0])
-			endTimeStr := strings.TrimSpace(parts[1])
-			startTime, err := parseSrtTime(startTimeStr)
-			if err != nil {
-				return err
-			}
-			endTime, err := parseSrtTime(endTimeStr)
-			if err != nil {
-				return err
-			}
-
-			var content string
-			scanner.Scan()
-			content = scanner.Text()
-			if content == "" {
-				continue
+			alignment := 2
+			if stepParam.AssStyle != nil {
+				alignment = assAlignment(majorStyle.Position)
 			}
-			totalTime := endTime - startTime
-
-			if !util.ContainsAlphabetic(content) {
-				// 处理中文字幕
-				chineseLines := splitChineseText(content, 10)
-				for i, line := range chineseLines {
-					iStart := startTime + time.Duration(float64(i)*float64(totalTime)/float64(len(chineseLines)))
-					iEnd := startTime + time.Duration(float64(i+1)*float64(totalTime)/float64(len(chineseLines)))
-					if iEnd > endTime {
-						iEnd = endTime
-					}
+			combinedText := fmt.Sprintf("{\\an%d}{\\rMajor%s}%s\\N{\\rMinor%s}%s", alignment, styleSuffix, majorText, styleSuffix, util.CleanPunction(minorText))
+			_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Major%s,,0,0,0,,%s\n", startFormatted, endFormatted, styleSuffix, combinedText))
+			continue
+		}
 
-					startFormatted := formatTimestamp(iStart)
-					endFormatted := formatTimestamp(iEnd)
-					cleanedText := util.CleanPunction(line)
-					combinedText := fmt.Sprintf("{\\an2}{\\rMajor}%s", cleanedText)
-					_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Major,,0,0,0,,%s\n", startFormatted, endFormatted, combinedText))
+		// TODO 竖屏拆分调优
+		content := verticalText
+		if content == "" {
+			continue
+		}
+		totalTime := cue.End - cue.Start
+		if !util.ContainsAlphabetic(content) {
+			// 处理中文字幕
+			chineseLines := subtitle.BreakLines(content, 10, string(majorLanguage))
+			for i, line := range chineseLines {
+				iStart := cue.Start + time.Duration(float64(i)*float64(totalTime)/float64(len(chineseLines)))
+				iEnd := cue.Start + time.Duration(float64(i+1)*float64(totalTime)/float64(len(chineseLines)))
+				if iEnd > cue.End {
+					iEnd = cue.End
+				}
+				cleanedText := util.CleanPunction(line)
+				if i == 0 {
+					cleanedText = addSpeakerPrefix(cleanedText, speaker)
 				}
-			} else {
-				// 处理英文字幕
-				startFormatted := formatTimestamp(startTime)
-				endFormatted := formatTimestamp(endTime)
-				cleanedText := util.CleanPunction(content)
-				combinedText := fmt.Sprintf("{\\an2}{\\rMinor}%s", cleanedText)
-				_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Minor,,0,0,0,,%s\n", startFormatted, endFormatted, combinedText))
+				combinedText := fmt.Sprintf("{\\an2}{\\rMajor%s}%s", styleSuffix, cleanedText)
+				_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Major%s,,0,0,0,,%s\n", formatTimestamp(iStart), formatTimestamp(iEnd), styleSuffix, combinedText))
 			}
+		} else {
+			// 处理英文字幕
+			cleanedText := addSpeakerPrefix(util.CleanPunction(content), speaker)
+			combinedText := fmt.Sprintf("{\\an2}{\\rMinor%s}%s", styleSuffix, cleanedText)
+			_, _ = assFile.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,Minor%s,,0,0,0,,%s\n", startFormatted, endFormatted, styleSuffix, combinedText))
 		}
 	}
 	return nil
@@ -316,9 +201,14 @@ func embedSubtitles(stepParam *types.SubtitleTaskStepParam, isHorizontal bool, w
 	}
 	assPath := filepath.Join(stepParam.TaskBasePath, "formatted_subtitles.ass")
 
-	if err := srtToAss(stepParam.BilingualSrtFilePath, assPath, isHorizontal, stepParam); err != nil {
-		log.GetLogger().Error("embedSubtitles srtToAss error", zap.Any("step param", stepParam), zap.Error(err))
-		return fmt.Errorf("embedSubtitles srtToAss error: %w", err)
+	sub, err := loadTaskSubtitle(stepParam)
+	if err != nil {
+		log.GetLogger().Error("embedSubtitles loadTaskSubtitle error", zap.Any("step param", stepParam), zap.Error(err))
+		return fmt.Errorf("embedSubtitles loadTaskSubtitle error: %w", err)
+	}
+	if err = subtitleToAss(sub, assPath, isHorizontal, stepParam); err != nil {
+		log.GetLogger().Error("embedSubtitles subtitleToAss error", zap.Any("step param", stepParam), zap.Error(err))
+		return fmt.Errorf("embedSubtitles subtitleToAss error: %w", err)
 	}
 	input := stepParam.InputVideoPath
 	if withTts {
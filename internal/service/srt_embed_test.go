@@ -0,0 +1,53 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"krillin-ai/pkg/subtitle"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubtitleToAssWrapsHorizontalLines(t *testing.T) {
+	sub := &subtitle.Subtitle{Cues: []*subtitle.Cue{
+		{
+			Index:          1,
+			Start:          0,
+			End:            4 * time.Second,
+			OriginText:     "Today we are going to talk about the new features of this release",
+			TranslatedText: "今天我们来聊聊这个版本带来的新功能和一些改进",
+		},
+		{Index: 2, Start: 4 * time.Second, End: 6 * time.Second, OriginText: "Short line", TranslatedText: "短句"},
+	}}
+	stepParam := &types.SubtitleTaskStepParam{
+		OriginLanguage:     types.LanguageNameEnglish,
+		TargetLanguage:     types.LanguageNameSimplifiedChinese,
+		SubtitleResultType: types.SubtitleResultTypeBilingualTranslationOnBottom,
+	}
+	assPath := filepath.Join(t.TempDir(), "out.ass")
+	if err := subtitleToAss(sub, assPath, true, stepParam); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(assPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dialogues []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Dialogue:") {
+			dialogues = append(dialogues, line)
+		}
+	}
+	if len(dialogues) != 2 {
+		t.Fatalf("got %d dialogue lines", len(dialogues))
+	}
+	// 长句按每行最多字符数拆成两行，原文和译文之间还有一个换行
+	if got := strings.Count(dialogues[0], `\N`); got != 3 {
+		t.Errorf("long cue has %d line breaks, want 3: %s", got, dialogues[0])
+	}
+	if got := strings.Count(dialogues[1], `\N`); got != 1 {
+		t.Errorf("short cue has %d line breaks, want 1: %s", got, dialogues[1])
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+)
+
+const styleProfileDir = "./style_profiles"
+
+var styleProfileStore = namedStore[types.StyleProfile]{
+	dir:      styleProfileDir,
+	label:    "风格配置",
+	builtins: types.BuiltinStyleProfiles,
+	nameOf:   func(profile *types.StyleProfile) string { return profile.Name },
+	validate: (*types.StyleProfile).Validate,
+}
+
+func (s Service) SaveStyleProfile(profile *types.StyleProfile) error {
+	return styleProfileStore.save(profile)
+}
+
+// LoadStyleProfile 读取已保存的风格配置，不存在时使用同名的内置配置
+func (s Service) LoadStyleProfile(name string) (*types.StyleProfile, error) {
+	return styleProfileStore.load(name)
+}
+
+// ListStyleProfiles 列出内置和已保存的风格配置
+func (s Service) ListStyleProfiles() ([]*types.StyleProfile, error) {
+	return styleProfileStore.list()
+}
+
+// DeleteStyleProfile 删除已保存的风格配置，内置配置无法删除
+func (s Service) DeleteStyleProfile(name string) error {
+	return styleProfileStore.delete(name)
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"krillin-ai/internal/dto"
+	"krillin-ai/internal/storage"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
+	"krillin-ai/pkg/util"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// 保证检查任务状态和保存修改不会同时进行
+var subtitleEditMutex sync.Mutex
+
+// saveStepParam 保存任务完成时的参数，修改字幕后据此重新执行后续步骤
+func saveStepParam(stepParam *types.SubtitleTaskStepParam) error {
+	return util.SaveToDisk(stepParam, filepath.Join(stepParam.TaskBasePath, types.SubtitleTaskStepParamPersistenceFileName))
+}
+
+func readStepParam(taskBasePath string) (*types.SubtitleTaskStepParam, error) {
+	data, err := os.ReadFile(filepath.Join(taskBasePath, types.SubtitleTaskStepParamPersistenceFileName))
+	if err != nil {
+		return nil, err
+	}
+	var stepParam types.SubtitleTaskStepParam
+	if err = json.Unmarshal(data, &stepParam); err != nil {
+		return nil, err
+	}
+	if stepParam.TaskPtr == nil {
+		return nil, errors.New("task info is empty")
+	}
+	// 替换器不能序列化，按保存的规则重建
+	if err = setStepParamReplacers(&stepParam); err != nil {
+		return nil, err
+	}
+	return &stepParam, nil
+}
+
+// loadStepParam 读取已完成任务的参数，editParam为要修改的目标语言的参数，language为空时为第一个目标语言
+func loadStepParam(taskId, language string) (mainParam, editParam *types.SubtitleTaskStepParam, err error) {
+	if taskId == "" || taskId != filepath.Base(taskId) || strings.HasPrefix(taskId, ".") {
+		return nil, nil, errors.New("任务id不合法")
+	}
+	mainParam, err = readStepParam(filepath.Join("./tasks", taskId))
+	if err != nil {
+		log.GetLogger().Error("loadStepParam readStepParam err", zap.String("taskId", taskId), zap.Error(err))
+		return nil, nil, errors.New("任务不存在或尚未完成")
+	}
+	// 优先使用内存中的任务信息，服务重启后用保存的任务信息恢复
+	if task, ok := storage.SubtitleTasks.Load(taskId); ok && task != nil {
+		mainParam.TaskPtr = task.(*types.SubtitleTask)
+	} else {
+		storage.SubtitleTasks.Store(taskId, mainParam.TaskPtr)
+	}
+
+	lang := types.StandardLanguageCode(language)
+	if language == "" || lang == mainParam.TargetLanguage {
+		return mainParam, mainParam, nil
+	}
+	if !lo.Contains(mainParam.TargetLanguages, lang) {
+		return nil, nil, fmt.Errorf("任务没有目标语言%s", language)
+	}
+	editParam, err = readStepParam(filepath.Join(mainParam.TaskBasePath, language))
+	if err != nil {
+		log.GetLogger().Error("loadStepParam readStepParam err", zap.String("taskId", taskId), zap.String("language", language), zap.Error(err))
+		return nil, nil, fmt.Errorf("目标语言%s的字幕不存在", language)
+	}
+	editParam.TaskPtr = mainParam.TaskPtr
+	return mainParam, editParam, nil
+}
+
+func (s Service) GetSubtitleCues(req dto.GetSubtitleCuesReq) (*dto.GetSubtitleCuesResData, error) {
+	subtitleEditMutex.Lock()
+	_, editParam, err := loadStepParam(req.TaskId, req.Language)
+	if err != nil {
+		subtitleEditMutex.Unlock()
+		return nil, err
+	}
+	sub, err := loadTaskSubtitle(editParam)
+	subtitleEditMutex.Unlock()
+	if err != nil {
+		log.GetLogger().Error("GetSubtitleCues loadTaskSubtitle err", zap.String("taskId", req.TaskId), zap.Error(err))
+		return nil, errors.New("读取字幕失败")
+	}
+	return &dto.GetSubtitleCuesResData{
+		TaskId:   editParam.TaskId,
+		Language: string(editParam.TargetLanguage),
+		Cues: lo.Map(sub.Cues, func(cue *subtitle.Cue, _ int) *dto.SubtitleCue {
+			return &dto.SubtitleCue{
+				Index:              cue.Index,
+				Start:              cue.Start.Seconds(),
+				End:                cue.End.Seconds(),
+				OriginText:         cue.OriginText,
+				TranslatedText:     cue.TranslatedText,
+				Speaker:            cue.Speaker,
+				Confidence:         cue.Confidence,
+				LowConfidenceWords: cue.LowConfidenceWords,
+			}
+		}),
+	}, nil
+}
+
+// UpdateSubtitleCues 修改字幕的文字或时间，重新生成字幕文件后在后台重新配音和合成视频
+func (s Service) UpdateSubtitleCues(req dto.UpdateSubtitleCuesReq) (*dto.UpdateSubtitleCuesResData, error) {
+	if len(req.Cues) == 0 {
+		return nil, errors.New("没有需要修改的字幕")
+	}
+	subtitleEditMutex.Lock()
+	defer subtitleEditMutex.Unlock()
+
+	mainParam, editParam, err := loadStepParam(req.TaskId, req.Language)
+	if err != nil {
+		return nil, err
+	}
+	if mainParam.TaskPtr.Status == types.SubtitleTaskStatusProcessing {
+		return nil, errors.New("任务处理中，请稍后再修改")
+	}
+	sub, err := loadTaskSubtitle(editParam)
+	if err != nil {
+		log.GetLogger().Error("UpdateSubtitleCues loadTaskSubtitle err", zap.String("taskId", req.TaskId), zap.Error(err))
+		return nil, errors.New("读取字幕失败")
+	}
+	var oldSentences []types.SrtSentenceWithStrTime
+	if editParam.EnableTts {
+		if oldSentences, err = ttsSentences(editParam); err != nil {
+			log.GetLogger().Error("UpdateSubtitleCues ttsSentences err", zap.String("taskId", req.TaskId), zap.Error(err))
+			return nil, errors.New("读取配音字幕失败")
+		}
+	}
+	if err = applyCuePatches(sub, req.Cues); err != nil {
+		return nil, err
+	}
+	// 人工修改的字幕不再自动调整，只更新可读性报告
+	if editParam.ReadabilityReportFilePath != "" {
+		if err = writeReadabilityReport(editParam, len(sub.Cues), sub.CheckReadability(readabilityConstraints())); err != nil {
+			log.GetLogger().Warn("UpdateSubtitleCues writeReadabilityReport err", zap.String("taskId", req.TaskId), zap.Error(err))
+		}
+	}
+
+	// 保存修改并重新生成各字幕文件，文件路径不变，沿用原有的字幕信息
+	if editParam.SubtitleFilePath == "" {
+		editParam.SubtitleFilePath = filepath.Join(editParam.TaskBasePath, types.SubtitleTaskSubtitleDataFileName)
+	}
+	if err = subtitle.SaveJson(editParam.SubtitleFilePath, sub); err != nil {
+		log.GetLogger().Error("UpdateSubtitleCues SaveJson err", zap.String("taskId", req.TaskId), zap.Error(err))
+		return nil, errors.New("保存字幕失败")
+	}
+	if err = subtitle.WriteFile(editParam.BilingualSrtFilePath, sub, subtitle.FormatSrt, srtOptions(editParam, bilingualLayout(editParam))); err != nil {
+		log.GetLogger().Error("UpdateSubtitleCues write bilingual srt err", zap.String("taskId", req.TaskId), zap.Error(err))
+		return nil, errors.New("生成字幕文件失败")
+	}
+	subtitleInfos := editParam.SubtitleInfos
+	err = splitSrt(editParam)
+	editParam.SubtitleInfos = subtitleInfos
+	if err != nil {
+		log.GetLogger().Error("UpdateSubtitleCues splitSrt err", zap.String("taskId", req.TaskId), zap.Error(err))
+		return nil, errors.New("生成字幕文件失败")
+	}
+	if err = saveStepParam(editParam); err != nil {
+		log.GetLogger().Warn("UpdateSubtitleCues saveStepParam err", zap.String("taskId", req.TaskId), zap.Error(err))
+	}
+
+	// 只为文字有变化的句子重新配音，时间变化只需要重新调整时长
+	var (
+		sentences  []types.SrtSentenceWithStrTime
+		regenerate map[int]bool
+		ttsChanged bool
+		ttsCueNum  int
+	)
+	if editParam.EnableTts {
+		if sentences, err = ttsSentences(editParam); err != nil {
+			log.GetLogger().Error("UpdateSubtitleCues ttsSentences err", zap.String("taskId", req.TaskId), zap.Error(err))
+			return nil, errors.New("读取配音字幕失败")
+		}
+		regenerate, ttsChanged = changedSentences(oldSentences, sentences)
+		ttsCueNum = len(regenerate)
+		if regenerate == nil {
+			ttsCueNum = len(sentences)
+		}
+	}
+
+	mainParam.TaskPtr.Status = types.SubtitleTaskStatusProcessing
+	mainParam.TaskPtr.FailReason = ""
+	mainParam.TaskPtr.ProcessPct = 90
+	go s.rerunAfterSubtitleEdit(mainParam, editParam, sentences, regenerate, ttsChanged)
+
+	return &dto.UpdateSubtitleCuesResData{
+		TaskId:        mainParam.TaskId,
+		ChangedCueNum: len(req.Cues),
+		TtsCueNum:     ttsCueNum,
+	}, nil
+}
+
+// applyCuePatches 把修改应用到字幕上，任意一条不合法或改动的时间与相邻字幕重叠时返回错误
+func applyCuePatches(sub *subtitle.Subtitle, patches []dto.SubtitleCuePatch) error {
+	cueMap := lo.KeyBy(sub.Cues, func(cue *subtitle.Cue) int { return cue.Index })
+	timeChanged := make(map[int]bool)
+	for _, patch := range patches {
+		cue, ok := cueMap[patch.Index]
+		if !ok {
+			return fmt.Errorf("字幕%d不存在", patch.Index)
+		}
+		start, end := cue.Start, cue.End
+		if patch.Start != nil {
+			start = secondsToDuration(*patch.Start)
+		}
+		if patch.End != nil {
+			end = secondsToDuration(*patch.End)
+		}
+		if start < 0 || end <= start {
+			return fmt.Errorf("字幕%d的时间不合法", patch.Index)
+		}
+		if start != cue.Start || end != cue.End {
+			timeChanged[patch.Index] = true
+		}
+		cue.Start, cue.End = start, end
+		if patch.OriginText != nil {
+			cue.OriginText = strings.TrimSpace(*patch.OriginText)
+			// 人工校对过的原文不再标记低置信度
+			cue.LowConfidenceWords = nil
+		}
+		if patch.TranslatedText != nil {
+			cue.TranslatedText = strings.TrimSpace(*patch.TranslatedText)
+		}
+	}
+	// 只检查改过时间的字幕，原有的重叠不影响修改文字
+	for i := 1; i < len(sub.Cues); i++ {
+		previous, cue := sub.Cues[i-1], sub.Cues[i]
+		if (timeChanged[previous.Index] || timeChanged[cue.Index]) && cue.Start < previous.End {
+			return fmt.Errorf("字幕%d与字幕%d的时间重叠", previous.Index, cue.Index)
+		}
+	}
+	return nil
+}
+
+// changedSentences 对比修改前后的配音句子，返回需要重新合成的句子序号和是否有任何变化，句子数量变化时返回nil表示全部重新合成
+func changedSentences(before, after []types.SrtSentenceWithStrTime) (map[int]bool, bool) {
+	if len(before) != len(after) {
+		return nil, true
+	}
+	regenerate := make(map[int]bool)
+	changed := false
+	for i := range after {
+		if before[i].Text != after[i].Text {
+			regenerate[i] = true
+		}
+		if before[i] != after[i] {
+			changed = true
+		}
+	}
+	return regenerate, changed
+}
+
+// rerunAfterSubtitleEdit 字幕修改后重新配音、合成视频并更新任务结果
+func (s Service) rerunAfterSubtitleEdit(mainParam, editParam *types.SubtitleTaskStepParam, sentences []types.SrtSentenceWithStrTime, regenerate map[int]bool, ttsChanged bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.GetLogger().Error("rerunAfterSubtitleEdit panic", zap.Any("panic:", r), zap.Any("stack:", buf))
+			mainParam.TaskPtr.Status = types.SubtitleTaskStatusFailed
+		}
+	}()
+	ctx := context.Background()
+	log.GetLogger().Info("rerunAfterSubtitleEdit start", zap.String("taskId", editParam.TaskId), zap.Any("language", editParam.TargetLanguage))
+	if editParam.EnableTts && ttsChanged {
+		if err := s.sentencesToSpeech(sentences, regenerate, editParam); err != nil {
+			log.GetLogger().Error("rerunAfterSubtitleEdit sentencesToSpeech err", zap.String("taskId", editParam.TaskId), zap.Error(err))
+			mainParam.TaskPtr.Status = types.SubtitleTaskStatusFailed
+			mainParam.TaskPtr.FailReason = err.Error()
+			return
+		}
+	}
+	if err := s.embedSubtitles(ctx, editParam); err != nil {
+		log.GetLogger().Error("rerunAfterSubtitleEdit embedSubtitles err", zap.String("taskId", editParam.TaskId), zap.Error(err))
+		mainParam.TaskPtr.Status = types.SubtitleTaskStatusFailed
+		mainParam.TaskPtr.FailReason = err.Error()
+		return
+	}
+	if err := s.uploadSubtitles(ctx, mainParam); err != nil {
+		log.GetLogger().Error("rerunAfterSubtitleEdit uploadSubtitles err", zap.String("taskId", editParam.TaskId), zap.Error(err))
+		mainParam.TaskPtr.Status = types.SubtitleTaskStatusFailed
+		mainParam.TaskPtr.FailReason = err.Error()
+		return
+	}
+	log.GetLogger().Info("rerunAfterSubtitleEdit end", zap.String("taskId", editParam.TaskId), zap.Any("language", editParam.TargetLanguage))
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"krillin-ai/internal/dto"
+	"krillin-ai/internal/types"
+	"krillin-ai/pkg/subtitle"
+	"krillin-ai/pkg/util"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func editTestSubtitle() *subtitle.Subtitle {
+	return &subtitle.Subtitle{Cues: []*subtitle.Cue{
+		{Index: 1, Start: 0, End: 2 * time.Second, OriginText: "one", TranslatedText: "一", LowConfidenceWords: []string{"one"}},
+		{Index: 2, Start: 2 * time.Second, End: 4 * time.Second, OriginText: "two", TranslatedText: "二"},
+		{Index: 3, Start: 4 * time.Second, End: 6 * time.Second, OriginText: "three", TranslatedText: "三"},
+	}}
+}
+
+func TestApplyCuePatches(t *testing.T) {
+	seconds := func(v float64) *float64 { return &v }
+	text := func(v string) *string { return &v }
+	tests := []struct {
+		name    string
+		patches []dto.SubtitleCuePatch
+		wantErr bool
+		check   func(t *testing.T, sub *subtitle.Subtitle)
+	}{
+		{
+			name:    "text",
+			patches: []dto.SubtitleCuePatch{{Index: 1, OriginText: text(" uno "), TranslatedText: text("壹")}},
+			check: func(t *testing.T, sub *subtitle.Subtitle) {
+				cue := sub.Cues[0]
+				if cue.OriginText != "uno" || cue.TranslatedText != "壹" || cue.LowConfidenceWords != nil || cue.End != 2*time.Second {
+					t.Errorf("unexpected cue %+v", cue)
+				}
+			},
+		},
+		{
+			name:    "shorten and move",
+			patches: []dto.SubtitleCuePatch{{Index: 2, Start: seconds(2.5), End: seconds(3.5)}},
+			check: func(t *testing.T, sub *subtitle.Subtitle) {
+				if sub.Cues[1].Start != 2500*time.Millisecond || sub.Cues[1].End != 3500*time.Millisecond {
+					t.Errorf("unexpected cue %+v", sub.Cues[1])
+				}
+			},
+		},
+		{
+			name:    "move two cues together",
+			patches: []dto.SubtitleCuePatch{{Index: 1, End: seconds(3)}, {Index: 2, Start: seconds(3)}},
+		},
+		{name: "unknown cue", patches: []dto.SubtitleCuePatch{{Index: 9, TranslatedText: text("九")}}, wantErr: true},
+		{name: "end before start", patches: []dto.SubtitleCuePatch{{Index: 2, Start: seconds(3), End: seconds(3)}}, wantErr: true},
+		{name: "negative start", patches: []dto.SubtitleCuePatch{{Index: 1, Start: seconds(-1)}}, wantErr: true},
+		{name: "overlaps next", patches: []dto.SubtitleCuePatch{{Index: 2, End: seconds(4.5)}}, wantErr: true},
+		{name: "overlaps previous", patches: []dto.SubtitleCuePatch{{Index: 3, Start: seconds(3.9)}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := editTestSubtitle()
+			err := applyCuePatches(sub, tt.patches)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyCuePatches() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.check != nil {
+				tt.check(t, sub)
+			}
+		})
+	}
+}
+
+func TestApplyCuePatchesKeepsExistingOverlap(t *testing.T) {
+	sub := editTestSubtitle()
+	sub.Cues[2].Start = 3 * time.Second
+	text := "贰"
+	if err := applyCuePatches(sub, []dto.SubtitleCuePatch{{Index: 2, TranslatedText: &text}}); err != nil {
+		t.Errorf("editing text of an overlapping cue: %v", err)
+	}
+}
+
+func TestChangedSentences(t *testing.T) {
+	before := []types.SrtSentenceWithStrTime{
+		{Text: "一", Start: "00:00:00,000", End: "00:00:02,000"},
+		{Text: "二", Start: "00:00:02,000", End: "00:00:04,000"},
+	}
+	tests := []struct {
+		name           string
+		after          []types.SrtSentenceWithStrTime
+		wantRegenerate map[int]bool
+		wantChanged    bool
+	}{
+		{"unchanged", before, map[int]bool{}, false},
+		{"text", []types.SrtSentenceWithStrTime{before[0], {Text: "贰", Start: "00:00:02,000", End: "00:00:04,000"}}, map[int]bool{1: true}, true},
+		{"time only", []types.SrtSentenceWithStrTime{{Text: "一", Start: "00:00:00,000", End: "00:00:01,500"}, before[1]}, map[int]bool{}, true},
+		{"count", before[:1], nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regenerate, changed := changedSentences(before, tt.after)
+			if !reflect.DeepEqual(regenerate, tt.wantRegenerate) || changed != tt.wantChanged {
+				t.Errorf("changedSentences() = (%v, %v), want (%v, %v)", regenerate, changed, tt.wantRegenerate, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestSaveReadStepParamReplaceRules(t *testing.T) {
+	rules := []types.ReplaceRule{
+		{From: "colour", To: "color"},
+		{From: `v(\d+)`, To: "version $1", Regex: true},
+	}
+	replacer, err := util.NewWordReplacer(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordReplacer, phraseReplacer := replacer.Split()
+	stepParam := &types.SubtitleTaskStepParam{
+		TaskId:         "task",
+		TaskPtr:        &types.SubtitleTask{TaskId: "task"},
+		TaskBasePath:   t.TempDir(),
+		ReplaceRules:   rules,
+		WordReplacer:   wordReplacer,
+		PhraseReplacer: phraseReplacer,
+	}
+	if err = saveStepParam(stepParam); err != nil {
+		t.Fatalf("saveStepParam() err = %v", err)
+	}
+
+	got, err := readStepParam(stepParam.TaskBasePath)
+	if err != nil {
+		t.Fatalf("readStepParam() err = %v", err)
+	}
+	if !reflect.DeepEqual(got.ReplaceRules, rules) {
+		t.Errorf("ReplaceRules = %+v, want %+v", got.ReplaceRules, rules)
+	}
+	if text := replaceText(got.WordReplacer, "the colour of v2"); text != "the color of v2" {
+		t.Errorf("WordReplacer.Replace() = %q", text)
+	}
+	if text := replaceText(got.PhraseReplacer, "the colour of v2"); text != "the colour of version 2" {
+		t.Errorf("PhraseReplacer.Replace() = %q", text)
+	}
+}
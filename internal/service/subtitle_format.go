@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"krillin-ai/internal/storage"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+var subtitleFormatNames = map[string]string{
+	subtitle.FormatVtt:  "WebVTT",
+	subtitle.FormatTtml: "TTML",
+	subtitle.FormatDfxp: "DFXP",
+	subtitle.FormatSbv:  "SBV",
+	// 剪辑软件交换格式
+	subtitle.FormatFcpxml: "FCPXML",
+	subtitle.FormatXmeml:  "Premiere XML",
+	subtitle.FormatScc:    "SCC",
+}
+
+// validateSubtitleFormats 校验任务指定的字幕导出格式，去重并转为小写
+func validateSubtitleFormats(formats []string, vttCueSettings string) ([]string, error) {
+	var result []string
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" || format == "srt" || lo.Contains(result, format) {
+			continue
+		}
+		if !subtitle.IsSupportedFormat(format) {
+			return nil, fmt.Errorf("不支持的字幕格式: %s", format)
+		}
+		result = append(result, format)
+	}
+	if err := subtitle.ValidateVttCueSettings(vttCueSettings); err != nil {
+		return nil, fmt.Errorf("webvtt字幕设置错误: %w", err)
+	}
+	return result, nil
+}
+
+// 字幕文件使用的语言标签，如zh_cn转为zh-CN
+func subtitleLanguageTag(code types.StandardLanguageCode) string {
+	lang, region, ok := strings.Cut(string(code), "_")
+	if !ok {
+		return lang
+	}
+	return lang + "-" + strings.ToUpper(region)
+}
+
+// exportSubtitleFormats 把结构化字幕按srt字幕的语言导出为任务指定的其他格式，并加入下载列表
+func exportSubtitleFormats(stepParam *types.SubtitleTaskStepParam, srtInfos []types.SubtitleFileInfo) {
+	sub, err := loadTaskSubtitle(stepParam)
+	if err != nil {
+		log.GetLogger().Warn("exportSubtitleFormats loadTaskSubtitle err", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		return
+	}
+	frameRate, width, height := editorVideoInfo(stepParam)
+	for _, info := range srtInfos {
+		// 双语字幕以目标语言标注
+		layout, language := subtitle.LayoutTarget, stepParam.TargetLanguage
+		if info.Path == stepParam.BilingualSrtFilePath {
+			layout = bilingualLayout(stepParam)
+		} else if info.LanguageIdentifier == string(stepParam.OriginLanguage) {
+			layout, language = subtitle.LayoutOrigin, stepParam.OriginLanguage
+		}
+		options := subtitle.Options{
+			Layout:         layout,
+			Language:       subtitleLanguageTag(language),
+			VttCueSettings: stepParam.VttCueSettings,
+			FrameRate:      frameRate,
+			Width:          width,
+			Height:         height,
+		}
+		for _, format := range stepParam.SubtitleFormats {
+			path := strings.TrimSuffix(info.Path, filepath.Ext(info.Path)) + "." + subtitle.FormatExtension(format)
+			err = subtitle.WriteFile(path, sub, format, options)
+			var dropped *subtitle.DroppedTextError
+			if errors.As(err, &dropped) {
+				// 文件已经写入，提示用户哪些字幕有文字被跳过
+				log.GetLogger().Warn("exportSubtitleFormats dropped text", zap.Any("taskId", stepParam.TaskId), zap.String("path", path), zap.Error(err))
+				stepParam.TaskPtr.Warnings = append(stepParam.TaskPtr.Warnings, droppedTextWarnings(info.Name, format, dropped)...)
+			} else if err != nil {
+				log.GetLogger().Warn("exportSubtitleFormats WriteFile err", zap.Any("taskId", stepParam.TaskId), zap.String("path", path), zap.Error(err))
+				continue
+			}
+			stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, types.SubtitleFileInfo{
+				Name:               fmt.Sprintf("%s (%s)", info.Name, subtitleFormatNames[format]),
+				Path:               path,
+				LanguageIdentifier: info.LanguageIdentifier,
+			})
+		}
+	}
+}
+
+// droppedTextWarnings 提示用户导出的字幕中被跳过的文字，字幕序号和字符最多各列出10个和20个
+func droppedTextWarnings(name, format string, dropped *subtitle.DroppedTextError) []string {
+	var warnings []string
+	if len(dropped.Cues) > 0 {
+		chars := string(dropped.Chars[:min(len(dropped.Chars), 20)])
+		if len(dropped.Chars) > 20 {
+			chars += "..."
+		}
+		warnings = append(warnings, fmt.Sprintf("%s (%s)中有%d条字幕包含该格式无法显示的字符，已跳过：%s，字幕序号：%s",
+			name, subtitleFormatNames[format], len(dropped.Cues), chars, cueIndexList(dropped.Cues)))
+	}
+	if len(dropped.TruncatedCues) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%s (%s)中有%d条字幕超过该格式最多显示的行数，多出的行已丢掉，字幕序号：%s",
+			name, subtitleFormatNames[format], len(dropped.TruncatedCues), cueIndexList(dropped.TruncatedCues)))
+	}
+	return warnings
+}
+
+// cueIndexList 列出最多10个字幕序号
+func cueIndexList(cues []int) string {
+	indexes := lo.Map(cues[:min(len(cues), 10)], func(index int, _ int) string { return strconv.Itoa(index) })
+	if len(cues) > 10 {
+		indexes = append(indexes, "...")
+	}
+	return strings.Join(indexes, ", ")
+}
+
+// editorVideoInfo 导出剪辑软件格式时获取源视频的帧率和分辨率，无法获取时使用默认值
+func editorVideoInfo(stepParam *types.SubtitleTaskStepParam) (subtitle.FrameRate, int, int) {
+	if !lo.ContainsBy(stepParam.SubtitleFormats, subtitle.IsFrameBasedFormat) || stepParam.InputVideoPath == "" {
+		return subtitle.FrameRate{}, 0, 0
+	}
+	if _, err := os.Stat(stepParam.InputVideoPath); err != nil {
+		return subtitle.FrameRate{}, 0, 0
+	}
+	frameRate, err := getFrameRate(stepParam.InputVideoPath)
+	if err != nil {
+		log.GetLogger().Warn("editorVideoInfo getFrameRate err, use default", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+		frameRate = subtitle.DefaultFrameRate
+	}
+	width, height, err := getResolution(stepParam.InputVideoPath)
+	if err != nil {
+		log.GetLogger().Warn("editorVideoInfo getResolution err, use default", zap.Any("taskId", stepParam.TaskId), zap.Error(err))
+	}
+	return frameRate, width, height
+}
+
+// getFrameRate 用ffprobe获取视频的平均帧率，如30000/1001。r_frame_rate是能表示全部时间戳的最小帧率，可变帧率视频会偏高
+func getFrameRate(inputVideo string) (subtitle.FrameRate, error) {
+	cmdArgs := []string{
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=avg_frame_rate",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		inputVideo,
+	}
+	output, err := exec.Command(storage.FfprobePath, cmdArgs...).CombinedOutput()
+	if err != nil {
+		log.GetLogger().Error("获取视频帧率失败", zap.String("output", string(output)), zap.Error(err))
+		return subtitle.FrameRate{}, err
+	}
+	return subtitle.ParseFrameRate(string(output))
+}
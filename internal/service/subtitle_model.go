@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
+	"krillin-ai/pkg/util"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+}
+
+// bilingualLayout 双语字幕中原文和译文的上下顺序
+func bilingualLayout(stepParam *types.SubtitleTaskStepParam) subtitle.Layout {
+	if stepParam.SubtitleResultType == types.SubtitleResultTypeBilingualTranslationOnTop {
+		return subtitle.LayoutTargetFirst
+	}
+	// on bottom 或者单语类型，都用on bottom
+	return subtitle.LayoutOriginFirst
+}
+
+// srtOptions 写入srt时的参数，开启说话人分离时以[说话人]前缀标注
+func srtOptions(stepParam *types.SubtitleTaskStepParam, layout subtitle.Layout) subtitle.Options {
+	return subtitle.Options{Layout: layout, SpeakerPrefix: stepParam.EnableDiarization}
+}
+
+// srtBlocksToSubtitle 把一个音频片段带时间戳的字幕块转为结构化字幕，没有时间戳的块跳过
+func srtBlocksToSubtitle(srtBlocks []*util.SrtBlock, words []types.Word, tsOffset float64, stepParam *types.SubtitleTaskStepParam) *subtitle.Subtitle {
+	sub := &subtitle.Subtitle{}
+	for _, block := range srtBlocks {
+		start, end, err := subtitle.ParseSrtTiming(block.Timestamp)
+		if err != nil {
+			log.GetLogger().Debug("srtBlocksToSubtitle skip block without timestamp", zap.Int("index", block.Index), zap.String("origin", block.OriginLanguageSentence))
+			continue
+		}
+		cue := &subtitle.Cue{
+			Start:              start,
+			End:                end,
+			OriginText:         block.OriginLanguageSentence,
+			TranslatedText:     block.TargetLanguageSentence,
+			Confidence:         block.Confidence,
+			LowConfidenceWords: block.LowConfidenceWords,
+		}
+		if stepParam.EnableDiarization {
+			cue.Speaker = speakerDisplayName(block.Speaker, stepParam.SpeakerNameMap)
+		}
+		// 词的时间相对于音频片段，加上偏移量后与字幕时间对齐
+		startSeconds, endSeconds := start.Seconds()-tsOffset, end.Seconds()-tsOffset
+		for _, word := range words {
+			if word.End <= startSeconds || word.Start >= endSeconds {
+				continue
+			}
+			cue.Words = append(cue.Words, subtitle.Word{
+				Text:       word.Text,
+				Start:      secondsToDuration(word.Start + tsOffset),
+				End:        secondsToDuration(word.End + tsOffset),
+				Confidence: word.Confidence,
+			})
+		}
+		sub.Cues = append(sub.Cues, cue)
+	}
+	sub.Renumber()
+	return sub
+}
+
+// mergeSegmentSubtitleData 合并各音频片段的结构化字幕，没有字幕的片段跳过
+func mergeSegmentSubtitleData(stepParam *types.SubtitleTaskStepParam, segmentNum int) (*subtitle.Subtitle, error) {
+	merged := &subtitle.Subtitle{}
+	for i := range segmentNum {
+		path := filepath.Join(stepParam.TaskBasePath, fmt.Sprintf(types.SubtitleTaskSplitSubtitleDataPersistenceFileNamePattern, i))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		sub, err := subtitle.LoadJson(path)
+		if err != nil {
+			return nil, fmt.Errorf("mergeSegmentSubtitleData load segment %d err: %w", i, err)
+		}
+		merged.Cues = append(merged.Cues, sub.Cues...)
+	}
+	merged.Renumber()
+	return merged, nil
+}
+
+// loadTaskSubtitle 读取任务的结构化字幕。双语srt无法区分原文和译文，不再从中解析
+func loadTaskSubtitle(stepParam *types.SubtitleTaskStepParam) (*subtitle.Subtitle, error) {
+	if stepParam.SubtitleFilePath == "" {
+		return nil, errors.New("loadTaskSubtitle task has no subtitle data")
+	}
+	return subtitle.LoadJson(stepParam.SubtitleFilePath)
+}
+
+// collectSubtitleSpeakers 按出现顺序收集字幕中的说话人
+func collectSubtitleSpeakers(sub *subtitle.Subtitle) []string {
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, cue := range sub.Cues {
+		if cue.Speaker != "" && !seen[cue.Speaker] {
+			seen[cue.Speaker] = true
+			speakers = append(speakers, cue.Speaker)
+		}
+	}
+	return speakers
+}
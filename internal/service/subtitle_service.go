@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"krillin-ai/config"
 	"krillin-ai/internal/dto"
 	"krillin-ai/internal/storage"
 	"krillin-ai/internal/types"
@@ -13,8 +17,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-	"github.com/samber/lo"
-	"go.uber.org/zap"
 )
 
 func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.StartVideoSubtitleTaskResData, error) {
@@ -36,10 +38,20 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 	taskId := fmt.Sprintf("%s_%s", util.SanitizePathName(string([]rune(strings.ReplaceAll(seperates[len(seperates)-1], " ", ""))[:16])), util.GenerateRandStringWithUpperLowerNum(4))
 	taskId = strings.ReplaceAll(taskId, "=", "") // 等于号影响ffmpeg处理
 	taskId = strings.ReplaceAll(taskId, "?", "") // 问号影响ffmpeg处理
+	// 目标语言去重，第一个为主目标语言
+	targetLanguages := lo.Uniq(lo.Map(req.TargetLang, func(lang string, _ int) types.StandardLanguageCode {
+		return types.StandardLanguageCode(lang)
+	}))
+	if len(targetLanguages) == 0 {
+		return nil, fmt.Errorf("目标语言不能为空")
+	}
+	if len(targetLanguages) > 1 && lo.Contains(targetLanguages, "none") {
+		return nil, fmt.Errorf("不翻译时不能同时指定其他目标语言")
+	}
 	// 构造任务所需参数
 	var resultType types.SubtitleResultType
 	// 根据入参选项确定要返回的字幕类型
-	if req.TargetLang == "none" {
+	if targetLanguages[0] == "none" {
 		resultType = types.SubtitleResultTypeOriginOnly
 	} else {
 		if req.Bilingual == types.SubtitleTaskBilingualYes {
@@ -52,19 +64,140 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 			resultType = types.SubtitleResultTypeTargetOnly
 		}
 	}
-	// 文字替换map
-	replaceWordsMap := make(map[string]string)
+	// 文字替换规则，replace中的替换按完整的词区分大小写匹配，排在replace_rules前面
+	var replaceRules []types.ReplaceRule
 	if len(req.Replace) > 0 {
 		for _, replace := range req.Replace {
 			beforeAfter := strings.Split(replace, "|")
 			if len(beforeAfter) == 2 {
-				replaceWordsMap[beforeAfter[0]] = beforeAfter[1]
+				replaceRules = append(replaceRules, types.ReplaceRule{From: beforeAfter[0], To: beforeAfter[1]})
 			} else {
 				log.GetLogger().Info("generateAudioSubtitles replace param length err", zap.Any("replace", replace), zap.Any("taskId", taskId))
 			}
 		}
 	}
-	var err error
+	replaceRules = append(replaceRules, req.ReplaceRules...)
+	replacer, err := util.NewWordReplacer(replaceRules)
+	if err != nil {
+		log.GetLogger().Error("StartVideoSubtitleTask NewWordReplacer err", zap.Any("req", req), zap.Error(err))
+		return nil, err
+	}
+	// 逐词替换的规则作用于转录结果，其余规则作用于送去翻译的句子和对齐时间戳后的原文
+	wordReplacer, phraseReplacer := replacer.Split()
+	// 说话人名称map
+	speakerNameMap := make(map[string]string)
+	for _, speakerName := range req.SpeakerNames {
+		idName := strings.Split(speakerName, "|")
+		if len(idName) == 2 {
+			speakerNameMap[idName[0]] = idName[1]
+		} else {
+			log.GetLogger().Info("generateAudioSubtitles speaker name param length err", zap.Any("speakerName", speakerName), zap.Any("taskId", taskId))
+		}
+	}
+	// 转录热词，全局配置在前，去重
+	var hotwords []string
+	hotwordSet := make(map[string]struct{})
+	for _, hotword := range append(append([]string{}, config.Conf.Transcribe.Hotwords...), req.Hotwords...) {
+		hotword = strings.TrimSpace(hotword)
+		if _, ok := hotwordSet[hotword]; ok || hotword == "" {
+			continue
+		}
+		hotwordSet[hotword] = struct{}{}
+		hotwords = append(hotwords, hotword)
+	}
+	// 术语表，任务内的术语优先级最高
+	glossaries := make([]*types.Glossary, 0)
+	for _, name := range req.GlossaryNames {
+		glossary, err := s.LoadGlossary(name)
+		if err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask LoadGlossary err", zap.Any("req", req), zap.Error(err))
+			return nil, err
+		}
+		glossaries = append(glossaries, glossary)
+	}
+	if req.GlossaryFile != "" {
+		glossaryFile, err := uploadedFilePath(req.GlossaryFile)
+		if err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask uploadedFilePath err", zap.Any("req", req), zap.Error(err))
+			return nil, err
+		}
+		glossary, err := ParseGlossaryFile(glossaryFile)
+		if err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask ParseGlossaryFile err", zap.Any("req", req), zap.Error(err))
+			return nil, fmt.Errorf("术语表文件解析失败: %w", err)
+		}
+		glossaries = append(glossaries, glossary)
+	}
+	taskGlossary := &types.Glossary{DoNotTranslate: req.DoNotTranslate}
+	for _, item := range req.Glossary {
+		sourceTarget := strings.Split(item, "|")
+		if len(sourceTarget) == 2 && sourceTarget[0] != "" {
+			taskGlossary.Terms = append(taskGlossary.Terms, types.GlossaryTerm{Source: sourceTarget[0], Target: sourceTarget[1]})
+		} else {
+			log.GetLogger().Info("generateAudioSubtitles glossary param length err", zap.Any("glossary", item), zap.Any("taskId", taskId))
+		}
+	}
+	glossaries = append(glossaries, taskGlossary)
+	// 风格要求，风格配置在前，任务内的要求附加在后
+	styleGuide := strings.TrimSpace(req.StyleGuide)
+	if req.StyleProfile != "" {
+		profile, err := s.LoadStyleProfile(req.StyleProfile)
+		if err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask LoadStyleProfile err", zap.Any("req", req), zap.Error(err))
+			return nil, err
+		}
+		styleGuide = strings.TrimSpace(profile.StyleGuide() + "\n" + styleGuide)
+	}
+	// 提示词模板，任务覆盖的模板在开始前校验
+	prompts, err := newTaskPromptSet(req.PromptTemplates, styleGuide)
+	if err != nil {
+		log.GetLogger().Error("StartVideoSubtitleTask newTaskPromptSet err", zap.Any("req", req), zap.Error(err))
+		return nil, err
+	}
+	subtitleFormats, err := validateSubtitleFormats(req.SubtitleFormats, req.VttCueSettings)
+	if err != nil {
+		log.GetLogger().Error("StartVideoSubtitleTask validateSubtitleFormats err", zap.Any("req", req), zap.Error(err))
+		return nil, err
+	}
+	// 字幕样式，任务内的样式优先于预设
+	var assStyle *types.AssStyle
+	if len(req.AssStyleConfig) > 0 && string(req.AssStyleConfig) != "null" {
+		assStyle = &types.AssStyle{}
+		if err = json.Unmarshal(req.AssStyleConfig, assStyle); err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask unmarshal AssStyleConfig err", zap.Any("req", req), zap.Error(err))
+			return nil, fmt.Errorf("字幕样式格式错误: %w", err)
+		}
+		if err = assStyle.Validate(); err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask validate AssStyleConfig err", zap.Any("req", req), zap.Error(err))
+			return nil, fmt.Errorf("字幕样式错误: %w", err)
+		}
+	} else if req.AssStyle != "" {
+		assStyle, err = s.LoadAssStyle(req.AssStyle)
+		if err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask LoadAssStyle err", zap.Any("req", req), zap.Error(err))
+			return nil, err
+		}
+	}
+	transcriptFormats, err := validateTranscriptFormats(req.TranscriptFormats)
+	if err != nil {
+		log.GetLogger().Error("StartVideoSubtitleTask validateTranscriptFormats err", zap.Any("req", req), zap.Error(err))
+		return nil, err
+	}
+	transcriptParagraphGap := req.TranscriptParagraphGap
+	if transcriptParagraphGap <= 0 {
+		transcriptParagraphGap = defaultTranscriptParagraphGap
+	}
+	subtitleSpec := strings.TrimSpace(req.SubtitleSpec)
+	if subtitleSpec != "" {
+		if _, err = subtitleSpecStore.load(subtitleSpec); err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask load subtitle spec err", zap.Any("req", req), zap.Error(err))
+			return nil, err
+		}
+	}
+	speakerLabelMode := types.SpeakerLabelModePrefix
+	if req.SpeakerLabel == types.SpeakerLabelModeStyle {
+		speakerLabelMode = types.SpeakerLabelModeStyle
+	}
 	ctx := context.Background()
 	// 创建字幕任务文件夹
 	taskBasePath := filepath.Join("./tasks", taskId)
@@ -78,9 +211,10 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 
 	// 创建任务
 	taskPtr := &types.SubtitleTask{
-		TaskId:   taskId,
-		VideoSrc: req.Url,
-		Status:   types.SubtitleTaskStatusProcessing,
+		TaskId:         taskId,
+		VideoSrc:       req.Url,
+		Status:         types.SubtitleTaskStatusProcessing,
+		TargetLanguage: strings.Join(req.TargetLang, ","),
 	}
 	storage.SubtitleTasks.Store(taskId, taskPtr)
 
@@ -98,6 +232,7 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 		log.GetLogger().Info("StartVideoSubtitleTask 上传声音克隆源成功", zap.Any("oss url", voiceCloneAudioUrl))
 	}
 
+	voiceCodes := ttsVoiceCodes(req, targetLanguages)
 	stepParam := types.SubtitleTaskStepParam{
 		TaskId:                  taskId,
 		TaskPtr:                 taskPtr,
@@ -106,21 +241,44 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 		SubtitleResultType:      resultType,
 		EnableModalFilter:       req.ModalFilter == types.SubtitleTaskModalFilterYes,
 		EnableTts:               req.Tts == types.SubtitleTaskTtsYes,
-		TtsVoiceCode:            req.TtsVoiceCode,
+		TtsVoiceCode:            voiceCodes[targetLanguages[0]],
+		TtsVoiceCodes:           voiceCodes,
 		VoiceCloneAudioUrl:      voiceCloneAudioUrl,
-		ReplaceWordsMap:         replaceWordsMap,
+		ReplaceRules:            replaceRules,
+		WordReplacer:            wordReplacer,
+		PhraseReplacer:          phraseReplacer,
 		OriginLanguage:          types.StandardLanguageCode(req.OriginLanguage),
-		TargetLanguage:          types.StandardLanguageCode(req.TargetLang),
+		TargetLanguage:          targetLanguages[0],
+		TargetLanguages:         targetLanguages,
 		UserUILanguage:          types.StandardLanguageCode(req.Language),
 		EmbedSubtitleVideoType:  req.EmbedSubtitleVideoType,
 		VerticalVideoMajorTitle: req.VerticalMajorTitle,
 		VerticalVideoMinorTitle: req.VerticalMinorTitle,
 		MaxWordOneLine:          12, // 默认值
+		EnableDiarization:       req.Diarization == types.SubtitleTaskDiarizationYes && config.Conf.Diarize.Provider != "",
+		SpeakerNameMap:          speakerNameMap,
+		SpeakerLabelMode:        speakerLabelMode,
+		Hotwords:                hotwords,
+		Glossary:                mergeGlossaries(glossaries...),
+		EnableGlossaryRetry:     req.GlossaryRetry == types.SubtitleTaskGlossaryRetryYes,
+		EnableQualityReview:     req.QualityReview == types.SubtitleTaskQualityReviewYes,
+		SubtitleFormats:         subtitleFormats,
+		VttCueSettings:          strings.Join(strings.Fields(req.VttCueSettings), " "),
+		AssStyle:                assStyle,
+		SubtitleSpec:            subtitleSpec,
+		TranscriptFormats:       transcriptFormats,
+		TranscriptParagraphGap:  transcriptParagraphGap,
+		TranscriptTimestamps:    req.TranscriptTimestamps == types.SubtitleTaskTranscriptTimestampsYes,
+		Prompts:                 prompts,
 	}
 	if req.OriginLanguageWordOneLine != 0 {
 		stepParam.MaxWordOneLine = req.OriginLanguageWordOneLine
 	}
 
+	if err = writePromptTemplates(&stepParam); err != nil {
+		log.GetLogger().Warn("StartVideoSubtitleTask writePromptTemplates err", zap.String("taskId", taskId), zap.Error(err))
+	}
+
 	log.GetLogger().Info("current task info", zap.String("taskId", taskId), zap.Any("param", stepParam))
 
 	go func() {
@@ -157,6 +315,13 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 			stepParam.TaskPtr.FailReason = err.Error()
 			return
 		}
+		err = s.processExtraTargetLanguages(ctx, &stepParam)
+		if err != nil {
+			log.GetLogger().Error("StartVideoSubtitleTask processExtraTargetLanguages err", zap.Any("req", req), zap.Error(err))
+			stepParam.TaskPtr.Status = types.SubtitleTaskStatusFailed
+			stepParam.TaskPtr.FailReason = err.Error()
+			return
+		}
 		err = s.srtFileToSpeech(ctx, &stepParam)
 		if err != nil {
 			log.GetLogger().Error("StartVideoSubtitleTask srtFileToSpeech err", zap.Any("req", req), zap.Error(err))
@@ -179,6 +344,11 @@ func (s Service) StartSubtitleTask(req dto.StartVideoSubtitleTaskReq) (*dto.Star
 			return
 		}
 
+		// 保存任务参数，供修改字幕后重新执行后续步骤
+		if err = saveStepParam(&stepParam); err != nil {
+			log.GetLogger().Warn("StartVideoSubtitleTask saveStepParam err", zap.String("taskId", taskId), zap.Error(err))
+		}
+
 		log.GetLogger().Info("video subtitle task end", zap.String("taskId", taskId))
 	}()
 
@@ -213,5 +383,11 @@ func (s Service) GetTaskStatus(req dto.GetVideoSubtitleTaskReq) (*dto.GetVideoSu
 		}),
 		TargetLanguage:    taskPtr.TargetLanguage,
 		SpeechDownloadUrl: taskPtr.SpeechDownloadUrl,
+		TranslationMemory: &dto.TmStats{
+			ExactHit: taskPtr.TmExactHitNum,
+			FuzzyHit: taskPtr.TmFuzzyHitNum,
+			Miss:     taskPtr.TmMissNum,
+		},
+		Warnings: taskPtr.Warnings,
 	}, nil
 }
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/pkg/subtitle"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const subtitleSpecDir = "./subtitle_specs"
+
+// builtinSubtitleSpecs 内置字幕规范，netflix按语言区分，参考Netflix Timed Text Style Guide和EBU字幕标准
+var builtinSubtitleSpecs = map[string]*subtitle.Spec{
+	"netflix": {
+		Name:          "netflix",
+		Description:   "Netflix Timed Text Style Guide通用规范，指定语言时优先使用该语言的规范",
+		MaxLineLength: 42,
+		MaxLines:      2,
+		MaxCps:        17,
+		MinDuration:   0.833,
+		MaxDuration:   7,
+		MinGap:        0.083,
+	},
+	"netflix_en": {
+		Name:          "netflix_en",
+		Description:   "Netflix英语字幕规范",
+		MaxLineLength: 42,
+		MaxLines:      2,
+		MaxCps:        20,
+		MinDuration:   0.833,
+		MaxDuration:   7,
+		MinGap:        0.083,
+	},
+	"netflix_zh_cn": {
+		Name:             "netflix_zh_cn",
+		Description:      "Netflix简体中文字幕规范，标点使用全角",
+		MaxLineLength:    16,
+		MaxLines:         2,
+		MaxCps:           9,
+		MinDuration:      0.833,
+		MaxDuration:      7,
+		MinGap:           0.083,
+		ForbiddenPattern: `[,!?;:]`,
+	},
+	"netflix_zh_tw": {
+		Name:             "netflix_zh_tw",
+		Description:      "Netflix繁体中文字幕规范，标点使用全角",
+		MaxLineLength:    16,
+		MaxLines:         2,
+		MaxCps:           9,
+		MinDuration:      0.833,
+		MaxDuration:      7,
+		MinGap:           0.083,
+		ForbiddenPattern: `[,!?;:]`,
+	},
+	"netflix_ja": {
+		Name:          "netflix_ja",
+		Description:   "Netflix日语字幕规范",
+		MaxLineLength: 13,
+		MaxLines:      2,
+		MaxCps:        4,
+		MinDuration:   0.833,
+		MaxDuration:   7,
+		MinGap:        0.083,
+	},
+	"netflix_ko": {
+		Name:          "netflix_ko",
+		Description:   "Netflix韩语字幕规范",
+		MaxLineLength: 16,
+		MaxLines:      2,
+		MaxCps:        12,
+		MinDuration:   0.833,
+		MaxDuration:   7,
+		MinGap:        0.083,
+	},
+	"netflix_ru": {
+		Name:          "netflix_ru",
+		Description:   "Netflix俄语字幕规范",
+		MaxLineLength: 39,
+		MaxLines:      2,
+		MaxCps:        17,
+		MinDuration:   0.833,
+		MaxDuration:   7,
+		MinGap:        0.083,
+	},
+	"ebu": {
+		Name:             "ebu",
+		Description:      "EBU广电字幕规范，字符限制在图文电视可显示的拉丁字符内",
+		MaxLineLength:    37,
+		MaxLines:         2,
+		MaxCps:           15,
+		MinDuration:      1,
+		MaxDuration:      7,
+		MinGap:           0.04,
+		ForbiddenPattern: `[^\x20-\x7E\x{A0}-\x{17F}\n]`,
+	},
+}
+
+var subtitleSpecStore = namedStore[subtitle.Spec]{
+	dir:      subtitleSpecDir,
+	label:    "字幕规范",
+	builtins: builtinSubtitleSpecs,
+	nameOf:   func(spec *subtitle.Spec) string { return spec.Name },
+	validate: (*subtitle.Spec).Check,
+}
+
+func (s Service) SaveSubtitleSpec(spec *subtitle.Spec) error {
+	return subtitleSpecStore.save(spec)
+}
+
+// LoadSubtitleSpec 读取已保存的字幕规范，不存在时使用同名的内置规范
+func (s Service) LoadSubtitleSpec(name string) (*subtitle.Spec, error) {
+	return subtitleSpecStore.load(name)
+}
+
+// ListSubtitleSpecs 列出内置和已保存的字幕规范
+func (s Service) ListSubtitleSpecs() ([]*subtitle.Spec, error) {
+	return subtitleSpecStore.list()
+}
+
+// DeleteSubtitleSpec 删除已保存的字幕规范，内置规范无法删除
+func (s Service) DeleteSubtitleSpec(name string) error {
+	return subtitleSpecStore.delete(name)
+}
+
+// resolveSubtitleSpec 获取检查某种语言字幕使用的规范，优先使用名称加语言后缀的规范，如netflix_ja
+func resolveSubtitleSpec(name string, language types.StandardLanguageCode) (*subtitle.Spec, error) {
+	if language != "" {
+		if spec, err := subtitleSpecStore.load(name + "_" + string(language)); err == nil {
+			return spec, nil
+		}
+	}
+	return subtitleSpecStore.load(name)
+}
+
+// ValidateSubtitleFile 按规范检查字幕文件，格式由扩展名决定，支持srt、vtt、ass
+func (s Service) ValidateSubtitleFile(path, specName string, language types.StandardLanguageCode) (*subtitle.ValidationReport, error) {
+	spec, err := resolveSubtitleSpec(specName, language)
+	if err != nil {
+		return nil, err
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format != subtitle.FormatSrt && format != subtitle.FormatVtt && format != subtitle.FormatAss {
+		return nil, fmt.Errorf("字幕检查只支持srt、vtt和ass格式: %s", format)
+	}
+	sub, err := subtitle.ReadFile(path, format, subtitle.Options{Layout: subtitle.LayoutOrigin})
+	if err != nil {
+		return nil, fmt.Errorf("ValidateSubtitleFile read subtitle err: %w", err)
+	}
+	report := sub.Validate(spec, subtitle.TrackOrigin)
+	report.Language = string(language)
+	return report, nil
+}
+
+// validateSubtitleSpecs 按任务指定的字幕规范检查原文和译文字幕，结果写入规范检查报告
+func validateSubtitleSpecs(stepParam *types.SubtitleTaskStepParam) error {
+	sub, err := loadTaskSubtitle(stepParam)
+	if err != nil {
+		return fmt.Errorf("validateSubtitleSpecs loadTaskSubtitle err: %w", err)
+	}
+	var reports []*subtitle.ValidationReport
+	for _, track := range []struct {
+		name     string
+		language types.StandardLanguageCode
+		enabled  bool
+	}{
+		// 多目标语言时原文只在主目标语言中检查一次
+		{subtitle.TrackOrigin, stepParam.OriginLanguage, stepParam.SubtitleResultType != types.SubtitleResultTypeTargetOnly && !isExtraTargetLanguage(stepParam)},
+		{subtitle.TrackTarget, stepParam.TargetLanguage, stepParam.SubtitleResultType != types.SubtitleResultTypeOriginOnly},
+	} {
+		if !track.enabled {
+			continue
+		}
+		spec, err := resolveSubtitleSpec(stepParam.SubtitleSpec, track.language)
+		if err != nil {
+			return fmt.Errorf("validateSubtitleSpecs resolveSubtitleSpec err: %w", err)
+		}
+		report := sub.Validate(spec, track.name)
+		report.Language = string(track.language)
+		reports = append(reports, report)
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("validateSubtitleSpecs marshal err: %w", err)
+	}
+	filePath := filepath.Join(stepParam.TaskBasePath, "output", types.SubtitleTaskSpecReportFileName)
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("validateSubtitleSpecs write file err: %w", err)
+	}
+	stepParam.SpecReportFilePath = filePath
+	return nil
+}
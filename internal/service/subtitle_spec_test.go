@@ -0,0 +1,32 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"testing"
+)
+
+func TestResolveSubtitleSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		want     string
+	}{
+		{"netflix", "ja", "netflix_ja"},
+		{"netflix", "zh_cn", "netflix_zh_cn"},
+		{"netflix", "fr", "netflix"},
+		{"netflix", "", "netflix"},
+		{"ebu", "en", "ebu"},
+	}
+	for _, tt := range tests {
+		spec, err := resolveSubtitleSpec(tt.name, types.StandardLanguageCode(tt.language))
+		if err != nil || spec.Name != tt.want {
+			t.Errorf("resolveSubtitleSpec(%s, %s) = %v, %v, want %s", tt.name, tt.language, spec, err, tt.want)
+		}
+	}
+	if _, err := resolveSubtitleSpec("missing", "en"); err == nil {
+		t.Error("expected an error for a missing spec")
+	}
+	if err := (Service{}).DeleteSubtitleSpec("netflix"); err == nil {
+		t.Error("expected an error when deleting a builtin spec")
+	}
+}
@@ -91,6 +91,8 @@ func (tg *TimestampGenerator) GenerateTimestamps(srtBlocks []*util.SrtBlock, wor
 			if endTime <= startTime {
 				endTime = startTime + 1.0 // Minimum 1 second duration
 			}
+			updatedBlocks[i].Speaker = dominantSpeaker(words, startTime, endTime)
+			updatedBlocks[i].Confidence, updatedBlocks[i].LowConfidenceWords = blockConfidence(words, startTime, endTime)
 		}
 
 		// Generate timestamp string
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/subtitle"
+	"strings"
+
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// defaultTranscriptParagraphGap 文稿中默认停顿超过2秒另起一段
+const defaultTranscriptParagraphGap = 2.0
+
+var transcriptFormatNames = map[string]string{
+	subtitle.TranscriptFormatTxt:  "TXT",
+	subtitle.TranscriptFormatMd:   "Markdown",
+	subtitle.TranscriptFormatDocx: "DOCX",
+	subtitle.TranscriptFormatJson: "JSON",
+}
+
+// validateTranscriptFormats 校验任务指定的文稿格式，去重并转为小写
+func validateTranscriptFormats(formats []string) ([]string, error) {
+	var result []string
+	for _, format := range formats {
+		format = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(format)), ".")
+		if format == "markdown" {
+			format = subtitle.TranscriptFormatMd
+		}
+		if format == "" || lo.Contains(result, format) {
+			continue
+		}
+		if !subtitle.IsTranscriptFormat(format) {
+			return nil, fmt.Errorf("不支持的文稿格式: %s", format)
+		}
+		result = append(result, format)
+	}
+	return result, nil
+}
+
+// transcriptOptions 任务的文稿参数，开启说话人分离时标注说话人
+func transcriptOptions(stepParam *types.SubtitleTaskStepParam, layout subtitle.Layout) subtitle.TranscriptOptions {
+	language := stepParam.OriginLanguage
+	if layout == subtitle.LayoutTarget {
+		language = stepParam.TargetLanguage
+	}
+	return subtitle.TranscriptOptions{
+		Layout:       layout,
+		Language:     subtitleLanguageTag(language),
+		ParagraphGap: secondsToDuration(stepParam.TranscriptParagraphGap),
+		Timestamps:   stepParam.TranscriptTimestamps,
+		Speakers:     stepParam.EnableDiarization,
+	}
+}
+
+// exportTranscripts 把原文和译文文稿导出为任务指定的格式，文件与txt文稿同名，并加入下载列表
+func exportTranscripts(stepParam *types.SubtitleTaskStepParam, sub *subtitle.Subtitle, originTextPath, targetTextPath string) {
+	for _, transcript := range []struct {
+		layout   subtitle.Layout
+		language types.StandardLanguageCode
+		textPath string
+		enabled  bool
+	}{
+		// 多目标语言时原文文稿只在主目标语言中导出一次
+		{subtitle.LayoutOrigin, stepParam.OriginLanguage, originTextPath, !isExtraTargetLanguage(stepParam)},
+		{subtitle.LayoutTarget, stepParam.TargetLanguage, targetTextPath, stepParam.SubtitleResultType != types.SubtitleResultTypeOriginOnly},
+	} {
+		if !transcript.enabled {
+			continue
+		}
+		options := transcriptOptions(stepParam, transcript.layout)
+		for _, format := range stepParam.TranscriptFormats {
+			// txt文稿总是生成，这里只需加入下载列表
+			path := strings.TrimSuffix(transcript.textPath, ".txt") + "." + format
+			if format != subtitle.TranscriptFormatTxt {
+				if err := subtitle.WriteTranscriptFile(path, sub, format, options); err != nil {
+					log.GetLogger().Warn("exportTranscripts WriteTranscriptFile err", zap.Any("taskId", stepParam.TaskId), zap.String("path", path), zap.Error(err))
+					continue
+				}
+			}
+			subtitleInfo := types.SubtitleFileInfo{
+				Path:               path,
+				LanguageIdentifier: "transcript_" + string(transcript.language),
+			}
+			if stepParam.UserUILanguage == types.LanguageNameEnglish {
+				subtitleInfo.Name = fmt.Sprintf("%s Transcript (%s)", types.GetStandardLanguageName(transcript.language), transcriptFormatNames[format])
+			} else if stepParam.UserUILanguage == types.LanguageNameSimplifiedChinese {
+				subtitleInfo.Name = fmt.Sprintf("%s 文稿(%s)", types.GetStandardLanguageName(transcript.language), transcriptFormatNames[format])
+			}
+			stepParam.SubtitleInfos = append(stepParam.SubtitleInfos, subtitleInfo)
+		}
+	}
+}
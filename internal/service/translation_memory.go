@@ -0,0 +1,435 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/texttheater/golang-levenshtein/levenshtein"
+	"go.uber.org/zap"
+)
+
+const translationMemoryFile = "./translation_memory/memory.json"
+
+// tmPair 一个语言对的翻译记忆
+type tmPair struct {
+	sourceLang string
+	targetLang string
+	exact      map[string]*types.TranslationMemoryEntry // 归一化后的原文 -> 记录
+	byLength   map[int][]string                         // 原文字符数 -> 归一化后的原文，模糊匹配时只比较长度相近的记录
+}
+
+var (
+	translationMemoryMutex sync.RWMutex
+	translationMemoryOnce  sync.Once
+	// 语言对 -> 记录
+	translationMemory = make(map[string]*tmPair)
+	// 去掉地区后的语言对 -> 原始语言对，如en->zh_cn对应en_us->zh_cn
+	translationMemoryAliases = make(map[string][]string)
+	tmNumberRegex            = regexp.MustCompile(`\d+(\.\d+)?`)
+	tmSpaceRegex             = regexp.MustCompile(`\s+`)
+)
+
+func languagePairKey(sourceLang, targetLang string) string {
+	return normalizeTmLang(sourceLang) + "->" + normalizeTmLang(targetLang)
+}
+
+// 统一语言代码格式，TMX中的zh-CN与系统中的zh_cn视为同一种语言
+func normalizeTmLang(lang string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "-", "_")
+}
+
+// tmLangVariants 返回语言代码本身及其主语言标签，如en_us返回[en_us en]
+func tmLangVariants(lang string) []string {
+	lang = normalizeTmLang(lang)
+	if primary, _, found := strings.Cut(lang, "_"); found && primary != "" {
+		return []string{lang, primary}
+	}
+	return []string{lang}
+}
+
+// languagePairKeys 返回语言对本身及去掉地区后的各种组合，第一个为语言对本身
+func languagePairKeys(sourceLang, targetLang string) []string {
+	var keys []string
+	for _, source := range tmLangVariants(sourceLang) {
+		for _, target := range tmLangVariants(targetLang) {
+			keys = append(keys, source+"->"+target)
+		}
+	}
+	return keys
+}
+
+// normalizeTmSource 归一化原文：忽略大小写、多余空白和首尾标点
+func normalizeTmSource(text string) string {
+	text = strings.ToLower(tmSpaceRegex.ReplaceAllString(strings.TrimSpace(text), " "))
+	return strings.TrimFunc(text, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+}
+
+// loadTranslationMemory 首次使用时从文件加载翻译记忆
+func loadTranslationMemory() {
+	translationMemoryOnce.Do(func() {
+		data, err := os.ReadFile(translationMemoryFile)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.GetLogger().Error("loadTranslationMemory read file err", zap.Error(err))
+			}
+			return
+		}
+		var entries []*types.TranslationMemoryEntry
+		if err = json.Unmarshal(data, &entries); err != nil {
+			log.GetLogger().Error("loadTranslationMemory unmarshal err", zap.Error(err))
+			return
+		}
+		translationMemoryMutex.Lock()
+		defer translationMemoryMutex.Unlock()
+		for _, entry := range entries {
+			putTranslationMemoryLocked(entry)
+		}
+	})
+}
+
+// 调用方需持有translationMemoryMutex写锁
+func putTranslationMemoryLocked(entry *types.TranslationMemoryEntry) {
+	normalized := normalizeTmSource(entry.Source)
+	if normalized == "" {
+		return
+	}
+	keys := languagePairKeys(entry.SourceLang, entry.TargetLang)
+	pair := translationMemory[keys[0]]
+	if pair == nil {
+		pair = &tmPair{
+			sourceLang: normalizeTmLang(entry.SourceLang),
+			targetLang: normalizeTmLang(entry.TargetLang),
+			exact:      make(map[string]*types.TranslationMemoryEntry),
+			byLength:   make(map[int][]string),
+		}
+		translationMemory[keys[0]] = pair
+		for _, alias := range keys[1:] {
+			translationMemoryAliases[alias] = append(translationMemoryAliases[alias], keys[0])
+		}
+	}
+	if _, ok := pair.exact[normalized]; !ok {
+		length := len([]rune(normalized))
+		pair.byLength[length] = append(pair.byLength[length], normalized)
+	}
+	pair.exact[normalized] = entry
+}
+
+// tmLangMatches 判断记忆中的语言能否用于查询的语言：相同，或者其中一方不带地区且主语言标签相同。
+// en可以匹配en_us，但zh_cn不会匹配zh_tw
+func tmLangMatches(stored, query string) bool {
+	if stored == query {
+		return true
+	}
+	storedVariants, queryVariants := tmLangVariants(stored), tmLangVariants(query)
+	return (len(storedVariants) == 1 || len(queryVariants) == 1) && storedVariants[len(storedVariants)-1] == queryVariants[len(queryVariants)-1]
+}
+
+// 调用方需持有translationMemoryMutex读锁。先找语言对本身，再按主语言标签回退，如en可以匹配TMX中的en-US
+func matchingTmPairsLocked(sourceLang, targetLang string) []*tmPair {
+	var (
+		pairs []*tmPair
+		seen  = make(map[string]bool)
+	)
+	sourceLang, targetLang = normalizeTmLang(sourceLang), normalizeTmLang(targetLang)
+	add := func(key string) {
+		pair, ok := translationMemory[key]
+		if !ok || seen[key] || !tmLangMatches(pair.sourceLang, sourceLang) || !tmLangMatches(pair.targetLang, targetLang) {
+			return
+		}
+		seen[key] = true
+		pairs = append(pairs, pair)
+	}
+	for _, key := range languagePairKeys(sourceLang, targetLang) {
+		add(key)
+		for _, fullKey := range translationMemoryAliases[key] {
+			add(fullKey)
+		}
+	}
+	return pairs
+}
+
+// 调用方需持有translationMemoryMutex
+func saveTranslationMemoryLocked() error {
+	entries := make([]*types.TranslationMemoryEntry, 0)
+	for _, pair := range translationMemory {
+		for _, entry := range pair.exact {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].SourceLang+entries[i].TargetLang != entries[j].SourceLang+entries[j].TargetLang {
+			return entries[i].SourceLang+entries[i].TargetLang < entries[j].SourceLang+entries[j].TargetLang
+		}
+		return entries[i].Source < entries[j].Source
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("saveTranslationMemory marshal err: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(translationMemoryFile), os.ModePerm); err != nil {
+		return fmt.Errorf("saveTranslationMemory MkdirAll err: %w", err)
+	}
+	return os.WriteFile(translationMemoryFile, data, 0644)
+}
+
+func tmSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	maxLen := max(len(ra), len(rb))
+	if maxLen == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein.DistanceForStrings(ra, rb, levenshtein.DefaultOptions))/float64(maxLen)
+}
+
+// lookupTranslationMemory 查询翻译记忆，先精确匹配，再按编辑距离模糊匹配。
+// 只有精确匹配的译文可以直接复用，模糊匹配的记录只作为翻译时的参考。数字不同的句子不做模糊匹配
+func lookupTranslationMemory(source string, sourceLang, targetLang types.StandardLanguageCode) (types.TranslationMemoryEntry, string) {
+	loadTranslationMemory()
+	normalized := normalizeTmSource(source)
+	if normalized == "" {
+		return types.TranslationMemoryEntry{}, ""
+	}
+
+	translationMemoryMutex.RLock()
+	best, match := findTranslationMemoryLocked(normalized, string(sourceLang), string(targetLang))
+	translationMemoryMutex.RUnlock()
+	if best == nil {
+		return types.TranslationMemoryEntry{}, ""
+	}
+
+	translationMemoryMutex.Lock()
+	best.HitCount++
+	entry := *best
+	translationMemoryMutex.Unlock()
+	return entry, match
+}
+
+// 调用方需持有translationMemoryMutex读锁
+func findTranslationMemoryLocked(normalized, sourceLang, targetLang string) (*types.TranslationMemoryEntry, string) {
+	pairs := matchingTmPairsLocked(sourceLang, targetLang)
+	for _, pair := range pairs {
+		if entry, ok := pair.exact[normalized]; ok {
+			return entry, types.TranslationMemoryMatchExact
+		}
+	}
+
+	threshold := config.Conf.App.TranslationMemoryFuzzy
+	if threshold <= 0 || threshold >= 1 {
+		return nil, ""
+	}
+	var (
+		best           *types.TranslationMemoryEntry
+		bestSimilarity float64
+	)
+	sourceLen := len([]rune(normalized))
+	numbers := strings.Join(tmNumberRegex.FindAllString(normalized, -1), ",")
+	// 长度超出这个范围时不可能满足相似度，不用计算编辑距离
+	minLen, maxLen := int(math.Ceil(threshold*float64(sourceLen))), int(float64(sourceLen)/threshold)
+	for _, pair := range pairs {
+		for length := minLen; length <= maxLen; length++ {
+			for _, key := range pair.byLength[length] {
+				if strings.Join(tmNumberRegex.FindAllString(key, -1), ",") != numbers {
+					continue
+				}
+				if similarity := tmSimilarity(normalized, key); similarity >= threshold && similarity > bestSimilarity {
+					best, bestSimilarity = pair.exact[key], similarity
+				}
+			}
+		}
+	}
+	if best == nil {
+		return nil, ""
+	}
+	return best, types.TranslationMemoryMatchFuzzy
+}
+
+// translationMemoryReference 把模糊匹配的记录格式化为提示词中的参考译文
+func translationMemoryReference(entry types.TranslationMemoryEntry) string {
+	return fmt.Sprintf("%s => %s", entry.Source, entry.Target)
+}
+
+// countTranslationMemoryHits 累加任务的翻译记忆命中统计
+func countTranslationMemoryHits(taskPtr *types.SubtitleTask, items []*TranslatedItem) {
+	for _, item := range items {
+		switch item.MemoryMatch {
+		case types.TranslationMemoryMatchExact:
+			taskPtr.TmExactHitNum++
+		case types.TranslationMemoryMatchFuzzy:
+			taskPtr.TmFuzzyHitNum++
+		default:
+			taskPtr.TmMissNum++
+		}
+	}
+}
+
+// rememberTranslations 把一个音频片段的译文写入翻译记忆。审查过的片段只写入审查通过或复核后修正的句子，
+// 仍有问题和未经复核的译文不写入，避免之后作为精确命中被复用
+func rememberTranslations(items []*TranslatedItem, reviewItems []*types.QualityReviewItem, sourceLang, targetLang types.StandardLanguageCode) {
+	if !config.Conf.App.EnableTranslationMemory {
+		return
+	}
+	if reviewItems != nil {
+		passed := make([]*TranslatedItem, 0, len(items))
+		for i, item := range items {
+			if status := reviewItems[i].Status; status == types.QualityStatusOk || status == types.QualityStatusFixed {
+				passed = append(passed, item)
+			}
+		}
+		items = passed
+	}
+	if err := addTranslationMemory(items, sourceLang, targetLang); err != nil {
+		log.GetLogger().Warn("rememberTranslations addTranslationMemory error", zap.Error(err))
+	}
+}
+
+// addTranslationMemory 把新的翻译结果写入翻译记忆，连同命中次数一起持久化
+func addTranslationMemory(items []*TranslatedItem, sourceLang, targetLang types.StandardLanguageCode) error {
+	loadTranslationMemory()
+	translationMemoryMutex.Lock()
+	defer translationMemoryMutex.Unlock()
+
+	changed := 0
+	now := time.Now().Unix()
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		// 精确命中记忆时命中次数已经更新，需要一起持久化。模糊命中的句子是重新翻译的，译文作为新记录保存
+		if item.MemoryMatch == types.TranslationMemoryMatchExact {
+			changed++
+			continue
+		}
+		// 翻译失败时译文等于原文，违反术语表的译文也不记录
+		if item.TranslatedText == "" || item.TranslatedText == item.OriginText || len(item.GlossaryViolations) > 0 {
+			continue
+		}
+		if normalizeTmSource(item.OriginText) == "" {
+			continue
+		}
+		putTranslationMemoryLocked(&types.TranslationMemoryEntry{
+			Source:     item.OriginText,
+			Target:     item.TranslatedText,
+			SourceLang: string(sourceLang),
+			TargetLang: string(targetLang),
+			UpdateTime: now,
+		})
+		changed++
+	}
+	if changed == 0 {
+		return nil
+	}
+	return saveTranslationMemoryLocked()
+}
+
+// ImportTmx 导入TMX文件，返回导入的记录数
+func (s Service) ImportTmx(r io.Reader) (int, error) {
+	var tmx types.Tmx
+	if err := xml.NewDecoder(r).Decode(&tmx); err != nil {
+		return 0, fmt.Errorf("ImportTmx decode err: %w", err)
+	}
+
+	loadTranslationMemory()
+	translationMemoryMutex.Lock()
+	defer translationMemoryMutex.Unlock()
+
+	count := 0
+	now := time.Now().Unix()
+	for _, tu := range tmx.Body.Tus {
+		if len(tu.Tuvs) < 2 {
+			continue
+		}
+		// 以header中的srclang为原文，未指定或为*时取第一个tuv
+		source := tu.Tuvs[0]
+		for _, tuv := range tu.Tuvs {
+			if normalizeTmLang(tuv.Lang) == normalizeTmLang(tmx.Header.SrcLang) {
+				source = tuv
+				break
+			}
+		}
+		for _, tuv := range tu.Tuvs {
+			if tuv.Lang == source.Lang || strings.TrimSpace(tuv.Seg) == "" || strings.TrimSpace(source.Seg) == "" {
+				continue
+			}
+			putTranslationMemoryLocked(&types.TranslationMemoryEntry{
+				Source:     strings.TrimSpace(source.Seg),
+				Target:     strings.TrimSpace(tuv.Seg),
+				SourceLang: normalizeTmLang(source.Lang),
+				TargetLang: normalizeTmLang(tuv.Lang),
+				UpdateTime: now,
+			})
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, saveTranslationMemoryLocked()
+}
+
+// ExportTmx 导出指定语言对的翻译记忆，语言为空时导出全部
+func (s Service) ExportTmx(w io.Writer, sourceLang, targetLang string) error {
+	tmx := types.Tmx{
+		Version: "1.4",
+		Header: types.TmxHeader{
+			CreationTool:        "KrillinAI",
+			CreationToolVersion: "1.0",
+			SegType:             "sentence",
+			OTmf:                "json",
+			AdminLang:           "en",
+			SrcLang:             "*all*",
+			DataType:            "plaintext",
+		},
+	}
+	// TMX要求BCP-47语言标签，系统中的zh_cn导出为zh-CN，导入时再归一化
+	if sourceLang != "" {
+		tmx.Header.SrcLang = tmxLanguageTag(sourceLang)
+	}
+	loadTranslationMemory()
+	translationMemoryMutex.RLock()
+	for _, pair := range translationMemory {
+		for _, entry := range pair.exact {
+			if sourceLang != "" && normalizeTmLang(entry.SourceLang) != normalizeTmLang(sourceLang) {
+				continue
+			}
+			if targetLang != "" && normalizeTmLang(entry.TargetLang) != normalizeTmLang(targetLang) {
+				continue
+			}
+			tmx.Body.Tus = append(tmx.Body.Tus, types.TmxTu{Tuvs: []types.TmxTuv{
+				{Lang: tmxLanguageTag(entry.SourceLang), Seg: entry.Source},
+				{Lang: tmxLanguageTag(entry.TargetLang), Seg: entry.Target},
+			}})
+		}
+	}
+	translationMemoryMutex.RUnlock()
+
+	sort.Slice(tmx.Body.Tus, func(i, j int) bool {
+		return tmx.Body.Tus[i].Tuvs[0].Seg < tmx.Body.Tus[j].Tuvs[0].Seg
+	})
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(tmx)
+}
+
+// tmxLanguageTag 把语言代码转为TMX使用的BCP-47标签，如zh_cn转为zh-CN
+func tmxLanguageTag(lang string) string {
+	return subtitleLanguageTag(types.StandardLanguageCode(normalizeTmLang(lang)))
+}
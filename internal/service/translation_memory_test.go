@@ -0,0 +1,127 @@
+package service
+
+import (
+	"encoding/xml"
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"os"
+	"strings"
+	"testing"
+)
+
+func resetTranslationMemory(t *testing.T, entries ...*types.TranslationMemoryEntry) {
+	t.Helper()
+	// 测试中不读取记忆文件
+	translationMemoryOnce.Do(func() {})
+	translationMemoryMutex.Lock()
+	defer translationMemoryMutex.Unlock()
+	translationMemory = make(map[string]*tmPair)
+	translationMemoryAliases = make(map[string][]string)
+	for _, entry := range entries {
+		putTranslationMemoryLocked(entry)
+	}
+}
+
+func TestLookupTranslationMemory(t *testing.T) {
+	oldFuzzy := config.Conf.App.TranslationMemoryFuzzy
+	config.Conf.App.TranslationMemoryFuzzy = 0.9
+	defer func() { config.Conf.App.TranslationMemoryFuzzy = oldFuzzy }()
+
+	resetTranslationMemory(t,
+		&types.TranslationMemoryEntry{Source: "Welcome back to the channel!", Target: "欢迎回到频道！", SourceLang: "en_us", TargetLang: "zh_cn"},
+		&types.TranslationMemoryEntry{Source: "See you in episode 3.", Target: "第3集见。", SourceLang: "en", TargetLang: "zh_cn"},
+		&types.TranslationMemoryEntry{Source: "Thanks for watching", Target: "感謝觀看", SourceLang: "en", TargetLang: "zh_tw"},
+	)
+
+	tests := []struct {
+		name       string
+		source     string
+		sourceLang types.StandardLanguageCode
+		targetLang types.StandardLanguageCode
+		wantTarget string
+		wantMatch  string
+	}{
+		{"exact with region fallback", "welcome back to the channel", "en", "zh_cn", "欢迎回到频道！", types.TranslationMemoryMatchExact},
+		{"exact", "See you in episode 3", "en", "zh_cn", "第3集见。", types.TranslationMemoryMatchExact},
+		{"fuzzy", "Welcome back to the channels", "en", "zh_cn", "欢迎回到频道！", types.TranslationMemoryMatchFuzzy},
+		{"different numbers", "See you in episode 4", "en", "zh_cn", "", ""},
+		{"different region is not reused", "Thanks for watching", "en", "zh_cn", "", ""},
+		{"miss", "Something else entirely", "en", "zh_cn", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, match := lookupTranslationMemory(tt.source, tt.sourceLang, tt.targetLang)
+			if match != tt.wantMatch || entry.Target != tt.wantTarget {
+				t.Errorf("got (%q, %q), want (%q, %q)", entry.Target, match, tt.wantTarget, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestLanguagePairKeys(t *testing.T) {
+	got := languagePairKeys("en-US", "zh_cn")
+	want := []string{"en_us->zh_cn", "en_us->zh", "en->zh_cn", "en->zh"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExportTmxLanguageTags(t *testing.T) {
+	resetTranslationMemory(t,
+		&types.TranslationMemoryEntry{Source: "Welcome back", Target: "欢迎回来", SourceLang: "en_us", TargetLang: "zh_cn"},
+		&types.TranslationMemoryEntry{Source: "Thanks", Target: "谢谢", SourceLang: "en", TargetLang: "zh_cn"},
+	)
+	var builder strings.Builder
+	if err := (Service{}).ExportTmx(&builder, "en_us", "zh-CN"); err != nil {
+		t.Fatal(err)
+	}
+	var tmx types.Tmx
+	if err := xml.Unmarshal([]byte(builder.String()), &tmx); err != nil {
+		t.Fatal(err)
+	}
+	if tmx.Header.SrcLang != "en-US" || len(tmx.Body.Tus) != 1 {
+		t.Fatalf("unexpected tmx %+v", tmx)
+	}
+	if tuvs := tmx.Body.Tus[0].Tuvs; tuvs[0].Lang != "en-US" || tuvs[1].Lang != "zh-CN" {
+		t.Errorf("tuv languages = %s, %s, want en-US, zh-CN", tuvs[0].Lang, tuvs[1].Lang)
+	}
+}
+
+func TestRememberTranslationsAfterReview(t *testing.T) {
+	oldEnable := config.Conf.App.EnableTranslationMemory
+	config.Conf.App.EnableTranslationMemory = true
+	defer func() { config.Conf.App.EnableTranslationMemory = oldEnable }()
+	// 记忆文件写到临时目录
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	resetTranslationMemory(t)
+
+	items := []*TranslatedItem{
+		{OriginText: "Good morning", TranslatedText: "早上好"},
+		{OriginText: "Welcome back", TranslatedText: "欢迎回来"},
+		{OriginText: "See you soon", TranslatedText: "See you soon now"},
+		{OriginText: "Thanks for watching", TranslatedText: "感谢收看"},
+	}
+	reviewItems := []*types.QualityReviewItem{
+		{Status: types.QualityStatusOk},
+		{Status: types.QualityStatusFixed},
+		{Status: types.QualityStatusUnresolved},
+		{Status: types.QualityStatusUnverified},
+	}
+	rememberTranslations(items, reviewItems, types.LanguageNameEnglish, types.LanguageNameSimplifiedChinese)
+
+	for i, want := range []bool{true, true, false, false} {
+		_, match := lookupTranslationMemory(items[i].OriginText, types.LanguageNameEnglish, types.LanguageNameSimplifiedChinese)
+		if (match == types.TranslationMemoryMatchExact) != want {
+			t.Errorf("%q remembered = %v, want %v", items[i].OriginText, match == types.TranslationMemoryMatchExact, want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"krillin-ai/config"
+	"krillin-ai/internal/storage"
+	"krillin-ai/log"
+	"krillin-ai/pkg/util"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+const (
+	VAD_SAMPLE_RATE        = 16000
+	VAD_FRAME_DURATION     = 0.03 // 每帧时长
+	VAD_MIN_THRESHOLD_DB   = -50  // 语音能量阈值的下限(dBFS)，防止纯静音文件的底噪被当成语音
+	VAD_UNVOICED_MARGIN_DB = 6    // 清辅音能量较低，过零率符合时放宽的阈值
+	VAD_UNVOICED_ZCR_MIN   = 0.15 // 清辅音的过零率范围
+	VAD_UNVOICED_ZCR_MAX   = 0.45
+	MUSIC_MIN_DURATION     = 10  // 超过该时长的连续片段才做音乐检测
+	MUSIC_LOW_ENERGY_RATIO = 0.1 // 低能量帧占比低于该值视为音乐，语音因为音节间停顿低能量帧占比明显更高
+)
+
+type speechRegion struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// GetSpeechSegments 通过语音活动检测得到待转录的音频片段，长静音和音乐不会出现在结果中，每段不超过maxSegmentDuration
+func GetSpeechSegments(input string, maxSegmentDuration float64) ([][2]float64, error) {
+	if maxSegmentDuration < MIN_SEGMENT_DURATION {
+		return nil, fmt.Errorf("segment duration must be greater than %v seconds", MIN_SEGMENT_DURATION)
+	}
+	audioDuration, err := util.GetAudioDuration(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio duration: %w", err)
+	}
+
+	var regions []speechRegion
+	switch config.Conf.Vad.Provider {
+	case "silero":
+		regions, err = detectSpeechBySilero(input)
+	default:
+		regions, err = detectSpeechByEnergy(input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vadConf := config.Conf.Vad
+	regions = mergeSpeechRegions(regions, vadConf.MinSilenceDuration)
+	kept := make([]speechRegion, 0, len(regions))
+	for _, r := range regions {
+		if r.End-r.Start < vadConf.MinSpeechDuration {
+			continue
+		}
+		kept = append(kept, speechRegion{
+			Start: math.Max(0, r.Start-vadConf.SpeechPadding),
+			End:   math.Min(audioDuration, r.End+vadConf.SpeechPadding),
+		})
+	}
+	regions = mergeSpeechRegions(kept, 0)
+
+	segments := groupSpeechRegions(regions, maxSegmentDuration, vadConf.MaxSilenceDuration)
+	return splitLongSegments(segments, maxSegmentDuration, func(start, end float64) (float64, error) {
+		return getQuietestTimePoint(input, start, end)
+	})
+}
+
+// 把按固定时长得到的切分点转换为连续的片段
+func splitPointsToSegments(timePoints []float64) [][2]float64 {
+	segments := make([][2]float64, 0, len(timePoints))
+	for i := 0; i+1 < len(timePoints); i++ {
+		segments = append(segments, [2]float64{timePoints[i], timePoints[i+1]})
+	}
+	return segments
+}
+
+// 合并间隔小于minGap的语音区间
+func mergeSpeechRegions(regions []speechRegion, minGap float64) []speechRegion {
+	if len(regions) == 0 {
+		return regions
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Start < regions[j].Start })
+	merged := []speechRegion{regions[0]}
+	for _, r := range regions[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start-last.End <= minGap {
+			last.End = math.Max(last.End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// 把语音区间组合成转录片段，遇到长静音或者片段超长时开始新片段
+func groupSpeechRegions(regions []speechRegion, maxSegmentDuration, maxSilenceDuration float64) [][2]float64 {
+	var segments [][2]float64
+	for _, r := range regions {
+		if len(segments) > 0 {
+			last := &segments[len(segments)-1]
+			if r.Start-last[1] <= maxSilenceDuration && r.End-last[0] <= maxSegmentDuration {
+				last[1] = r.End
+				continue
+			}
+		}
+		segments = append(segments, [2]float64{r.Start, r.End})
+	}
+	return segments
+}
+
+// 单个语音区间超过最大时长时，在其中最安静的位置切开，quietestTimePoint返回时间范围内最安静的位置
+func splitLongSegments(segments [][2]float64, maxSegmentDuration float64, quietestTimePoint func(start, end float64) (float64, error)) ([][2]float64, error) {
+	var result [][2]float64
+	for _, seg := range segments {
+		for seg[1]-seg[0] > maxSegmentDuration+MIN_DURATION {
+			splitPoint, err := quietestTimePoint(seg[0]+maxSegmentDuration-2*TOLERANCE_DURATION, seg[0]+maxSegmentDuration)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get quietest time point: %w", err)
+			}
+			result = append(result, [2]float64{seg[0], splitPoint})
+			seg[0] = splitPoint
+		}
+		result = append(result, seg)
+	}
+	return result, nil
+}
+
+// 解码音频并计算每帧的能量(dBFS)和过零率
+func computeFrameFeatures(input string) (energies, zcrs []float64, err error) {
+	cmd := exec.Command(
+		storage.FfmpegPath,
+		"-i", input,
+		"-f", "s16le",
+		"-ar", fmt.Sprintf("%d", VAD_SAMPLE_RATE),
+		"-ac", "1",
+		"-af", "highpass=f=80",
+		"pipe:1",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start ffmpeg command: [%s] %w", cmd.String(), err)
+	}
+
+	frameSize := int(VAD_SAMPLE_RATE * VAD_FRAME_DURATION)
+	frame := make([]int16, frameSize)
+	reader := bufio.NewReader(stdout)
+	for {
+		if err := binary.Read(reader, binary.LittleEndian, frame); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			_ = cmd.Wait()
+			return nil, nil, fmt.Errorf("error reading from stdout: [%s] %w", cmd.String(), err)
+		}
+		var sum float64
+		crossings := 0
+		for i, sample := range frame {
+			s := float64(sample) / math.MaxInt16
+			sum += s * s
+			if i > 0 && (sample >= 0) != (frame[i-1] >= 0) {
+				crossings++
+			}
+		}
+		energies = append(energies, 10*math.Log10(sum/float64(frameSize)+1e-10))
+		zcrs = append(zcrs, float64(crossings)/float64(frameSize-1))
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, nil, fmt.Errorf("ffmpeg command run failed: [%s] %w", cmd.String(), err)
+	}
+	return energies, zcrs, nil
+}
+
+// 以能量最低的10%帧估计底噪
+func estimateNoiseFloor(energies []float64) float64 {
+	sorted := append([]float64(nil), energies...)
+	sort.Float64s(sorted)
+	return sorted[len(sorted)/10]
+}
+
+// 基于短时能量和过零率的语音活动检测
+func detectSpeechByEnergy(input string) ([]speechRegion, error) {
+	energies, zcrs, err := computeFrameFeatures(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(energies) == 0 {
+		return nil, nil
+	}
+
+	noiseFloor := estimateNoiseFloor(energies)
+	threshold := math.Max(noiseFloor+config.Conf.Vad.EnergyThresholdDb, VAD_MIN_THRESHOLD_DB)
+	log.GetLogger().Info("detectSpeechByEnergy threshold", zap.Float64("noiseFloor", noiseFloor), zap.Float64("threshold", threshold))
+
+	isSpeech := func(i int) bool {
+		if energies[i] > threshold {
+			return true
+		}
+		return energies[i] > threshold-VAD_UNVOICED_MARGIN_DB && zcrs[i] >= VAD_UNVOICED_ZCR_MIN && zcrs[i] <= VAD_UNVOICED_ZCR_MAX
+	}
+
+	var regions []speechRegion
+	startFrame := -1
+	for i := 0; i <= len(energies); i++ {
+		if i < len(energies) && isSpeech(i) {
+			if startFrame < 0 {
+				startFrame = i
+			}
+			continue
+		}
+		if startFrame >= 0 {
+			regions = append(regions, speechRegion{
+				Start: float64(startFrame) * VAD_FRAME_DURATION,
+				End:   float64(i) * VAD_FRAME_DURATION,
+			})
+			startFrame = -1
+		}
+	}
+	regions = mergeSpeechRegions(regions, config.Conf.Vad.MinSilenceDuration)
+
+	if !config.Conf.Vad.SkipMusic {
+		return regions, nil
+	}
+	filtered := make([]speechRegion, 0, len(regions))
+	for _, r := range regions {
+		if r.End-r.Start >= MUSIC_MIN_DURATION {
+			from := int(r.Start / VAD_FRAME_DURATION)
+			to := min(int(r.End/VAD_FRAME_DURATION), len(energies))
+			if ratio := lowEnergyFrameRatio(energies[from:to]); ratio < MUSIC_LOW_ENERGY_RATIO {
+				log.GetLogger().Info("detectSpeechByEnergy skip music", zap.Float64("start", r.Start), zap.Float64("end", r.End), zap.Float64("lowEnergyRatio", ratio))
+				continue
+			}
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered, nil
+}
+
+// 计算每1秒窗口内能量低于窗口平均能量一半的帧占比
+func lowEnergyFrameRatio(energies []float64) float64 {
+	window := int(math.Round(1 / VAD_FRAME_DURATION))
+	if len(energies) < window {
+		return 1
+	}
+	lowCount := 0
+	for i := 0; i+window <= len(energies); i += window {
+		var mean float64
+		for _, e := range energies[i : i+window] {
+			mean += math.Pow(10, e/10)
+		}
+		mean /= float64(window)
+		for _, e := range energies[i : i+window] {
+			if math.Pow(10, e/10) < mean/2 {
+				lowCount++
+			}
+		}
+	}
+	return float64(lowCount) / float64(len(energies)/window*window)
+}
+
+// 调用silero-vad命令行工具，输出为[{"start":0.5,"end":3.2}]格式的json
+func detectSpeechBySilero(input string) ([]speechRegion, error) {
+	// 使用临时文件，避免同一目录下同时进行的任务互相覆盖结果
+	tmpFile, err := os.CreateTemp("", "silero_vad_*.json")
+	if err != nil {
+		return nil, fmt.Errorf("detectSpeechBySilero create temp file err: %w", err)
+	}
+	outputFile := tmpFile.Name()
+	tmpFile.Close()
+	defer os.Remove(outputFile)
+	cmd := exec.Command(storage.SileroVadPath, "--output", outputFile, input)
+	log.GetLogger().Info("detectSpeechBySilero", zap.String("cmd", cmd.String()))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.GetLogger().Error("detectSpeechBySilero cmd err", zap.String("output", string(output)), zap.Error(err))
+		return nil, fmt.Errorf("detectSpeechBySilero cmd err: %w", err)
+	}
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("detectSpeechBySilero read output err: %w", err)
+	}
+	var regions []speechRegion
+	if err = json.Unmarshal(data, &regions); err != nil {
+		return nil, fmt.Errorf("detectSpeechBySilero unmarshal output err: %w", err)
+	}
+	return regions, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSpeechRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []speechRegion
+		minGap  float64
+		want    []speechRegion
+	}{
+		{"empty", nil, 0.5, nil},
+		{"unsorted and overlapping", []speechRegion{{5, 6}, {0, 2}, {1, 3}}, 0, []speechRegion{{0, 3}, {5, 6}}},
+		{"small gap", []speechRegion{{0, 1}, {1.4, 2}, {3, 4}}, 0.5, []speechRegion{{0, 2}, {3, 4}}},
+		{"contained", []speechRegion{{0, 10}, {2, 3}}, 0, []speechRegion{{0, 10}}},
+	}
+	for _, tt := range tests {
+		if got := mergeSpeechRegions(tt.regions, tt.minGap); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGroupSpeechRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []speechRegion
+		want    [][2]float64
+	}{
+		{"empty", nil, nil},
+		{"short silences join", []speechRegion{{0, 10}, {12, 20}, {21, 30}}, [][2]float64{{0, 30}}},
+		{"long silence splits", []speechRegion{{0, 10}, {20, 30}}, [][2]float64{{0, 10}, {20, 30}}},
+		{"max duration splits", []speechRegion{{0, 40}, {41, 70}}, [][2]float64{{0, 40}, {41, 70}}},
+	}
+	for _, tt := range tests {
+		if got := groupSpeechRegions(tt.regions, 60, 5); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitLongSegments(t *testing.T) {
+	var calls [][2]float64
+	quietest := func(start, end float64) (float64, error) {
+		calls = append(calls, [2]float64{start, end})
+		return end - 1, nil
+	}
+	got, err := splitLongSegments([][2]float64{{0, 50}, {100, 230}}, 60, quietest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50秒不超过60+MIN_DURATION，不切分；130秒的片段在最安静的位置切成三段
+	want := [][2]float64{{0, 50}, {100, 159}, {159, 218}, {218, 230}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	wantCalls := [][2]float64{{100 + 60 - 2*TOLERANCE_DURATION, 160}, {159 + 60 - 2*TOLERANCE_DURATION, 219}}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("calls: got %v, want %v", calls, wantCalls)
+	}
+}
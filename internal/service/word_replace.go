@@ -0,0 +1,75 @@
+package service
+
+import (
+	"krillin-ai/internal/types"
+	"krillin-ai/pkg/util"
+)
+
+// setStepParamReplacers 根据保存的替换规则构建替换器，逐词替换的规则作用于转录结果，其余规则作用于原文句子
+func setStepParamReplacers(stepParam *types.SubtitleTaskStepParam) error {
+	replacer, err := util.NewWordReplacer(stepParam.ReplaceRules)
+	if err != nil {
+		return err
+	}
+	stepParam.WordReplacer, stepParam.PhraseReplacer = replacer.Split()
+	return nil
+}
+
+// replaceText 应用替换规则，没有规则时原样返回
+func replaceText(replacer types.TextReplacer, text string) string {
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// replaceTranscriptionWords 用逐词替换的规则替换转录文字和词级时间戳中的文字，替换前后词一一对应，分句后的句子仍能和词对齐
+func replaceTranscriptionWords(replacer types.TextReplacer, transcriptionData *types.TranscriptionData) {
+	transcriptionData.Text = replaceText(replacer, transcriptionData.Text)
+	for i := range transcriptionData.Words {
+		transcriptionData.Words[i].Text = replaceText(replacer, transcriptionData.Words[i].Text)
+	}
+}
+
+// replaceSentences 用其余规则替换送去翻译的句子，返回新的切片，原句子保留用于对齐时间戳
+func replaceSentences(replacer types.TextReplacer, sentences []string) []string {
+	if replacer == nil {
+		return sentences
+	}
+	replaced := make([]string, len(sentences))
+	for i, sentence := range sentences {
+		replaced[i] = replacer.Replace(sentence)
+	}
+	return replaced
+}
+
+// restoreOriginText 把译文条目中的原文换回替换前的句子，二次分割和对齐时间戳需要和转录的词一致
+func restoreOriginText(translatedItems []*TranslatedItem, sentences []string) {
+	for i, item := range translatedItems {
+		if item != nil && i < len(sentences) {
+			item.OriginText = sentences[i]
+		}
+	}
+}
+
+// replaceTranslatedText 替换译文，先应用逐词替换的规则再应用其余规则
+func replaceTranslatedText(stepParam *types.SubtitleTaskStepParam, translatedItems []*TranslatedItem) {
+	for _, item := range translatedItems {
+		item.TranslatedText = replaceText(stepParam.PhraseReplacer, replaceText(stepParam.WordReplacer, item.TranslatedText))
+	}
+}
+
+// replaceAlignedOrigin 对齐时间戳后用其余规则替换字幕中的原文，这些规则可能改变分词，不能在对齐前应用
+func replaceAlignedOrigin(replacer types.TextReplacer, srtBlocks []*util.SrtBlock, shortOriginSrtMap map[int][]util.SrtBlock) {
+	if replacer == nil {
+		return
+	}
+	for _, block := range srtBlocks {
+		block.OriginLanguageSentence = replacer.Replace(block.OriginLanguageSentence)
+	}
+	for _, blocks := range shortOriginSrtMap {
+		for i := range blocks {
+			blocks[i].OriginLanguageSentence = replacer.Replace(blocks[i].OriginLanguageSentence)
+		}
+	}
+}
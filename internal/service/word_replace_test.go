@@ -0,0 +1,87 @@
+package service
+
+import (
+	"krillin-ai/config"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/util"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestWordReplaceKeepsWordsAligned(t *testing.T) {
+	replacer, err := util.NewWordReplacer([]types.ReplaceRule{{From: "colour", To: "color"}, {From: "open ai", To: "OpenAI", IgnoreCase: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wordReplacer, phraseReplacer := replacer.Split()
+	data := &types.TranscriptionData{
+		Text:  "open AI picks a colour",
+		Words: []types.Word{{Text: "open"}, {Text: "AI"}, {Text: "picks"}, {Text: "a"}, {Text: "colour"}},
+	}
+	replaceTranscriptionWords(wordReplacer, data)
+	if data.Text != "open AI picks a color" || data.Words[1].Text != "AI" || data.Words[4].Text != "color" {
+		t.Errorf("unexpected transcription %+v", data)
+	}
+
+	blocks := []*util.SrtBlock{{Index: 1, OriginLanguageSentence: data.Text}}
+	shortBlocks := map[int][]util.SrtBlock{1: {{Index: 1, OriginLanguageSentence: "open AI "}, {Index: 1, OriginLanguageSentence: "picks a color "}}}
+	replaceAlignedOrigin(phraseReplacer, blocks, shortBlocks)
+	if blocks[0].OriginLanguageSentence != "OpenAI picks a color" || shortBlocks[1][0].OriginLanguageSentence != "OpenAI " {
+		t.Errorf("unexpected aligned origin %q, %q", blocks[0].OriginLanguageSentence, shortBlocks[1][0].OriginLanguageSentence)
+	}
+
+	items := []*TranslatedItem{{TranslatedText: "open ai 的 colour"}}
+	replaceTranslatedText(&types.SubtitleTaskStepParam{WordReplacer: wordReplacer, PhraseReplacer: phraseReplacer}, items)
+	if items[0].TranslatedText != "OpenAI 的 color" {
+		t.Errorf("unexpected translation %q", items[0].TranslatedText)
+	}
+	replaceTranslatedText(&types.SubtitleTaskStepParam{}, items)
+}
+
+func TestPhraseRulesApplyBeforeTranslation(t *testing.T) {
+	log.Logger = zap.NewNop()
+	oldMode, oldParallel, oldMemory := config.Conf.App.TranslateMode, config.Conf.App.TranslateParallelNum, config.Conf.App.EnableTranslationMemory
+	config.Conf.App.TranslateMode, config.Conf.App.TranslateParallelNum, config.Conf.App.EnableTranslationMemory = "", 1, false
+	defer func() {
+		config.Conf.App.TranslateMode, config.Conf.App.TranslateParallelNum, config.Conf.App.EnableTranslationMemory = oldMode, oldParallel, oldMemory
+	}()
+	replacer, err := util.NewWordReplacer([]types.ReplaceRule{{From: "open ai", To: "OpenAI", IgnoreCase: true}, {From: `gpt (\d)`, To: "GPT-$1", Regex: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, phraseReplacer := replacer.Split()
+
+	var (
+		mu      sync.Mutex
+		prompts []string
+	)
+	s := Service{ChatCompleter: fakeChatCompleter(func(prompt string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		prompts = append(prompts, prompt)
+		return "译文", nil
+	})}
+	sentences := []string{"open AI released gpt 5"}
+	results, err := s.translateSplitSentences(t.TempDir(), replaceSentences(phraseReplacer, sentences), types.LanguageNameEnglish, types.LanguageNameSimplifiedChinese, 0, nil, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 1 || !strings.Contains(prompts[0], "OpenAI released GPT-5") || strings.Contains(prompts[0], "open AI") {
+		t.Errorf("translation prompt should contain the replaced sentence, got %q", prompts)
+	}
+	if results[0].OriginText != "OpenAI released GPT-5" {
+		t.Errorf("OriginText before restore = %q", results[0].OriginText)
+	}
+
+	restoreOriginText(results, sentences)
+	if results[0].OriginText != sentences[0] || results[0].TranslatedText != "译文" {
+		t.Errorf("restored item = %+v", results[0])
+	}
+	if got := replaceSentences(nil, sentences); &got[0] != &sentences[0] {
+		t.Errorf("sentences without rules should be returned as is")
+	}
+}
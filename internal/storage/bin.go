@@ -9,4 +9,6 @@ var (
 	WhisperKitPath    string
 	WhispercppPath    string
 	EdgeTtsPath       string
+	PyannotePath      string
+	SileroVadPath     string
 )
@@ -0,0 +1,55 @@
+package testutil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// FakeApi 测试第三方服务客户端用的假接口，记录收到的请求并返回固定的响应
+type FakeApi struct {
+	Path        string // 期望的请求路径，不一致时返回404，为空时不检查
+	Status      int    // 响应状态码，0为200
+	ContentType string // 响应类型，为空时为application/json
+	Response    string // 响应内容
+
+	mutex   sync.Mutex
+	request *http.Request
+	body    []byte
+}
+
+// NewFakeServer 启动假接口服务，返回服务地址，测试结束时自动关闭
+func NewFakeServer(t *testing.T, api *FakeApi) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		api.mutex.Lock()
+		api.request, api.body = r, body
+		api.mutex.Unlock()
+
+		if api.Path != "" && r.URL.Path != api.Path {
+			http.NotFound(w, r)
+			return
+		}
+		contentType := api.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		w.Header().Set("Content-Type", contentType)
+		if api.Status != 0 {
+			w.WriteHeader(api.Status)
+		}
+		_, _ = io.WriteString(w, api.Response)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// LastRequest 返回最后一次收到的请求和请求体，没有收到请求时为nil
+func (a *FakeApi) LastRequest() (*http.Request, []byte) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.request, a.body
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 字幕行在画面中的位置
+const (
+	AssPositionBottom = "bottom"
+	AssPositionTop    = "top"
+)
+
+var assColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// AssLineStyle 一种语言字幕行的样式
+type AssLineStyle struct {
+	FontSize     float64 `json:"font_size"`     // 字号，占视频高度的百分比，如5表示5%
+	PrimaryColor string  `json:"primary_color"` // 文字颜色，#RRGGBB
+	Position     string  `json:"position"`      // bottom或top
+}
+
+// AssStyle ass字幕样式，尺寸都按视频分辨率的百分比设置，不同分辨率下效果一致
+type AssStyle struct {
+	Name         string       `json:"name"`
+	FontFamily   string       `json:"font_family"`
+	Bold         bool         `json:"bold"`
+	OutlineColor string       `json:"outline_color"` // 描边颜色，#RRGGBB
+	OutlineWidth float64      `json:"outline_width"` // 描边宽度，占视频高度的百分比
+	Shadow       float64      `json:"shadow"`        // 阴影距离，占视频高度的百分比
+	MarginH      float64      `json:"margin_h"`      // 左右边距，占视频宽度的百分比
+	MarginV      float64      `json:"margin_v"`      // 上下边距，占视频高度的百分比
+	Origin       AssLineStyle `json:"origin"`        // 原文字幕行
+	Target       AssLineStyle `json:"target"`        // 译文字幕行
+}
+
+// 内置的字幕样式预设，同名的已保存预设优先
+var BuiltinAssStyles = map[string]*AssStyle{
+	"classic": {
+		Name:         "classic",
+		FontFamily:   "Arial",
+		Bold:         true,
+		OutlineColor: "#000000",
+		OutlineWidth: 0.8,
+		Shadow:       0.5,
+		MarginH:      2.5,
+		MarginV:      7,
+		Origin:       AssLineStyle{FontSize: 3.5, PrimaryColor: "#FFBF00", Position: AssPositionBottom},
+		Target:       AssLineStyle{FontSize: 4.8, PrimaryColor: "#FFBF00", Position: AssPositionBottom},
+	},
+	"clean": {
+		Name:         "clean",
+		FontFamily:   "Arial",
+		OutlineColor: "#000000",
+		OutlineWidth: 0.3,
+		Shadow:       0.2,
+		MarginH:      10,
+		MarginV:      5,
+		Origin:       AssLineStyle{FontSize: 3.5, PrimaryColor: "#D0D0D0", Position: AssPositionBottom},
+		Target:       AssLineStyle{FontSize: 4.5, PrimaryColor: "#FFFFFF", Position: AssPositionBottom},
+	},
+	"split": {
+		Name:         "split",
+		FontFamily:   "Arial",
+		Bold:         true,
+		OutlineColor: "#000000",
+		OutlineWidth: 0.5,
+		Shadow:       0.3,
+		MarginH:      5,
+		MarginV:      5,
+		Origin:       AssLineStyle{FontSize: 3.5, PrimaryColor: "#FFFFFF", Position: AssPositionTop},
+		Target:       AssLineStyle{FontSize: 4.5, PrimaryColor: "#FFE066", Position: AssPositionBottom},
+	},
+}
+
+func (l AssLineStyle) validate(name string) error {
+	if l.FontSize < 1 || l.FontSize > 20 {
+		return fmt.Errorf("%s字号font_size的取值范围为1-20", name)
+	}
+	if !assColorRegex.MatchString(l.PrimaryColor) {
+		return fmt.Errorf("%s颜色primary_color必须为#RRGGBB格式", name)
+	}
+	if l.Position != AssPositionBottom && l.Position != AssPositionTop {
+		return fmt.Errorf("%s位置position只能为bottom或top", name)
+	}
+	return nil
+}
+
+func (s *AssStyle) Validate() error {
+	if strings.TrimSpace(s.FontFamily) == "" || strings.Contains(s.FontFamily, ",") {
+		return errors.New("字体font_family不能为空，且不能包含逗号")
+	}
+	if !assColorRegex.MatchString(s.OutlineColor) {
+		return errors.New("描边颜色outline_color必须为#RRGGBB格式")
+	}
+	if s.OutlineWidth < 0 || s.OutlineWidth > 5 || s.Shadow < 0 || s.Shadow > 5 {
+		return errors.New("描边宽度outline_width和阴影shadow的取值范围为0-5")
+	}
+	if s.MarginH < 0 || s.MarginH > 40 || s.MarginV < 0 || s.MarginV > 40 {
+		return errors.New("边距margin_h和margin_v的取值范围为0-40")
+	}
+	if err := s.Origin.validate("原文"); err != nil {
+		return err
+	}
+	return s.Target.validate("译文")
+}
+
+// AssColor 把#RRGGBB转为ass使用的&H00BBGGRR
+func AssColor(color string) string {
+	color = strings.TrimPrefix(color, "#")
+	if len(color) != 6 {
+		return "&H00FFFFFF"
+	}
+	return strings.ToUpper("&H00" + color[4:6] + color[2:4] + color[0:2])
+}
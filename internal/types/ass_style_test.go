@@ -0,0 +1,57 @@
+package types
+
+import "testing"
+
+func TestAssColor(t *testing.T) {
+	tests := []struct {
+		color string
+		want  string
+	}{
+		{"#FFBF00", "&H0000BFFF"},
+		{"#1a2b3c", "&H003C2B1A"},
+		{"000000", "&H00000000"},
+		{"#FFF", "&H00FFFFFF"},
+		{"", "&H00FFFFFF"},
+	}
+	for _, tt := range tests {
+		if got := AssColor(tt.color); got != tt.want {
+			t.Errorf("AssColor(%q) = %q, want %q", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestAssStyleValidate(t *testing.T) {
+	valid := func(modify func(*AssStyle)) AssStyle {
+		style := *BuiltinAssStyles["classic"]
+		modify(&style)
+		return style
+	}
+	tests := []struct {
+		name    string
+		style   AssStyle
+		wantErr bool
+	}{
+		{"classic", valid(func(*AssStyle) {}), false},
+		{"no font", valid(func(s *AssStyle) { s.FontFamily = " " }), true},
+		{"comma in font", valid(func(s *AssStyle) { s.FontFamily = "Arial,Bold" }), true},
+		{"bad outline color", valid(func(s *AssStyle) { s.OutlineColor = "black" }), true},
+		{"outline too wide", valid(func(s *AssStyle) { s.OutlineWidth = 6 }), true},
+		{"negative shadow", valid(func(s *AssStyle) { s.Shadow = -1 }), true},
+		{"margin too large", valid(func(s *AssStyle) { s.MarginV = 41 }), true},
+		{"origin font too small", valid(func(s *AssStyle) { s.Origin.FontSize = 0.5 }), true},
+		{"target bad color", valid(func(s *AssStyle) { s.Target.PrimaryColor = "#GGGGGG" }), true},
+		{"target bad position", valid(func(s *AssStyle) { s.Target.Position = "middle" }), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.style.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	for name, style := range BuiltinAssStyles {
+		if err := style.Validate(); err != nil {
+			t.Errorf("builtin %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 大模型请求的用途，不同用途可以单独配置模型和参数
+const (
+	ChatPurposeTranslate = "translate" // 句子翻译
+	ChatPurposeSplit     = "split"     // 原文长句拆分
+	ChatPurposeAlign     = "align"     // 长句原文译文对齐拆分
+	ChatPurposeTitle     = "title"     // 视频标题和描述翻译
+	ChatPurposeReview    = "review"    // 译文质量审查
+)
+
+// ChatJsonSchema 要求大模型按schema输出json
+type ChatJsonSchema struct {
+	Name   string
+	Schema json.RawMessage
+}
+
+// ChatOptions 单次大模型请求的参数
+type ChatOptions struct {
+	Model        string // 为空时使用客户端配置的模型
+	SystemPrompt string // 为空时不发送系统提示词
+	Temperature  float64
+	MaxTokens    int
+	Timeout      time.Duration   // 0表示不限制
+	JsonSchema   *ChatJsonSchema // 不为空时使用接口的结构化输出能力，不支持的接口忽略
+}
+
+// 以下schema同时满足openai strict模式的要求：所有字段必填且不允许额外字段
+
+var SplitLongSentenceJsonSchema = &ChatJsonSchema{
+	Name: "split_long_sentence",
+	Schema: json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"align": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"origin_part": {"type": "string"},
+					"translated_part": {"type": "string"}
+				},
+				"required": ["origin_part", "translated_part"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["align"],
+	"additionalProperties": false
+}`),
+}
+
+var SplitOriginLongSentenceJsonSchema = &ChatJsonSchema{
+	Name: "split_origin_long_sentence",
+	Schema: json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"short_sentences": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"text": {"type": "string"}
+				},
+				"required": ["text"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["short_sentences"],
+	"additionalProperties": false
+}`),
+}
+
+var BatchTranslateJsonSchema = &ChatJsonSchema{
+	Name: "batch_translate",
+	Schema: json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"translations": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"id": {"type": "integer"},
+					"text": {"type": "string"}
+				},
+				"required": ["id", "text"],
+				"additionalProperties": false
+			}
+		},
+		"summary": {"type": "string"}
+	},
+	"required": ["translations", "summary"],
+	"additionalProperties": false
+}`),
+}
+
+var JsonRepairPrompt = `Your previous output for the task below is not valid. Fix it.
+
+[Task]
+{{.PreviousPrompt}}
+
+[Previous Output]
+{{.PreviousOutput}}
+
+[Error]
+{{.Error}}
+
+[Required JSON Schema]
+{{.Schema}}
+
+**Output only the corrected JSON object matching the schema, without any explanation or markdown:**`
@@ -0,0 +1,11 @@
+package types
+
+// SubtitleConfidence 单条字幕的识别置信度，用于置信度json文件和审阅报告
+type SubtitleConfidence struct {
+	Index              int      `json:"index"`
+	Timestamp          string   `json:"timestamp"`
+	OriginText         string   `json:"origin_text"`
+	TargetText         string   `json:"target_text"`
+	Confidence         float64  `json:"confidence"` // 为0表示转录服务未提供置信度
+	LowConfidenceWords []string `json:"low_confidence_words,omitempty"`
+}
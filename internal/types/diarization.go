@@ -0,0 +1,20 @@
+package types
+
+const (
+	SpeakerLabelModePrefix = "prefix" // 在字幕文字前加上[说话人]
+	SpeakerLabelModeStyle  = "style"  // 嵌入视频时按说话人使用不同的ass样式
+)
+
+type SpeakerSegment struct {
+	Speaker string  `json:"speaker"`
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+}
+
+// DiarizationHttpOutput http说话人分离服务的返回格式
+type DiarizationHttpOutput struct {
+	Segments []SpeakerSegment `json:"segments"`
+}
+
+// SpeakerAssColors 按说话人区分ass样式时依次使用的主颜色（BGR）
+var SpeakerAssColors = []string{"&H00BFFF", "&HFFFF00", "&H00FF00", "&HFF80FF", "&H0080FF", "&HFFFFFF"}
@@ -0,0 +1,29 @@
+package types
+
+type GlossaryTerm struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// Glossary 翻译术语表，Terms为原文术语到译文术语的映射，DoNotTranslate中的词保持原样不翻译
+type Glossary struct {
+	Name           string         `json:"name"`
+	Terms          []GlossaryTerm `json:"terms"`
+	DoNotTranslate []string       `json:"do_not_translate"`
+}
+
+func (g *Glossary) IsEmpty() bool {
+	return g == nil || (len(g.Terms) == 0 && len(g.DoNotTranslate) == 0)
+}
+
+var GlossaryPromptSection = `
+**Terminology (MUST follow)**:
+%s`
+
+var GlossaryRetryPrompt = `{{.PreviousPrompt}}
+
+[Previous Translation]
+{{.PreviousOutput}}
+
+The previous translation violated the terminology: {{.Violations}}
+Translate the target sentence again and follow the terminology strictly. Provide only the translation result:`
@@ -1,13 +1,23 @@
 package types
 
+// ChatCompleter 大模型接口，请求参数都由options指定
 type ChatCompleter interface {
-	ChatCompletion(query string) (string, error)
+	ChatCompletionWithOptions(query string, options ChatOptions) (string, error)
 }
 
 type Transcriber interface {
-	Transcription(audioFile, language, wordDir string) (*TranscriptionData, error)
+	Transcription(audioFile, language, wordDir string, options TranscriptionOptions) (*TranscriptionData, error)
 }
 
 type Ttser interface {
 	Text2Speech(text string, voice string, outputFile string) error
 }
+
+type Diarizer interface {
+	Diarization(audioFile, workDir string) ([]SpeakerSegment, error)
+}
+
+// Translator 机器翻译引擎，按顺序返回与texts一一对应的译文
+type Translator interface {
+	Translate(texts []string, sourceLang, targetLang string) ([]string, error)
+}
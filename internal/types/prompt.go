@@ -0,0 +1,86 @@
+package types
+
+import "text/template"
+
+// 提示词模板名称，模板目录中的<名称>.tmpl会覆盖同名的内置模板
+const (
+	PromptTranslate               = "translate"                  // 结合上下文逐句翻译
+	PromptGlossaryRetry           = "glossary_retry"             // 译文违反术语时重新翻译
+	PromptBatchTranslate          = "batch_translate"            // 按窗口批量翻译
+	PromptSplitLongSentence       = "split_long_sentence"        // 长句原文译文对齐拆分
+	PromptSplitOriginLongSentence = "split_origin_long_sentence" // 原文长句拆分
+	PromptSplitLongTextByMeaning  = "split_long_text_by_meaning" // 超长原文按语义拆分
+	PromptTranslateTitle          = "translate_title"            // 视频标题和描述翻译
+	PromptJsonRepair              = "json_repair"                // 修复不合格的json输出
+	PromptQualityReview           = "quality_review"             // 译文质量审查
+)
+
+// 提示词模板来源
+const (
+	PromptSourceBuiltin = "builtin"
+	PromptSourceFile    = "file"
+	PromptSourceTask    = "task"
+)
+
+var DefaultPromptTemplates = map[string]string{
+	PromptTranslate:               SplitTextWithContextPrompt,
+	PromptGlossaryRetry:           GlossaryRetryPrompt,
+	PromptBatchTranslate:          BatchTranslatePrompt,
+	PromptSplitLongSentence:       SplitLongSentencePrompt,
+	PromptSplitOriginLongSentence: SplitOriginLongSentencePrompt,
+	PromptSplitLongTextByMeaning:  SplitLongTextByMeaningPrompt,
+	PromptTranslateTitle:          TranslateVideoTitleAndDescriptionPrompt,
+	PromptJsonRepair:              JsonRepairPrompt,
+	PromptQualityReview:           QualityReviewPrompt,
+}
+
+// PromptData 提示词模板中可用的变量，每个模板只用到其中一部分
+type PromptData struct {
+	SourceLanguage       string // 源语言名称
+	TargetLanguage       string // 目标语言名称
+	Glossary             string // 格式化后的术语要求，没有术语时为空
+	StyleGuide           string // 翻译风格要求，没有时为空
+	MemoryReference      string // 翻译记忆中相似句子的译文，没有时为空
+	Text                 string // 需要处理的文本
+	TranslatedText       string // 已有的译文
+	PreviousSentences    string // 前文
+	NextSentences        string // 后文
+	Summary              string // 批量翻译的滚动摘要
+	PreviousTranslations string // 批量翻译时前一个窗口的译文
+	Sentences            string // 批量翻译的句子json
+	PreviousPrompt       string // 重试时上一次的提示词
+	PreviousOutput       string // 重试时上一次的输出
+	Violations           string // 违反的术语
+	Error                string // json校验错误
+	Schema               string // 要求的json schema
+}
+
+// StyleGuideSection 英文提示词中使用的风格要求段落，没有风格要求时为空
+func (d PromptData) StyleGuideSection() string {
+	if d.StyleGuide == "" {
+		return ""
+	}
+	return "\n**Style Guide (follow it unless it conflicts with the rules)**:\n" + d.StyleGuide + "\n"
+}
+
+// MemoryReferenceSection 英文提示词中使用的翻译记忆参考段落，没有相似句子时为空
+func (d PromptData) MemoryReferenceSection() string {
+	if d.MemoryReference == "" {
+		return ""
+	}
+	return "\n**Reference Translations (similar sentences translated before, reuse their wording where it fits, but translate the actual sentence)**:\n" + d.MemoryReference + "\n"
+}
+
+type PromptTemplate struct {
+	Name     string             `json:"name"`
+	Source   string             `json:"source"`
+	Version  string             `json:"version"` // 模板内容的sha256前12位
+	Text     string             `json:"text"`
+	Template *template.Template `json:"-"`
+}
+
+// PromptSet 任务使用的提示词模板和风格要求
+type PromptSet struct {
+	Templates  map[string]*PromptTemplate
+	StyleGuide string
+}
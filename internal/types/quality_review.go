@@ -0,0 +1,95 @@
+package types
+
+import "encoding/json"
+
+// 译文质量问题类型
+const (
+	QualityIssueEmpty          = "empty"          // 译文为空
+	QualityIssueUntranslated   = "untranslated"   // 译文与原文相同
+	QualityIssueWrongLanguage  = "wrong_language" // 译文不是目标语言
+	QualityIssueTruncated      = "truncated"      // 译文明显比原文短或被截断
+	QualityIssueMistranslation = "mistranslation" // 大模型判定的误译
+)
+
+// 单行的审查结果
+const (
+	QualityStatusOk         = "ok"
+	QualityStatusFixed      = "fixed"      // 重新翻译后通过规则检查和大模型复核
+	QualityStatusUnverified = "unverified" // 重新翻译后通过规则检查，但大模型复核失败，未能确认问题已解决
+	QualityStatusUnresolved = "unresolved" // 重新翻译后仍有问题
+)
+
+const (
+	SubtitleTaskQualityReviewYes uint8 = iota + 1
+	SubtitleTaskQualityReviewNo
+)
+
+// QualityReviewItem 一行译文的审查记录
+type QualityReviewItem struct {
+	SegmentId      int      `json:"segment_id"`
+	Index          int      `json:"index"` // 句子在音频片段中的序号
+	OriginText     string   `json:"origin_text"`
+	TranslatedText string   `json:"translated_text"` // 审查前的译文
+	Issues         []string `json:"issues,omitempty"`
+	Reason         string   `json:"reason,omitempty"` // 大模型给出的理由
+	FinalText      string   `json:"final_text"`
+	Status         string   `json:"status"`
+}
+
+type QualityReportSummary struct {
+	Total      int `json:"total"`
+	Ok         int `json:"ok"`
+	Fixed      int `json:"fixed"`
+	Unverified int `json:"unverified"`
+	Unresolved int `json:"unresolved"`
+}
+
+type QualityReport struct {
+	TargetLanguage string               `json:"target_language"`
+	Summary        QualityReportSummary `json:"summary"`
+	Lines          []*QualityReviewItem `json:"lines"`
+}
+
+var QualityReviewPrompt = `You are a senior subtitle translation reviewer.
+
+[TRANSLATION REVIEW TASK]
+**Objective**:
+Review the {{.TargetLanguage}} translation of every subtitle line in "Lines". The lines are consecutive subtitles of the same video.
+{{.StyleGuideSection}}
+**Report a line ONLY if it has one of these problems**:
+- untranslated: the translation is (partly) left in the source language
+- wrong_language: the translation is not in {{.TargetLanguage}}
+- truncated: part of the source meaning is missing from the translation
+- mistranslation: the translation changes the meaning of the source
+
+Do NOT report stylistic preferences, and do NOT report lines that are acceptable.
+
+[Lines]
+{{.Sentences}}
+
+**Output only a JSON object in the following format, without any explanation or markdown. Use an empty array when all lines are acceptable:**
+{"issues":[{"id":1,"issue":"mistranslation","reason":"short reason in English"}]}`
+
+var QualityReviewJsonSchema = &ChatJsonSchema{
+	Name: "quality_review",
+	Schema: json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"issues": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"id": {"type": "integer"},
+					"issue": {"type": "string"},
+					"reason": {"type": "string"}
+				},
+				"required": ["id", "issue", "reason"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["issues"],
+	"additionalProperties": false
+}`),
+}
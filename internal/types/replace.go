@@ -0,0 +1,16 @@
+package types
+
+// ReplaceRule 文字替换规则，翻译前作用于转录结果，并同样作用于译文
+type ReplaceRule struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Regex        bool   `json:"regex"`         // From为正则表达式，To中可用$1引用分组
+	IgnoreCase   bool   `json:"ignore_case"`   // 匹配时忽略大小写
+	PreserveCase bool   `json:"preserve_case"` // 忽略大小写匹配时，按被替换文字的大小写调整替换文字，如全大写或首字母大写
+	PartialMatch bool   `json:"partial_match"` // 也替换单词内部的匹配，默认只替换完整的词
+}
+
+// TextReplacer 编译好的文字替换规则，由util.WordReplacer实现
+type TextReplacer interface {
+	Replace(text string) string
+}
@@ -0,0 +1,119 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 正式程度
+const (
+	StyleFormalityFormal  = "formal"
+	StyleFormalityNeutral = "neutral"
+	StyleFormalityCasual  = "casual"
+)
+
+// 日语、韩语的敬语处理
+const (
+	StyleHonorificKeep   = "keep"   // 保持原文的敬语层级
+	StyleHonorificPolite = "polite" // 统一使用敬体
+	StyleHonorificPlain  = "plain"  // 统一使用简体
+)
+
+// 脏话处理
+const (
+	StyleProfanityKeep   = "keep"   // 如实翻译
+	StyleProfanitySoften = "soften" // 用温和的说法替代
+	StyleProfanityCensor = "censor" // 用*遮盖
+)
+
+// StyleProfile 翻译风格配置，字段为空表示不做要求
+type StyleProfile struct {
+	Name      string `json:"name"`
+	Formality string `json:"formality"` // formal, neutral, casual
+	Audience  string `json:"audience"`  // 目标观众，如 high school students
+	Domain    string `json:"domain"`    // 内容领域，如 gaming
+	Honorific string `json:"honorific"` // keep, polite, plain，仅翻译为日语和韩语时生效
+	Profanity string `json:"profanity"` // keep, soften, censor
+	Notes     string `json:"notes"`     // 其他风格要求
+}
+
+// 内置的风格配置，同名的已保存配置优先
+var BuiltinStyleProfiles = map[string]*StyleProfile{
+	"education": {
+		Name:      "education",
+		Formality: StyleFormalityNeutral,
+		Audience:  "students and learners",
+		Domain:    "education",
+		Honorific: StyleHonorificPolite,
+		Profanity: StyleProfanitySoften,
+		Notes:     "Keep technical terms precise and explanations clear.",
+	},
+	"gaming": {
+		Name:      "gaming",
+		Formality: StyleFormalityCasual,
+		Audience:  "gamers",
+		Domain:    "video games and live streaming",
+		Honorific: StyleHonorificPlain,
+		Profanity: StyleProfanityKeep,
+		Notes:     "Use the slang and official localized names common in the gaming community.",
+	},
+}
+
+var styleFormalityGuides = map[string]string{
+	StyleFormalityFormal:  "Use a formal, respectful register.",
+	StyleFormalityNeutral: "Use a neutral register, neither stiff nor overly casual.",
+	StyleFormalityCasual:  "Use a casual, conversational register, as people actually talk.",
+}
+
+var styleHonorificGuides = map[string]string{
+	StyleHonorificKeep:   "When translating into Japanese or Korean, mirror the politeness level of each speaker in the original.",
+	StyleHonorificPolite: "When translating into Japanese or Korean, use polite forms (です/ます, 해요체/합니다체).",
+	StyleHonorificPlain:  "When translating into Japanese or Korean, use plain forms (だ/である, 반말).",
+}
+
+var styleProfanityGuides = map[string]string{
+	StyleProfanityKeep:   "Translate profanity faithfully with equivalent strength.",
+	StyleProfanitySoften: "Replace profanity with milder expressions.",
+	StyleProfanityCensor: "Censor profanity by replacing the middle letters with *.",
+}
+
+// Validate 检查枚举字段的取值
+func (p *StyleProfile) Validate() error {
+	if _, ok := styleFormalityGuides[p.Formality]; p.Formality != "" && !ok {
+		return fmt.Errorf("不支持的formality: %s，可选值：formal,neutral,casual", p.Formality)
+	}
+	if _, ok := styleHonorificGuides[p.Honorific]; p.Honorific != "" && !ok {
+		return fmt.Errorf("不支持的honorific: %s，可选值：keep,polite,plain", p.Honorific)
+	}
+	if _, ok := styleProfanityGuides[p.Profanity]; p.Profanity != "" && !ok {
+		return fmt.Errorf("不支持的profanity: %s，可选值：keep,soften,censor", p.Profanity)
+	}
+	return nil
+}
+
+// StyleGuide 生成填入提示词的风格要求
+func (p *StyleProfile) StyleGuide() string {
+	if p == nil {
+		return ""
+	}
+	var lines []string
+	if guide, ok := styleFormalityGuides[p.Formality]; ok {
+		lines = append(lines, "- "+guide)
+	}
+	if p.Audience != "" {
+		lines = append(lines, "- Target audience: "+p.Audience+". Choose words they understand and relate to.")
+	}
+	if p.Domain != "" {
+		lines = append(lines, "- Domain: "+p.Domain+". Use the terminology and conventions of this domain.")
+	}
+	if guide, ok := styleHonorificGuides[p.Honorific]; ok {
+		lines = append(lines, "- "+guide)
+	}
+	if guide, ok := styleProfanityGuides[p.Profanity]; ok {
+		lines = append(lines, "- "+guide)
+	}
+	if notes := strings.TrimSpace(p.Notes); notes != "" {
+		lines = append(lines, "- "+notes)
+	}
+	return strings.Join(lines, "\n")
+}
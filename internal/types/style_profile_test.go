@@ -0,0 +1,57 @@
+package types
+
+import "testing"
+
+func TestStyleProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile StyleProfile
+		wantErr bool
+	}{
+		{"empty", StyleProfile{Name: "empty"}, false},
+		{"all fields", StyleProfile{Formality: StyleFormalityFormal, Honorific: StyleHonorificKeep, Profanity: StyleProfanityCensor}, false},
+		{"bad formality", StyleProfile{Formality: "rude"}, true},
+		{"bad honorific", StyleProfile{Honorific: "always"}, true},
+		{"bad profanity", StyleProfile{Profanity: "remove"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.profile.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	for name, profile := range BuiltinStyleProfiles {
+		if err := profile.Validate(); err != nil {
+			t.Errorf("builtin %s: %v", name, err)
+		}
+	}
+}
+
+func TestStyleProfileStyleGuide(t *testing.T) {
+	var nilProfile *StyleProfile
+	if got := nilProfile.StyleGuide(); got != "" {
+		t.Errorf("nil profile: got %q", got)
+	}
+	if got := (&StyleProfile{Name: "empty", Formality: "unknown"}).StyleGuide(); got != "" {
+		t.Errorf("empty profile: got %q", got)
+	}
+
+	profile := &StyleProfile{
+		Formality: StyleFormalityCasual,
+		Audience:  "gamers",
+		Domain:    "video games",
+		Honorific: StyleHonorificPlain,
+		Profanity: StyleProfanitySoften,
+		Notes:     "  Keep it short.  ",
+	}
+	want := "- " + styleFormalityGuides[StyleFormalityCasual] +
+		"\n- Target audience: gamers. Choose words they understand and relate to." +
+		"\n- Domain: video games. Use the terminology and conventions of this domain." +
+		"\n- " + styleHonorificGuides[StyleHonorificPlain] +
+		"\n- " + styleProfanityGuides[StyleProfanitySoften] +
+		"\n- Keep it short."
+	if got := profile.StyleGuide(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
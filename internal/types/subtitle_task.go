@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 // var SplitTextPrompt = `你是一个英语处理专家，擅长翻译成%s和处理英文文本，根据句意和标点对句子进行拆分。
 
 // - 不要漏掉原英文任何一个单词
@@ -112,26 +114,33 @@ var SplitTextPromptWithModalFilterJson = `你是一个语言处理专家，专
 `
 
 var TranslateVideoTitleAndDescriptionPrompt = `你是一个专业的翻译专家，请翻译下面给出的标题和描述信息（两者用####来分隔），要求如下：
- - 将内容翻译成 %s
+ - 将内容翻译成 {{.TargetLanguage}}
  - 翻译后的内容仍然用####来分隔标题和描述两部分
+{{- if .StyleGuide}}
+ - 翻译风格：{{.StyleGuide}}
+{{- end}}
  以下全部是源内容，请完整按要求翻译：
-%s
+{{.Text}}
 `
 
 var SplitLongSentencePrompt = `请将以下原文和译文分割成多个部分，确保每个部分都尽可能短：
-原文：%s
-译文：%s
+原文：{{.Text}}
+译文：{{.TranslatedText}}
 
 要求：
 1. 分割后的原文与原文不能有偏差 
 2. 分割后的每个翻译句都需要符合语法规范，可进行添加连词、去除助词等操作等保证每句读起来都是自然的
 3. 译文如果有遗漏，请在分割的同时补全
 4. 务必返回JSON格式，包含origin_part和translated_part数组，例如：
-{"align":[{"origin_part":"原文部分1","translated_part":"译文部分1"},{"origin_part":"原文部分2","translated_part":"译文部分2"}]}`
+{"align":[{"origin_part":"原文部分1","translated_part":"译文部分1"},{"origin_part":"原文部分2","translated_part":"译文部分2"}]}
+{{- if .StyleGuide}}
+5. 补全或调整译文时遵循以下翻译风格：
+{{.StyleGuide}}
+{{- end}}`
 
 var SplitOriginLongSentencePrompt = `Please split the following text into multiple parts, ensuring it's divided into at most 3 short sentences, preferably 2 parts,
 
-Original text: %s
+Original text: {{.Text}}
 
 Requirements:
 1. The split sentences must exactly match the original text, absolutely no changes to the original text are allowed
@@ -145,7 +154,7 @@ Requirements:
 
 var SplitLongTextByMeaningPrompt = `Please split the following long text into shorter sentences based on semantic meaning. Do not change, add, or remove any words from the original text.
 
-Original text: %s
+Original text: {{.Text}}
 
 Requirements:
 1. Split the text into as many shorter, meaningful sentences as possible while preserving ALL original words
@@ -205,7 +214,7 @@ var SplitTextWithContextPrompt = `You are a professional subtitle translation ex
 
 [STRICT TRANSLATION TASK]
 **Objective**: 
-Translate ONLY the "Target Sentence" below into %s.
+Translate ONLY the "Target Sentence" below into {{.TargetLanguage}}.
 Use "Previous Sentences" ONLY to understand the context of referents (e.g. pronouns or ellipses), not to infer meaning.
 
 **Critical Rules**:
@@ -214,19 +223,49 @@ Use "Previous Sentences" ONLY to understand the context of referents (e.g. prono
 3. If the sentence is fragmentary or dependent (e.g. starts with "that"), KEEP IT THAT WAY in translation
 4. Do NOT complete or rewrite the sentence for fluency
 5. IGNORE the "Next Sentences" completely
-
+{{.Glossary}}{{.StyleGuideSection}}{{.MemoryReferenceSection}}
 **Context**:
 [Previous Sentences]
-%s
+{{.PreviousSentences}}
 
 [Target Sentence]
-%s
+{{.Text}}
 
 [Next Sentences]
-%s
+{{.NextSentences}}
 
 **Your output must be literal, minimal, and on a single line. Provide only the translation result:**`
 
+const (
+	TranslateModeSentence = "sentence" // 逐句翻译，每句带前后3句上下文
+	TranslateModeBatch    = "batch"    // 按窗口批量翻译，带滚动摘要
+)
+
+var BatchTranslatePrompt = `You are a professional subtitle translation expert.
+
+[BATCH TRANSLATION TASK]
+**Objective**:
+Translate every sentence in "Sentences" into {{.TargetLanguage}}. The sentences are consecutive subtitles of the same video.
+{{.Glossary}}{{.StyleGuideSection}}{{.MemoryReferenceSection}}
+**Critical Rules**:
+1. Translate each sentence separately. Output EXACTLY one translation per input sentence, with the same id and in the same order
+2. Do NOT merge, split, skip or reorder sentences, even if a sentence is fragmentary
+3. Keep names and terminology consistent with "Story So Far" and "Previous Translations"
+4. Update "summary" to briefly describe the whole content so far (at most 100 words, in English), it will be used as context for the next batch
+
+**Context**:
+[Story So Far]
+{{.Summary}}
+
+[Previous Translations]
+{{.PreviousTranslations}}
+
+[Sentences]
+{{.Sentences}}
+
+**Output only a JSON object in the following format, without any explanation or markdown:**
+{"translations":[{"id":1,"text":"translation of sentence 1"}],"summary":"updated summary"}`
+
 type SmallAudio struct {
 	AudioFile         string
 	TranscriptionData *TranscriptionData
@@ -262,6 +301,21 @@ const (
 	SubtitleTaskTtsNo
 )
 
+const (
+	SubtitleTaskDiarizationYes uint8 = iota + 1
+	SubtitleTaskDiarizationNo
+)
+
+const (
+	SubtitleTaskGlossaryRetryYes uint8 = iota + 1
+	SubtitleTaskGlossaryRetryNo
+)
+
+const (
+	SubtitleTaskTranscriptTimestampsYes uint8 = iota + 1
+	SubtitleTaskTranscriptTimestampsNo
+)
+
 const (
 	SubtitleTaskTtsVoiceCodeLongyu uint8 = iota + 1
 	SubtitleTaskTtsVoiceCodeLongchen
@@ -273,6 +327,9 @@ const (
 	SubtitleTaskStatusFailed
 )
 
+// UploadDir 上传接口保存文件的目录
+const UploadDir = "./uploads"
+
 const (
 	SubtitleTaskAudioFileName                                    = "origin_audio.mp3"
 	SubtitleTaskVideoFileName                                    = "origin_video.mp4"
@@ -282,7 +339,6 @@ const (
 	SubtitleTaskSplitAudioWordsFileNamePattern                   = "split_audio_words_%d.txt"
 	SubtitleTaskSplitSrtNoTimestampFileNamePattern               = "srt_no_ts_%d.srt"
 	SubtitleTaskSrtNoTimestampFileName                           = "srt_no_ts.srt"
-	SubtitleTaskSplitBilingualSrtFileNamePattern                 = "split_bilingual_srt_%d.srt"
 	SubtitleTaskSplitShortOriginMixedSrtFileNamePattern          = "split_short_origin_mixed_srt_%d.srt" //长中文+短英文
 	SubtitleTaskSplitShortOriginSrtFileNamePattern               = "split_short_origin_srt_%d.srt"       //短英文
 	SubtitleTaskBilingualSrtFileName                             = "bilingual_srt.srt"
@@ -293,9 +349,21 @@ const (
 	SubtitleTaskTargetLanguageSrtFileName                        = "target_language_srt.srt"
 	SubtitleTaskTargetLanguageTextFileName                       = "target_language.txt"
 	SubtitleTaskStepParamGobPersistenceFileName                  = "step_param.gob"
+	SubtitleTaskStepParamPersistenceFileName                     = "step_param.json"
 	SubtitleTaskAudioTranscriptionDataPersistenceFileNamePattern = "audio_transcription_data_%d.json"
 	SubtitleTaskTranslationRawDataPersistenceFileNamePattern     = "audio_translation_raw_data_%d.json"
 	SubtitleTaskTranslationDataPersistenceFileNamePattern        = "translation_data_%d.json"
+	SubtitleTaskSplitSubtitleDataPersistenceFileNamePattern      = "split_subtitle_data_%d.json"
+	SubtitleTaskSubtitleDataFileName                             = "subtitle_data.json"
+	SubtitleTaskDiarizationFileName                              = "diarization.json"
+	SubtitleTaskConfidenceSidecarFileName                        = "subtitle_confidence.json"
+	SubtitleTaskReviewReportFileName                             = "review_report.txt"
+	SubtitleTaskGlossaryCheckFileNamePattern                     = "glossary_check_%d.txt"
+	SubtitleTaskPromptTemplatesFileName                          = "prompt_templates.json"
+	SubtitleTaskQualityReportFileName                            = "quality_report.json"
+	SubtitleTaskReadabilityReportFileName                        = "readability_report.json"
+	SubtitleTaskSpecReportFileName                               = "spec_report.json"
+	SubtitleTaskStyledAssFileName                                = "styled_subtitles.ass"
 	SubtitleTaskTransferredVerticalVideoFileName                 = "transferred_vertical_video.mp4"
 	SubtitleTaskHorizontalEmbedVideoFileName                     = "horizontal_embed.mp4"
 	SubtitleTaskVerticalEmbedVideoFileName                       = "vertical_embed.mp4"
@@ -326,13 +394,18 @@ type SubtitleTaskStepParam struct {
 	SubtitleResultType          SubtitleResultType
 	EnableModalFilter           bool
 	EnableTts                   bool
-	TtsVoiceCode                string // 人声语音编码
-	VoiceCloneAudioUrl          string // 音色克隆的源音频oss地址
-	ReplaceWordsMap             map[string]string
-	OriginLanguage              StandardLanguageCode // 视频源语言
-	TargetLanguage              StandardLanguageCode // 用户希望的目标翻译语言
-	UserUILanguage              StandardLanguageCode // 用户的使用语言
+	TtsVoiceCode                string                          // 人声语音编码
+	TtsVoiceCodes               map[StandardLanguageCode]string // 每种目标语言的人声语音编码
+	VoiceCloneAudioUrl          string                          // 音色克隆的源音频oss地址
+	ReplaceRules                []ReplaceRule                   // 用户的替换规则，读取保存的参数后据此重建替换器
+	WordReplacer                TextReplacer                    `json:"-"` // 一个词替换为一个词的规则，翻译前作用于转录文字和词级时间戳
+	PhraseReplacer              TextReplacer                    `json:"-"` // 其余替换规则，作用于送去翻译的句子和对齐时间戳后的原文
+	OriginLanguage              StandardLanguageCode            // 视频源语言
+	TargetLanguage              StandardLanguageCode            // 用户希望的目标翻译语言
+	TargetLanguages             []StandardLanguageCode          // 全部目标语言，第一个即TargetLanguage
+	UserUILanguage              StandardLanguageCode            // 用户的使用语言
 	BilingualSrtFilePath        string
+	SubtitleFilePath            string // 结构化字幕数据路径，包含词级时间戳、说话人和置信度，各步骤以此为准
 	ShortOriginMixedSrtFilePath string
 	SubtitleInfos               []SubtitleFileInfo
 	TtsSourceFilePath           string
@@ -341,8 +414,38 @@ type SubtitleTaskStepParam struct {
 	EmbedSubtitleVideoType      string // 合成字幕嵌入的视频类型 none不嵌入 horizontal横屏 vertical竖屏
 	VerticalVideoMajorTitle     string // 合成竖屏视频的主标题
 	VerticalVideoMinorTitle     string
-	MaxWordOneLine              int    // 字幕一行最多显示多少个字
-	VideoWithTtsFilePath        string // 替换源视频的音频为tts结果后的视频路径
+	MaxWordOneLine              int                // 字幕一行最多显示多少个字
+	VideoWithTtsFilePath        string             // 替换源视频的音频为tts结果后的视频路径
+	EnableDiarization           bool               // 是否进行说话人分离
+	SpeakerNameMap              map[string]string  // 说话人id -> 显示名称
+	SpeakerLabelMode            string             // 说话人标注方式 prefix文字前缀 style按说话人区分ass样式
+	Hotwords                    []string           // 转录热词，全局配置与任务参数合并后的结果
+	ConfidenceSidecarFilePath   string             // 字幕置信度json文件路径，未生成时为空
+	ReviewReportFilePath        string             // 低置信度审阅报告路径，未生成时为空
+	Glossary                    *Glossary          // 翻译术语表，由任务指定的术语表和任务内术语合并而成
+	EnableGlossaryRetry         bool               // 译文违反术语表时是否重新请求翻译
+	SentenceSegments            []*SentenceSegment // 每个音频片段的转录和分句结果，多目标语言时复用
+	Prompts                     *PromptSet         // 任务使用的提示词模板和风格要求
+	PromptTemplatesFilePath     string             // 任务使用的提示词模板及版本记录，未生成时为空
+	EnableQualityReview         bool               // 是否在翻译后审查译文质量并重新翻译有问题的句子
+	QualityReviewItems          []*QualityReviewItem
+	QualityReportFilePath       string    // 译文质量报告路径，未生成时为空
+	ReadabilityReportFilePath   string    // 字幕可读性报告路径，未启用可读性约束时为空
+	SubtitleFormats             []string  // 除srt外额外导出的字幕格式，如vtt、ttml、sbv、fcpxml
+	VttCueSettings              string    // webvtt字幕的cue设置，如line:85% align:center
+	AssStyle                    *AssStyle // 字幕样式，为空时压制视频使用默认样式
+	SubtitleSpec                string    // 检查字幕使用的规范名称，为空不检查
+	SpecReportFilePath          string    // 字幕规范检查报告路径，未检查时为空
+	TranscriptFormats           []string  // 导出的文稿格式，如md、docx、json
+	TranscriptParagraphGap      float64   // 文稿中停顿超过多少秒另起一段
+	TranscriptTimestamps        bool      // 文稿每段前是否标注开始时间
+}
+
+// 一个音频片段的转录和分句结果
+type SentenceSegment struct {
+	Offset    float64 // 片段在完整音频中的起始时间
+	Words     []Word
+	Sentences []string
 }
 
 type SrtSentence struct {
@@ -382,6 +485,10 @@ type SubtitleTask struct {
 	ProcessPct            uint8          `json:"process_percent" gorm:"column:process_percent"`               // 处理进度
 	Duration              uint32         `json:"duration" gorm:"column:duration"`                             // 视频时长
 	SrtNum                int            `json:"srt_num" gorm:"column:srt_num"`                               // 字幕数量
+	TmExactHitNum         int            `json:"tm_exact_hit_num" gorm:"column:tm_exact_hit_num"`             // 翻译记忆精确命中的句子数
+	TmFuzzyHitNum         int            `json:"tm_fuzzy_hit_num" gorm:"column:tm_fuzzy_hit_num"`             // 翻译记忆模糊命中的句子数
+	TmMissNum             int            `json:"tm_miss_num" gorm:"column:tm_miss_num"`                       // 未命中翻译记忆、调用大模型翻译的句子数
+	Warnings              []string       `json:"warnings" gorm:"-"`                                           // 不影响任务完成、需要提示用户的问题
 	SubtitleInfos         []SubtitleInfo `gorm:"foreignKey:TaskId;references:TaskId"`
 	Cover                 string         `json:"cover" gorm:"column:cover"`                             // 封面
 	SpeechDownloadUrl     string         `json:"speech_download_url" gorm:"column:speech_download_url"` // 语音文件下载地址
@@ -390,14 +497,26 @@ type SubtitleTask struct {
 }
 
 type Word struct {
-	Num   int
-	Text  string
-	Start float64
-	End   float64
+	Num        int
+	Text       string
+	Start      float64
+	End        float64
+	Speaker    string  // 说话人id，未开启说话人分离时为空
+	Confidence float64 // 识别置信度，范围0-1，转录服务不提供时为0
+}
+
+// 转录参数，由各转录服务按自己支持的方式使用
+type TranscriptionOptions struct {
+	Hotwords []string // 热词，如产品名、人名等容易识别错的专有名词
+}
+
+// HotwordsPrompt 将热词拼接成whisper系模型可用的提示词
+func (o TranscriptionOptions) HotwordsPrompt() string {
+	return strings.Join(o.Hotwords, ", ")
 }
 
 type TranscriptionData struct {
 	Language string
 	Text     string
 	Words    []Word
-}
\ No newline at end of file
+}
@@ -0,0 +1,49 @@
+package types
+
+import "encoding/xml"
+
+const (
+	TranslationMemoryMatchExact = "exact"
+	TranslationMemoryMatchFuzzy = "fuzzy"
+)
+
+// TranslationMemoryEntry 翻译记忆中的一条记录
+type TranslationMemoryEntry struct {
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	SourceLang string `json:"source_lang"`
+	TargetLang string `json:"target_lang"`
+	HitCount   int    `json:"hit_count"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// Tmx TMX 1.4 翻译记忆交换格式
+type Tmx struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  TmxHeader `xml:"header"`
+	Body    TmxBody   `xml:"body"`
+}
+
+type TmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTmf                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type TmxBody struct {
+	Tus []TmxTu `xml:"tu"`
+}
+
+type TmxTu struct {
+	Tuvs []TmxTuv `xml:"tuv"`
+}
+
+type TmxTuv struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Seg  string `xml:"seg"`
+}
@@ -10,6 +10,7 @@ type WhisperXOutput struct {
 			End         float64 `json:"end"`
 			Word        string  `json:"word"`
 			Probability float64 `json:"score"`
+			Speaker     string  `json:"speaker"`
 		} `json:"words"`
 		Text string `json:"text"`
 	} `json:"segments"`
@@ -91,7 +91,7 @@ type TaskResponse struct {
 	maxPollTime  time.Duration
 }
 
-func (c *AsrClient) Transcription(audioFile, language, workDir string) (*types.TranscriptionData, error) {
+func (c *AsrClient) Transcription(audioFile, language, workDir string, options types.TranscriptionOptions) (*types.TranscriptionData, error) {
 	const (
 		postRequestAction = "SubmitTask"
 		getRequestAction  = "GetTaskResult"
@@ -126,6 +126,12 @@ func (c *AsrClient) Transcription(audioFile, language, workDir string) (*types.T
 		"version":      "4.0",
 		"enable_words": fmt.Sprintf("%v", c.enableWords),
 	}
+	// 阿里云不支持直接传入热词，需要先在控制台创建热词表，再通过vocabulary_id使用
+	if vocabularyId := config.Conf.Transcribe.Aliyun.VocabularyId; vocabularyId != "" {
+		taskParams["vocabulary_id"] = vocabularyId
+	} else if len(options.Hotwords) > 0 {
+		log.GetLogger().Warn("阿里云转录未配置vocabulary_id，热词不会生效", zap.Strings("hotwords", options.Hotwords))
+	}
 
 	task, err := json.Marshal(taskParams)
 	if err != nil {
@@ -217,10 +223,11 @@ func (c *AsrClient) Transcription(audioFile, language, workDir string) (*types.T
 			if c.enableWords && getResult.Result.Words != nil {
 				for i, v := range getResult.Result.Words {
 					words = append(words, types.Word{
-						Num:   i,
-						Text:  strings.TrimSpace(v.Word), // 阿里云这边的word后面会有空格
-						Start: v.BeginTime / 1000,
-						End:   v.EndTime / 1000,
+						Num:        i,
+						Text:       strings.TrimSpace(v.Word), // 阿里云这边的word后面会有空格
+						Confidence: v.Confidence,
+						Start:      v.BeginTime / 1000,
+						End:        v.EndTime / 1000,
 					})
 				}
 			}
@@ -0,0 +1,93 @@
+package anthropic
+
+import (
+	"context"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+}
+
+type messagesResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ChatCompletionWithOptions 调用Anthropic Messages API，接口不支持指定输出schema，json格式由提示词约束
+func (c *Client) ChatCompletionWithOptions(query string, options types.ChatOptions) (string, error) {
+	model := options.Model
+	if model == "" {
+		model = c.model
+	}
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	req := messagesRequest{
+		Model:  model,
+		System: options.SystemPrompt,
+		Messages: []message{
+			{Role: "user", Content: query},
+		},
+		MaxTokens:   options.MaxTokens,
+		Temperature: options.Temperature,
+	}
+	var (
+		result  messagesResponse
+		errResp errorResponse
+	)
+	resp, err := c.restyClient.R().
+		SetContext(ctx).
+		SetHeader("x-api-key", c.apiKey).
+		SetHeader("anthropic-version", apiVersion).
+		SetBody(req).
+		SetResult(&result).
+		SetError(&errResp).
+		Post(c.baseUrl + "/v1/messages")
+	if err != nil {
+		log.GetLogger().Error("anthropic messages request failed", zap.Error(err))
+		return "", fmt.Errorf("anthropic messages request err: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("anthropic messages none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return "", fmt.Errorf("anthropic messages none-200 status code: %d, %s", resp.StatusCode(), errResp.Error.Message)
+	}
+
+	var builder strings.Builder
+	for _, content := range result.Content {
+		if content.Type == "text" {
+			builder.WriteString(content.Text)
+		}
+	}
+	if builder.Len() == 0 {
+		return "", fmt.Errorf("anthropic messages empty response, stop reason: %s", result.StopReason)
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,64 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestChatCompletionRequest(t *testing.T) {
+	log.Logger = zap.NewNop()
+	api := &testutil.FakeApi{
+		Path:     "/v1/messages",
+		Response: `{"content":[{"type":"text","text":"你好"},{"type":"tool_use","id":"1","name":"noop"},{"type":"text","text":"世界"}],"stop_reason":"end_turn"}`,
+	}
+	url := testutil.NewFakeServer(t, api)
+	options := types.ChatOptions{Model: "claude-override", SystemPrompt: "system", Temperature: 0.3, MaxTokens: 1024}
+	res, err := NewClient(url, "test-key", "test-model", "").ChatCompletionWithOptions("hello", options)
+	if err != nil || res != "你好世界" {
+		t.Fatalf("got (%q, %v), want only the text blocks joined", res, err)
+	}
+
+	r, body := api.LastRequest()
+	if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != apiVersion || r.Header.Get("Authorization") != "" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+	var req messagesRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("decode request err: %v", err)
+	}
+	// 系统提示词放在顶层的system字段，不作为消息发送
+	if req.Model != "claude-override" || req.System != "system" || len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content != "hello" {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.Temperature != 0.3 || req.MaxTokens != 1024 {
+		t.Errorf("unexpected sampling options %+v", req)
+	}
+}
+
+func TestChatCompletionErrors(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name    string
+		api     *testutil.FakeApi
+		wantErr string
+	}{
+		{"error body", &testutil.FakeApi{Status: http.StatusUnauthorized, Response: `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`}, "invalid x-api-key"},
+		{"stop reason", &testutil.FakeApi{Response: `{"content":[],"stop_reason":"max_tokens"}`}, "max_tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := testutil.NewFakeServer(t, tt.api)
+			_, err := NewClient(url, "test-key", "test-model", "").ChatCompletionWithOptions("hello", types.ChatOptions{MaxTokens: 1024})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
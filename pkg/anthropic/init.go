@@ -0,0 +1,38 @@
+package anthropic
+
+import (
+	"krillin-ai/config"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultBaseUrl = "https://api.anthropic.com"
+	apiVersion     = "2023-06-01"
+)
+
+type Client struct {
+	restyClient *resty.Client
+	baseUrl     string
+	apiKey      string
+	model       string
+}
+
+func NewClient(baseUrl, apiKey, model, proxyAddr string) *Client {
+	if baseUrl == "" {
+		baseUrl = defaultBaseUrl
+	}
+	restyClient := resty.New()
+	if proxyAddr != "" {
+		restyClient.SetTransport(&http.Transport{
+			Proxy: http.ProxyURL(config.Conf.App.ParsedProxy),
+		})
+	}
+	return &Client{
+		restyClient: restyClient,
+		baseUrl:     baseUrl,
+		apiKey:      apiKey,
+		model:       model,
+	}
+}
@@ -0,0 +1,85 @@
+package deepl
+
+import (
+	"fmt"
+	"krillin-ai/log"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type translateRequest struct {
+	Text       []string `json:"text"`
+	SourceLang string   `json:"source_lang,omitempty"`
+	TargetLang string   `json:"target_lang"`
+}
+
+type translateResponse struct {
+	Translations []struct {
+		DetectedSourceLanguage string `json:"detected_source_language"`
+		Text                   string `json:"text"`
+	} `json:"translations"`
+}
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// 目标语言中需要区分地区变体的语言
+var targetLangMap = map[string]string{
+	"zh_cn": "ZH-HANS",
+	"zh_tw": "ZH-HANT",
+	"en":    "EN-US",
+	"pt":    "PT-BR",
+}
+
+func sourceLangCode(lang string) string {
+	if strings.HasPrefix(lang, "zh") {
+		return "ZH"
+	}
+	return strings.ToUpper(lang)
+}
+
+func targetLangCode(lang string) string {
+	if code, ok := targetLangMap[lang]; ok {
+		return code
+	}
+	return strings.ToUpper(lang)
+}
+
+// Translate 调用DeepL翻译接口，源语言为空时由DeepL自动检测
+func (c *Client) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	req := translateRequest{
+		Text:       texts,
+		TargetLang: targetLangCode(targetLang),
+	}
+	if sourceLang != "" {
+		req.SourceLang = sourceLangCode(sourceLang)
+	}
+	var (
+		result  translateResponse
+		errResp errorResponse
+	)
+	resp, err := c.restyClient.R().
+		SetHeader("Authorization", "DeepL-Auth-Key "+c.apiKey).
+		SetBody(req).
+		SetResult(&result).
+		SetError(&errResp).
+		Post(c.baseUrl + "/v2/translate")
+	if err != nil {
+		log.GetLogger().Error("deepl translate request failed", zap.Error(err))
+		return nil, fmt.Errorf("deepl translate request err: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("deepl translate none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return nil, fmt.Errorf("deepl translate none-200 status code: %d, %s", resp.StatusCode(), errResp.Message)
+	}
+	if len(result.Translations) != len(texts) {
+		return nil, fmt.Errorf("deepl translate translation count mismatch, expect %d, got %d", len(texts), len(result.Translations))
+	}
+	translations := make([]string, len(texts))
+	for i, translation := range result.Translations {
+		translations[i] = translation.Text
+	}
+	return translations, nil
+}
@@ -0,0 +1,80 @@
+package deepl
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/log"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestTranslateLanguageCodes(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		sourceLang, targetLang string
+		wantSource, wantTarget string
+	}{
+		{"en", "zh_cn", "EN", "ZH-HANS"},
+		{"zh_tw", "en", "ZH", "EN-US"},
+		{"", "pt", "", "PT-BR"}, // 源语言为空时由DeepL自动检测
+		{"ja", "de", "JA", "DE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sourceLang+"->"+tt.targetLang, func(t *testing.T) {
+			api := &testutil.FakeApi{Path: "/v2/translate", Response: `{"translations":[{"detected_source_language":"EN","text":"你好"}]}`}
+			url := testutil.NewFakeServer(t, api)
+			res, err := NewClient(url, "test-key", "").Translate([]string{"hello"}, tt.sourceLang, tt.targetLang)
+			if err != nil || len(res) != 1 || res[0] != "你好" {
+				t.Fatalf("got (%v, %v)", res, err)
+			}
+
+			r, body := api.LastRequest()
+			if r.Header.Get("Authorization") != "DeepL-Auth-Key test-key" {
+				t.Errorf("unexpected headers %v", r.Header)
+			}
+			var req map[string]any
+			if err = json.Unmarshal(body, &req); err != nil {
+				t.Fatalf("decode request err: %v", err)
+			}
+			if source, ok := req["source_lang"]; (tt.wantSource == "" && ok) || (tt.wantSource != "" && source != tt.wantSource) {
+				t.Errorf("source_lang = %v, want %q", source, tt.wantSource)
+			}
+			if req["target_lang"] != tt.wantTarget {
+				t.Errorf("target_lang = %v, want %q", req["target_lang"], tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name    string
+		api     *testutil.FakeApi
+		wantErr string
+	}{
+		// DeepL用456表示额度用完
+		{"quota exceeded", &testutil.FakeApi{Status: 456, Response: `{"message":"Quota exceeded"}`}, "456, Quota exceeded"},
+		{"count mismatch", &testutil.FakeApi{Response: `{"translations":[{"text":"你好"}]}`}, "expect 2, got 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := testutil.NewFakeServer(t, tt.api)
+			_, err := NewClient(url, "test-key", "").Translate([]string{"hello", "world"}, "en", "zh_cn")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClientFreeEndpoint(t *testing.T) {
+	if c := NewClient("", "key:fx", ""); c.baseUrl != "https://api-free.deepl.com" {
+		t.Errorf("free key base url = %s", c.baseUrl)
+	}
+	if c := NewClient("", "key", ""); c.baseUrl != "https://api.deepl.com" {
+		t.Errorf("pro key base url = %s", c.baseUrl)
+	}
+}
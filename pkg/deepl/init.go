@@ -0,0 +1,37 @@
+package deepl
+
+import (
+	"krillin-ai/config"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type Client struct {
+	restyClient *resty.Client
+	baseUrl     string
+	apiKey      string
+}
+
+func NewClient(baseUrl, apiKey, proxyAddr string) *Client {
+	if baseUrl == "" {
+		// 免费版密钥以:fx结尾，需要使用单独的域名
+		if strings.HasSuffix(apiKey, ":fx") {
+			baseUrl = "https://api-free.deepl.com"
+		} else {
+			baseUrl = "https://api.deepl.com"
+		}
+	}
+	restyClient := resty.New()
+	if proxyAddr != "" {
+		restyClient.SetTransport(&http.Transport{
+			Proxy: http.ProxyURL(config.Conf.App.ParsedProxy),
+		})
+	}
+	return &Client{
+		restyClient: restyClient,
+		baseUrl:     baseUrl,
+		apiKey:      apiKey,
+	}
+}
@@ -0,0 +1,27 @@
+package diarizehttp
+
+import (
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+
+	"go.uber.org/zap"
+)
+
+func (c *Client) Diarization(audioFile, workDir string) ([]types.SpeakerSegment, error) {
+	var result types.DiarizationHttpOutput
+	req := c.restyClient.R().SetFile("file", audioFile).SetResult(&result)
+	if c.apiKey != "" {
+		req.SetAuthToken(c.apiKey)
+	}
+	resp, err := req.Post(c.baseUrl)
+	if err != nil {
+		log.GetLogger().Error("diarizehttp post error", zap.String("audio file", audioFile), zap.Error(err))
+		return nil, fmt.Errorf("diarizehttp post error: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("diarizehttp none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return nil, fmt.Errorf("diarizehttp none-200 status code: %d", resp.StatusCode())
+	}
+	return result.Segments, nil
+}
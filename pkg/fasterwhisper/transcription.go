@@ -14,7 +14,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func (c *FastwhisperProcessor) Transcription(audioFile, language, workDir string) (*types.TranscriptionData, error) {
+func (c *FastwhisperProcessor) Transcription(audioFile, language, workDir string, options types.TranscriptionOptions) (*types.TranscriptionData, error) {
 	cmdArgs := []string{
 		"--model_dir", "./models/",
 		"--model", c.Model,
@@ -29,6 +29,9 @@ func (c *FastwhisperProcessor) Transcription(audioFile, language, workDir string
 		cmdArgs = append(cmdArgs[:len(cmdArgs)-1], "--compute_type", "float16", cmdArgs[len(cmdArgs)-1])
 		log.GetLogger().Info("FastwhisperProcessor启用GPU加速", zap.String("model", c.Model))
 	}
+	if len(options.Hotwords) > 0 {
+		cmdArgs = append(cmdArgs[:len(cmdArgs)-1], "--hotwords", options.HotwordsPrompt(), cmdArgs[len(cmdArgs)-1])
+	}
 
 	cmd := exec.Command(storage.FasterwhisperPath, cmdArgs...)
 	log.GetLogger().Info("FastwhisperProcessor转录开始", zap.String("cmd", cmd.String()))
@@ -65,25 +68,28 @@ func (c *FastwhisperProcessor) Transcription(audioFile, language, workDir string
 				seperatedWords := strings.Split(word.Word, "—")
 				transcriptionData.Words = append(transcriptionData.Words, []types.Word{
 					{
-						Num:   num,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
-						Start: word.Start,
-						End:   mid,
+						Num:        num,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
+						Confidence: word.Probability,
+						Start:      word.Start,
+						End:        mid,
 					},
 					{
-						Num:   num + 1,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
-						Start: mid,
-						End:   word.End,
+						Num:        num + 1,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
+						Confidence: word.Probability,
+						Start:      mid,
+						End:        word.End,
 					},
 				}...)
 				num += 2
 			} else {
 				transcriptionData.Words = append(transcriptionData.Words, types.Word{
-					Num:   num,
-					Text:  util.CleanPunction(strings.TrimSpace(word.Word)),
-					Start: word.Start,
-					End:   word.End,
+					Num:        num,
+					Text:       util.CleanPunction(strings.TrimSpace(word.Word)),
+					Confidence: word.Probability,
+					Start:      word.Start,
+					End:        word.End,
 				})
 				num++
 			}
@@ -0,0 +1,114 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type part struct {
+	Text string `json:"text"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type generationConfig struct {
+	Temperature        float64         `json:"temperature"`
+	MaxOutputTokens    int             `json:"maxOutputTokens"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJsonSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+type generateContentRequest struct {
+	SystemInstruction *content         `json:"systemInstruction,omitempty"`
+	Contents          []content        `json:"contents"`
+	GenerationConfig  generationConfig `json:"generationConfig"`
+}
+
+type generateContentResponse struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// ChatCompletionWithOptions 调用Gemini generateContent接口
+func (c *Client) ChatCompletionWithOptions(query string, options types.ChatOptions) (string, error) {
+	model := options.Model
+	if model == "" {
+		model = c.model
+	}
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	req := generateContentRequest{
+		Contents: []content{
+			{Role: "user", Parts: []part{{Text: query}}},
+		},
+		GenerationConfig: generationConfig{
+			Temperature:     options.Temperature,
+			MaxOutputTokens: options.MaxTokens,
+		},
+	}
+	if options.SystemPrompt != "" {
+		req.SystemInstruction = &content{Parts: []part{{Text: options.SystemPrompt}}}
+	}
+	if options.JsonSchema != nil {
+		req.GenerationConfig.ResponseMimeType = "application/json"
+		req.GenerationConfig.ResponseJsonSchema = options.JsonSchema.Schema
+	}
+	var (
+		result  generateContentResponse
+		errResp errorResponse
+	)
+	resp, err := c.restyClient.R().
+		SetContext(ctx).
+		SetHeader("x-goog-api-key", c.apiKey).
+		SetBody(req).
+		SetResult(&result).
+		SetError(&errResp).
+		Post(fmt.Sprintf("%s/v1beta/models/%s:generateContent", c.baseUrl, url.PathEscape(model)))
+	if err != nil {
+		log.GetLogger().Error("gemini generateContent request failed", zap.Error(err))
+		return "", fmt.Errorf("gemini generateContent request err: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("gemini generateContent none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return "", fmt.Errorf("gemini generateContent none-200 status code: %d, %s", resp.StatusCode(), errResp.Error.Message)
+	}
+
+	if len(result.Candidates) == 0 {
+		return "", fmt.Errorf("gemini generateContent no candidates, block reason: %s", result.PromptFeedback.BlockReason)
+	}
+	var builder strings.Builder
+	for _, p := range result.Candidates[0].Content.Parts {
+		builder.WriteString(p.Text)
+	}
+	if builder.Len() == 0 {
+		return "", fmt.Errorf("gemini generateContent empty response, finish reason: %s", result.Candidates[0].FinishReason)
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const okResponse = `{"candidates":[{"content":{"role":"model","parts":[{"text":"你好"},{"text":"世界"}]},"finishReason":"STOP"}]}`
+
+func TestChatCompletionRequest(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name    string
+		options types.ChatOptions
+		path    string
+	}{
+		{"default model", types.ChatOptions{Temperature: 0.3, MaxTokens: 1024}, "/v1beta/models/test-model:generateContent"},
+		{"system prompt and schema", types.ChatOptions{Model: "gemini-2.0-flash", SystemPrompt: "system", MaxTokens: 1024, JsonSchema: types.SplitOriginLongSentenceJsonSchema}, "/v1beta/models/gemini-2.0-flash:generateContent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &testutil.FakeApi{Path: tt.path, Response: okResponse}
+			url := testutil.NewFakeServer(t, api)
+			res, err := NewClient(url, "test-key", "test-model", "").ChatCompletionWithOptions("hello", tt.options)
+			if err != nil || res != "你好世界" {
+				t.Fatalf("got (%q, %v), want the parts joined", res, err)
+			}
+
+			r, body := api.LastRequest()
+			if r.Header.Get("x-goog-api-key") != "test-key" || r.URL.Query().Get("key") != "" {
+				t.Errorf("api key should only be sent in the header: %s %v", r.URL, r.Header)
+			}
+			var req generateContentRequest
+			if err = json.Unmarshal(body, &req); err != nil {
+				t.Fatalf("decode request err: %v", err)
+			}
+			if len(req.Contents) != 1 || req.Contents[0].Role != "user" || req.Contents[0].Parts[0].Text != "hello" {
+				t.Errorf("unexpected contents %+v", req.Contents)
+			}
+			// 系统提示词使用systemInstruction，未设置时不发送
+			if tt.options.SystemPrompt == "" && req.SystemInstruction != nil {
+				t.Errorf("unexpected system instruction %+v", req.SystemInstruction)
+			}
+			if tt.options.SystemPrompt != "" && (req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "system") {
+				t.Errorf("missing system instruction %+v", req.SystemInstruction)
+			}
+			if req.GenerationConfig.Temperature != tt.options.Temperature || req.GenerationConfig.MaxOutputTokens != tt.options.MaxTokens {
+				t.Errorf("unexpected generation config %+v", req.GenerationConfig)
+			}
+			wantSchema := tt.options.JsonSchema != nil
+			if (req.GenerationConfig.ResponseMimeType == "application/json") != wantSchema || (len(req.GenerationConfig.ResponseJsonSchema) > 0) != wantSchema {
+				t.Errorf("unexpected response format %s %s", req.GenerationConfig.ResponseMimeType, req.GenerationConfig.ResponseJsonSchema)
+			}
+		})
+	}
+}
+
+func TestChatCompletionBlocked(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name     string
+		response string
+		wantErr  string
+	}{
+		{"prompt blocked", `{"candidates":[],"promptFeedback":{"blockReason":"SAFETY"}}`, "block reason: SAFETY"},
+		{"empty candidate", `{"candidates":[{"content":{"role":"model","parts":[]},"finishReason":"RECITATION"}]}`, "finish reason: RECITATION"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := testutil.NewFakeServer(t, &testutil.FakeApi{Response: tt.response})
+			_, err := NewClient(url, "test-key", "test-model", "").ChatCompletionWithOptions("hello", types.ChatOptions{MaxTokens: 1024})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
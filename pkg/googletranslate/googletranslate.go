@@ -0,0 +1,83 @@
+package googletranslate
+
+import (
+	"fmt"
+	"krillin-ai/log"
+
+	"go.uber.org/zap"
+)
+
+type translateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source,omitempty"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+}
+
+type translateResponse struct {
+	Data struct {
+		Translations []struct {
+			TranslatedText string `json:"translatedText"`
+		} `json:"translations"`
+	} `json:"data"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// 与系统语言代码不一致的部分
+var langMap = map[string]string{
+	"zh_cn": "zh-CN",
+	"zh_tw": "zh-TW",
+	"fil":   "tl",
+}
+
+func langCode(lang string) string {
+	if code, ok := langMap[lang]; ok {
+		return code
+	}
+	return lang
+}
+
+// Translate 调用Google Cloud Translation v2接口，源语言为空时自动检测
+func (c *Client) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	req := translateRequest{
+		Q:      texts,
+		Target: langCode(targetLang),
+		// 使用纯文本格式，避免返回HTML转义字符
+		Format: "text",
+	}
+	if sourceLang != "" {
+		req.Source = langCode(sourceLang)
+	}
+	var (
+		result  translateResponse
+		errResp errorResponse
+	)
+	resp, err := c.restyClient.R().
+		SetHeader("X-Goog-Api-Key", c.apiKey).
+		SetBody(req).
+		SetResult(&result).
+		SetError(&errResp).
+		Post(c.baseUrl + "/language/translate/v2")
+	if err != nil {
+		log.GetLogger().Error("google translate request failed", zap.Error(err))
+		return nil, fmt.Errorf("google translate request err: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("google translate none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return nil, fmt.Errorf("google translate none-200 status code: %d, %s", resp.StatusCode(), errResp.Error.Message)
+	}
+	if len(result.Data.Translations) != len(texts) {
+		return nil, fmt.Errorf("google translate translation count mismatch, expect %d, got %d", len(texts), len(result.Data.Translations))
+	}
+	translations := make([]string, len(texts))
+	for i, translation := range result.Data.Translations {
+		translations[i] = translation.TranslatedText
+	}
+	return translations, nil
+}
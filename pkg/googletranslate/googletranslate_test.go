@@ -0,0 +1,56 @@
+package googletranslate
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestTranslateRequest(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		sourceLang, targetLang string
+		wantSource, wantTarget string
+	}{
+		{"en", "zh_tw", "en", "zh-TW"},
+		{"fil", "zh_cn", "tl", "zh-CN"},
+		{"", "ja", "", "ja"}, // 源语言为空时不传，由接口自动检测
+	}
+	for _, tt := range tests {
+		t.Run(tt.sourceLang+"->"+tt.targetLang, func(t *testing.T) {
+			// 使用text格式，译文中的&等字符不会被转义
+			api := &testutil.FakeApi{Path: "/language/translate/v2", Response: `{"data":{"translations":[{"translatedText":"R&D"}]}}`}
+			url := testutil.NewFakeServer(t, api)
+			res, err := NewClient(url, "test-key", "").Translate([]string{"R&D"}, tt.sourceLang, tt.targetLang)
+			if err != nil || len(res) != 1 || res[0] != "R&D" {
+				t.Fatalf("got (%v, %v)", res, err)
+			}
+
+			r, body := api.LastRequest()
+			if r.Header.Get("X-Goog-Api-Key") != "test-key" || r.URL.Query().Get("key") != "" {
+				t.Errorf("api key should only be sent in the header: %s %v", r.URL, r.Header)
+			}
+			var req translateRequest
+			if err = json.Unmarshal(body, &req); err != nil {
+				t.Fatalf("decode request err: %v", err)
+			}
+			if req.Source != tt.wantSource || req.Target != tt.wantTarget || req.Format != "text" {
+				t.Errorf("unexpected request %+v", req)
+			}
+		})
+	}
+}
+
+func TestTranslateApiError(t *testing.T) {
+	log.Logger = zap.NewNop()
+	url := testutil.NewFakeServer(t, &testutil.FakeApi{Status: http.StatusBadRequest, Response: `{"error":{"code":400,"message":"API key not valid"}}`})
+	_, err := NewClient(url, "test-key", "").Translate([]string{"hello"}, "en", "zh_cn")
+	if err == nil || !strings.Contains(err.Error(), "API key not valid") {
+		t.Errorf("err = %v, want the google error message", err)
+	}
+}
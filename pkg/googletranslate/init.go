@@ -0,0 +1,33 @@
+package googletranslate
+
+import (
+	"krillin-ai/config"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const defaultBaseUrl = "https://translation.googleapis.com"
+
+type Client struct {
+	restyClient *resty.Client
+	baseUrl     string
+	apiKey      string
+}
+
+func NewClient(baseUrl, apiKey, proxyAddr string) *Client {
+	if baseUrl == "" {
+		baseUrl = defaultBaseUrl
+	}
+	restyClient := resty.New()
+	if proxyAddr != "" {
+		restyClient.SetTransport(&http.Transport{
+			Proxy: http.ProxyURL(config.Conf.App.ParsedProxy),
+		})
+	}
+	return &Client{
+		restyClient: restyClient,
+		baseUrl:     baseUrl,
+		apiKey:      apiKey,
+	}
+}
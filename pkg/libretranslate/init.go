@@ -0,0 +1,30 @@
+package libretranslate
+
+import (
+	"krillin-ai/config"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type Client struct {
+	restyClient *resty.Client
+	baseUrl     string
+	apiKey      string
+}
+
+// NewClient 自建服务不需要api key时apiKey留空即可
+func NewClient(baseUrl, apiKey, proxyAddr string) *Client {
+	restyClient := resty.New()
+	if proxyAddr != "" {
+		restyClient.SetTransport(&http.Transport{
+			Proxy: http.ProxyURL(config.Conf.App.ParsedProxy),
+		})
+	}
+	return &Client{
+		restyClient: restyClient,
+		baseUrl:     strings.TrimSuffix(baseUrl, "/"),
+		apiKey:      apiKey,
+	}
+}
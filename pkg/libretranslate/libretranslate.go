@@ -0,0 +1,73 @@
+package libretranslate
+
+import (
+	"fmt"
+	"krillin-ai/log"
+
+	"go.uber.org/zap"
+)
+
+type translateRequest struct {
+	Q      []string `json:"q"`
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Format string   `json:"format"`
+	ApiKey string   `json:"api_key,omitempty"`
+}
+
+type translateResponse struct {
+	TranslatedText []string `json:"translatedText"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// 与系统语言代码不一致的部分，繁体中文在LibreTranslate中为zt
+var langMap = map[string]string{
+	"zh_cn": "zh",
+	"zh_tw": "zt",
+	"fil":   "tl",
+}
+
+func langCode(lang string) string {
+	if code, ok := langMap[lang]; ok {
+		return code
+	}
+	return lang
+}
+
+// Translate 调用LibreTranslate翻译接口，源语言为空时自动检测
+func (c *Client) Translate(texts []string, sourceLang, targetLang string) ([]string, error) {
+	req := translateRequest{
+		Q:      texts,
+		Source: "auto",
+		Target: langCode(targetLang),
+		Format: "text",
+		ApiKey: c.apiKey,
+	}
+	if sourceLang != "" {
+		req.Source = langCode(sourceLang)
+	}
+	var (
+		result  translateResponse
+		errResp errorResponse
+	)
+	resp, err := c.restyClient.R().
+		SetBody(req).
+		SetResult(&result).
+		SetError(&errResp).
+		Post(c.baseUrl + "/translate")
+	if err != nil {
+		log.GetLogger().Error("libretranslate request failed", zap.Error(err))
+		return nil, fmt.Errorf("libretranslate request err: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("libretranslate none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return nil, fmt.Errorf("libretranslate none-200 status code: %d, %s", resp.StatusCode(), errResp.Error)
+	}
+	if len(result.TranslatedText) != len(texts) {
+		return nil, fmt.Errorf("libretranslate translation count mismatch, expect %d, got %d", len(texts), len(result.TranslatedText))
+	}
+	return result.TranslatedText, nil
+}
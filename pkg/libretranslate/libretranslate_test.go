@@ -0,0 +1,56 @@
+package libretranslate
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestTranslateRequest(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name                   string
+		apiKey                 string
+		sourceLang, targetLang string
+		wantSource, wantTarget string
+	}{
+		{"auto detect", "", "", "zh_cn", "auto", "zh"},
+		{"traditional chinese with key", "test-key", "en", "zh_tw", "en", "zt"},
+		{"filipino", "", "fil", "en", "tl", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &testutil.FakeApi{Path: "/translate", Response: `{"translatedText":["你好","世界"]}`}
+			url := testutil.NewFakeServer(t, api)
+			// 自建服务地址常带有末尾的斜杠
+			res, err := NewClient(url+"/", tt.apiKey, "").Translate([]string{"hello", "world"}, tt.sourceLang, tt.targetLang)
+			if err != nil || strings.Join(res, ",") != "你好,世界" {
+				t.Fatalf("got (%v, %v)", res, err)
+			}
+
+			_, body := api.LastRequest()
+			var req translateRequest
+			if err = json.Unmarshal(body, &req); err != nil {
+				t.Fatalf("decode request err: %v", err)
+			}
+			// 密钥放在请求体中，未配置时不发送
+			if req.Source != tt.wantSource || req.Target != tt.wantTarget || req.ApiKey != tt.apiKey || req.Format != "text" {
+				t.Errorf("unexpected request %+v", req)
+			}
+		})
+	}
+}
+
+func TestTranslateUnsupportedLanguage(t *testing.T) {
+	log.Logger = zap.NewNop()
+	url := testutil.NewFakeServer(t, &testutil.FakeApi{Status: http.StatusBadRequest, Response: `{"error":"zt is not supported"}`})
+	_, err := NewClient(url, "", "").Translate([]string{"hello"}, "en", "zh_tw")
+	if err == nil || !strings.Contains(err.Error(), "zt is not supported") {
+		t.Errorf("err = %v, want the libretranslate error message", err)
+	}
+}
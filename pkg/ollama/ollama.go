@@ -0,0 +1,90 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+
+	"go.uber.org/zap"
+)
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict"`
+}
+
+type chatRequest struct {
+	Model    string          `json:"model"`
+	Messages []message       `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  chatOptions     `json:"options"`
+}
+
+type chatResponse struct {
+	Message message `json:"message"`
+	Done    bool    `json:"done"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ChatCompletionWithOptions 调用Ollama原生的/api/chat接口，format字段传入schema进行结构化输出
+func (c *Client) ChatCompletionWithOptions(query string, options types.ChatOptions) (string, error) {
+	model := options.Model
+	if model == "" {
+		model = c.model
+	}
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	var messages []message
+	if options.SystemPrompt != "" {
+		messages = append(messages, message{Role: "system", Content: options.SystemPrompt})
+	}
+	messages = append(messages, message{Role: "user", Content: query})
+	req := chatRequest{
+		Model:    model,
+		Messages: messages,
+		Stream:   false,
+		Options: chatOptions{
+			Temperature: options.Temperature,
+			NumPredict:  options.MaxTokens,
+		},
+	}
+	if options.JsonSchema != nil {
+		req.Format = options.JsonSchema.Schema
+	}
+	var (
+		result  chatResponse
+		errResp errorResponse
+	)
+	request := c.restyClient.R().SetContext(ctx).SetBody(req).SetResult(&result).SetError(&errResp)
+	if c.apiKey != "" {
+		request.SetAuthToken(c.apiKey)
+	}
+	resp, err := request.Post(c.baseUrl + "/api/chat")
+	if err != nil {
+		log.GetLogger().Error("ollama chat request failed", zap.Error(err))
+		return "", fmt.Errorf("ollama chat request err: %w", err)
+	}
+	if resp.IsError() {
+		log.GetLogger().Error("ollama chat none-200 status code", zap.Int("status_code", resp.StatusCode()), zap.String("body", resp.String()))
+		return "", fmt.Errorf("ollama chat none-200 status code: %d, %s", resp.StatusCode(), errResp.Error)
+	}
+	if result.Message.Content == "" {
+		return "", fmt.Errorf("ollama chat empty response")
+	}
+	return result.Message.Content, nil
+}
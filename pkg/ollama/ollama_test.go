@@ -0,0 +1,73 @@
+package ollama
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestChatCompletionRequest(t *testing.T) {
+	log.Logger = zap.NewNop()
+	tests := []struct {
+		name         string
+		apiKey       string
+		options      types.ChatOptions
+		wantModel    string
+		wantMessages int
+	}{
+		{"local", "", types.ChatOptions{SystemPrompt: "system", Temperature: 0.3, MaxTokens: 1024}, "qwen2.5", 2},
+		{"model override with key and schema", "test-key", types.ChatOptions{Model: "llama3.1", MaxTokens: 1024, JsonSchema: types.SplitOriginLongSentenceJsonSchema}, "llama3.1", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &testutil.FakeApi{Path: "/api/chat", Response: `{"model":"qwen2.5","message":{"role":"assistant","content":"你好"},"done":true}`}
+			url := testutil.NewFakeServer(t, api)
+			res, err := NewClient(url, tt.apiKey, "qwen2.5", "").ChatCompletionWithOptions("hello", tt.options)
+			if err != nil || res != "你好" {
+				t.Fatalf("got (%q, %v)", res, err)
+			}
+
+			r, body := api.LastRequest()
+			// 本地服务不需要鉴权，配置了key时才带上
+			wantAuth := ""
+			if tt.apiKey != "" {
+				wantAuth = "Bearer " + tt.apiKey
+			}
+			if r.Header.Get("Authorization") != wantAuth {
+				t.Errorf("Authorization = %q, want %q", r.Header.Get("Authorization"), wantAuth)
+			}
+			// 原生接口默认流式返回，必须显式关闭
+			if !strings.Contains(string(body), `"stream":false`) {
+				t.Errorf("request should disable streaming: %s", body)
+			}
+			var req chatRequest
+			if err = json.Unmarshal(body, &req); err != nil {
+				t.Fatalf("decode request err: %v", err)
+			}
+			if req.Model != tt.wantModel || len(req.Messages) != tt.wantMessages || req.Messages[len(req.Messages)-1].Content != "hello" {
+				t.Errorf("unexpected request %+v", req)
+			}
+			if req.Options.Temperature != tt.options.Temperature || req.Options.NumPredict != tt.options.MaxTokens {
+				t.Errorf("unexpected options %+v", req.Options)
+			}
+			if (tt.options.JsonSchema != nil) != (len(req.Format) > 0) {
+				t.Errorf("unexpected format %s", req.Format)
+			}
+		})
+	}
+}
+
+func TestChatCompletionModelNotFound(t *testing.T) {
+	log.Logger = zap.NewNop()
+	url := testutil.NewFakeServer(t, &testutil.FakeApi{Status: http.StatusNotFound, Response: `{"error":"model \"qwen2.5\" not found, try pulling it first"}`})
+	_, err := NewClient(url, "", "qwen2.5", "").ChatCompletionWithOptions("hello", types.ChatOptions{MaxTokens: 1024})
+	if err == nil || !strings.Contains(err.Error(), "try pulling it first") {
+		t.Errorf("err = %v, want the ollama error message", err)
+	}
+}
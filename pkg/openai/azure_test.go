@@ -0,0 +1,56 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestAzureChatCompletionWithOptions(t *testing.T) {
+	log.Logger = zap.NewNop()
+	var stream string
+	for _, chunk := range []string{"你好", "世界"} {
+		stream += fmt.Sprintf("data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"%s\"}}]}\n\n", chunk)
+	}
+	stream += "data: [DONE]\n\n"
+	api := testutil.FakeApi{
+		Path:        "/openai/deployments/my-gpt-4o.deployment/chat/completions",
+		ContentType: "text/event-stream",
+		Response:    stream,
+	}
+	url := testutil.NewFakeServer(t, &api)
+
+	options := types.ChatOptions{Model: "my-gpt-4o.deployment", SystemPrompt: "system", Temperature: 0.3, MaxTokens: 1024}
+	res, err := NewAzureClient(url, "test-key", "2024-06-01", "").ChatCompletionWithOptions("hello", options)
+	if err != nil {
+		t.Fatalf("ChatCompletionWithOptions err: %v", err)
+	}
+	if res != "你好世界" {
+		t.Errorf("expected 你好世界, got %s", res)
+	}
+
+	r, body := api.LastRequest()
+	if r.URL.Query().Get("api-version") != "2024-06-01" || r.Header.Get("api-key") != "test-key" {
+		t.Errorf("unexpected request %s %v", r.URL, r.Header)
+	}
+	var req struct {
+		Stream      bool    `json:"stream"`
+		Temperature float32 `json:"temperature"`
+		MaxTokens   int     `json:"max_tokens"`
+		Messages    []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if err = json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("decode request err: %v", err)
+	}
+	if !req.Stream || req.Temperature != 0.3 || req.MaxTokens != 1024 || len(req.Messages) != 2 || req.Messages[0].Content != "system" || req.Messages[1].Content != "hello" {
+		t.Errorf("unexpected request %+v", req)
+	}
+}
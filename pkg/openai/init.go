@@ -24,6 +24,29 @@ func NewClient(baseUrl, apiKey, proxyAddr string) *Client {
 			Transport: transport,
 		}
 	}
+	cfg.HTTPClient = explicitTemperatureDoer{doer: cfg.HTTPClient}
+
+	client := openai.NewClientWithConfig(cfg)
+	return &Client{client: client}
+}
+
+// NewAzureClient 创建Azure OpenAI客户端，baseUrl为资源终结点，请求中的模型名即部署名称
+func NewAzureClient(baseUrl, apiKey, apiVersion, proxyAddr string) *Client {
+	cfg := openai.DefaultAzureConfig(apiKey, baseUrl)
+	cfg.APIVersion = apiVersion
+	cfg.AzureModelMapperFunc = func(model string) string {
+		return model
+	}
+
+	if proxyAddr != "" {
+		transport := &http.Transport{
+			Proxy: http.ProxyURL(config.Conf.App.ParsedProxy),
+		}
+		cfg.HTTPClient = &http.Client{
+			Transport: transport,
+		}
+	}
+	cfg.HTTPClient = explicitTemperatureDoer{doer: cfg.HTTPClient}
 
 	client := openai.NewClientWithConfig(cfg)
 	return &Client{client: client}
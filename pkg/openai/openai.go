@@ -8,34 +8,57 @@ import (
 	"go.uber.org/zap"
 	"io"
 	"krillin-ai/config"
+	"krillin-ai/internal/types"
 	"krillin-ai/log"
 	"net/http"
 	"os"
 	"strings"
 )
 
-func (c *Client) ChatCompletion(query string) (string, error) {
+func (c *Client) ChatCompletionWithOptions(query string, options types.ChatOptions) (string, error) {
 	var responseFormat *openai.ChatCompletionResponseFormat
+	if options.JsonSchema != nil {
+		responseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   options.JsonSchema.Name,
+				Schema: options.JsonSchema.Schema,
+				Strict: true,
+			},
+		}
+	}
+	model := options.Model
+	if model == "" {
+		model = config.Conf.Llm.Model
+	}
+	var messages []openai.ChatCompletionMessage
+	if options.SystemPrompt != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: options.SystemPrompt,
+		})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: query,
+	})
 
 	req := openai.ChatCompletionRequest{
-		Model: config.Conf.Llm.Model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are an assistant that helps with subtitle translation.",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: query,
-			},
-		},
-		Temperature:    0.9,
+		Model:          model,
+		Messages:       messages,
+		Temperature:    float32(options.Temperature), // 为0时由explicitTemperatureDoer补上，见temperature.go
 		Stream:         true,
-		MaxTokens:      8192,
+		MaxTokens:      options.MaxTokens,
 		ResponseFormat: responseFormat,
 	}
 
-	stream, err := c.client.CreateChatCompletionStream(context.Background(), req)
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
 		log.GetLogger().Error("openai create chat completion stream failed", zap.Error(err))
 		return "", err
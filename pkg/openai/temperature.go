@@ -0,0 +1,44 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// explicitTemperatureDoer go-openai的temperature字段带omitempty，温度为0时请求中不带这个字段，服务端会使用默认温度(通常为1)。
+// 这里在发送对话请求前补上"temperature":0，让配置的0真正生效
+type explicitTemperatureDoer struct {
+	doer openai.HTTPDoer
+}
+
+func (d explicitTemperatureDoer) Do(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return d.doer.Do(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("explicitTemperatureDoer read body err: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(body, &fields); err == nil {
+		if _, ok := fields["temperature"]; !ok {
+			fields["temperature"] = json.RawMessage("0")
+			if newBody, marshalErr := json.Marshal(fields); marshalErr == nil {
+				body = newBody
+			}
+		}
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return d.doer.Do(req)
+}
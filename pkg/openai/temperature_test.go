@@ -0,0 +1,37 @@
+package openai
+
+import (
+	"encoding/json"
+	"krillin-ai/internal/testutil"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestChatCompletionSendsZeroTemperature(t *testing.T) {
+	log.Logger = zap.NewNop()
+	for _, temperature := range []float64{0, 0.3} {
+		api := testutil.FakeApi{
+			Path:        "/v1/chat/completions",
+			ContentType: "text/event-stream",
+			Response:    "data: {\"id\":\"1\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n",
+		}
+		url := testutil.NewFakeServer(t, &api)
+		options := types.ChatOptions{Model: "gpt-4o-mini", Temperature: temperature, MaxTokens: 1024}
+		if _, err := NewClient(url+"/v1", "test-key", "").ChatCompletionWithOptions("hello", options); err != nil {
+			t.Fatalf("ChatCompletionWithOptions err: %v", err)
+		}
+
+		_, body := api.LastRequest()
+		var req map[string]json.RawMessage
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("decode request err: %v", err)
+		}
+		var got float64
+		if raw, ok := req["temperature"]; !ok || json.Unmarshal(raw, &got) != nil || float32(got) != float32(temperature) {
+			t.Errorf("temperature %v: got %s", temperature, req["temperature"])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package pyannote
+
+import (
+	"bufio"
+	"fmt"
+	"krillin-ai/internal/storage"
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+	"krillin-ai/pkg/util"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+func (c *PyannoteProcessor) Diarization(audioFile, workDir string) ([]types.SpeakerSegment, error) {
+	rttmFile := util.ChangeFileExtension(audioFile, ".rttm")
+	cmdArgs := []string{
+		"--model", c.Model,
+		"--hf_token", c.HfToken,
+		"--output", rttmFile,
+		audioFile,
+	}
+	cmd := exec.Command(storage.PyannotePath, cmdArgs...)
+	log.GetLogger().Info("PyannoteProcessor说话人分离开始", zap.String("audio file", audioFile))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.GetLogger().Error("PyannoteProcessor cmd 执行失败", zap.String("output", string(output)), zap.Error(err))
+		return nil, err
+	}
+
+	segments, err := parseRttm(rttmFile)
+	if err != nil {
+		log.GetLogger().Error("PyannoteProcessor 解析rttm文件失败", zap.Error(err))
+		return nil, err
+	}
+	log.GetLogger().Info("PyannoteProcessor说话人分离成功", zap.Int("segments", len(segments)))
+	return segments, nil
+}
+
+// parseRttm 解析rttm文件，每行格式：SPEAKER <file> <channel> <start> <duration> <NA> <NA> <speaker> <NA> <NA>
+func parseRttm(rttmFile string) ([]types.SpeakerSegment, error) {
+	file, err := os.Open(rttmFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var segments []types.SpeakerSegment
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] != "SPEAKER" {
+			continue
+		}
+		start, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parseRttm invalid start: %s", fields[3])
+		}
+		duration, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parseRttm invalid duration: %s", fields[4])
+		}
+		segments = append(segments, types.SpeakerSegment{
+			Speaker: fields[7],
+			Start:   start,
+			End:     start + duration,
+		})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
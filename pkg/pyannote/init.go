@@ -0,0 +1,13 @@
+package pyannote
+
+type PyannoteProcessor struct {
+	Model   string
+	HfToken string
+}
+
+func NewPyannoteProcessor(model, hfToken string) *PyannoteProcessor {
+	return &PyannoteProcessor{
+		Model:   model,
+		HfToken: hfToken,
+	}
+}
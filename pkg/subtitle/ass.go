@@ -0,0 +1,155 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认的ass头部，Major为字幕的上方文字，Minor为双语时的下方文字
+const defaultAssHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Major,Arial,52,&H00FFFFFF,&H000000FF,&H00000000,&H64000000,0,0,0,0,100,100,0,0,1,3,1,2,40,40,60,1
+Style: Minor,Arial,40,&H00FFFFFF,&H000000FF,&H00000000,&H64000000,0,0,0,0,100,100,0,0,1,3,1,2,40,40,60,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+var (
+	assMinorPattern    = regexp.MustCompile(`\\N\{\\rMinor[^}]*\}`) // 双语时下方文字的开头
+	assOverridePattern = regexp.MustCompile(`\{[^}]*\}`)
+	assTimePattern     = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})\.(\d{2})$`)
+	assLineReplacer    = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ")
+)
+
+// FormatAssTime 格式化为ass的时间，精度为10毫秒，如0:00:01.50
+func FormatAssTime(d time.Duration) string {
+	hours, minutes, seconds, millis := splitDuration(d)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", hours, minutes, seconds, millis/10)
+}
+
+func parseAssTime(value string) (time.Duration, error) {
+	match := assTimePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid ass time: %s", value)
+	}
+	centis, _ := strconv.Atoi(match[4])
+	return parseTimingGroups([]string{match[1], match[2], match[3], "0"}) + time.Duration(centis)*10*time.Millisecond, nil
+}
+
+func assText(text string) string {
+	return strings.ReplaceAll(text, "\n", `\N`)
+}
+
+// ReadAss 读取ass字幕的Dialogue，Name读为说话人，双语时以{\rMinor}区分上下两种语言
+func ReadAss(r io.Reader, options Options) (*Subtitle, error) {
+	sub := &Subtitle{}
+	var fields []string
+	inEvents := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(strings.TrimSpace(line), "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if key == "Format" {
+			fields = strings.Split(value, ",")
+			for i := range fields {
+				fields[i] = strings.TrimSpace(fields[i])
+			}
+			continue
+		}
+		if key != "Dialogue" || len(fields) == 0 {
+			continue
+		}
+		values := strings.SplitN(strings.TrimPrefix(value, " "), ",", len(fields))
+		if len(values) != len(fields) {
+			continue
+		}
+		cue := &Cue{Index: len(sub.Cues) + 1}
+		var text string
+		var err error
+		for i, field := range fields {
+			switch field {
+			case "Start":
+				cue.Start, err = parseAssTime(values[i])
+			case "End":
+				cue.End, err = parseAssTime(values[i])
+			case "Style":
+				cue.Style = values[i]
+			case "Name":
+				cue.Speaker = values[i]
+			case "Text":
+				text = values[i]
+			}
+			if err != nil {
+				return nil, fmt.Errorf("ReadAss parse dialogue err: %w", err)
+			}
+		}
+		var parts []string
+		if options.Layout.Bilingual() {
+			parts = assMinorPattern.Split(text, 2)
+		} else {
+			parts = []string{text}
+		}
+		for i, part := range parts {
+			parts[i] = assLineReplacer.Replace(assOverridePattern.ReplaceAllString(part, ""))
+		}
+		cue.SetParts(options.Layout, parts...)
+		sub.Cues = append(sub.Cues, cue)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ReadAss scan err: %w", err)
+	}
+	return sub, nil
+}
+
+// WriteAss 写入ass字幕，每条字幕一个Dialogue，样式为空时使用Major
+func WriteAss(w io.Writer, sub *Subtitle, options Options) error {
+	header := options.AssHeader
+	if header == "" {
+		header = defaultAssHeader
+	}
+	var builder strings.Builder
+	builder.WriteString(header)
+	for _, cue := range sub.Cues {
+		parts := cue.Parts(options.Layout)
+		if len(parts) == 0 {
+			continue
+		}
+		style := cue.Style
+		if style == "" {
+			style = "Major"
+		}
+		text := assText(parts[0])
+		if len(parts) > 1 {
+			text += `\N{\rMinor}` + assText(parts[1])
+		}
+		builder.WriteString(fmt.Sprintf("Dialogue: 0,%s,%s,%s,%s,0,0,0,,%s\n", FormatAssTime(cue.Start), FormatAssTime(cue.End), style, strings.ReplaceAll(cue.Speaker, ",", " "), text))
+	}
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("WriteAss write err: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const fcpxmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE fcpxml>
+<fcpxml version="1.9">
+  <resources>
+    <format id="r1" frameDuration="%s" width="%d" height="%d"/>
+  </resources>
+  <library>
+    <event name="Subtitles">
+      <project name="Subtitles">
+        <sequence format="r1" duration="%s" tcStart="0s" tcFormat="%s">
+          <spine>
+            <gap name="Gap" offset="0s" start="0s" duration="%s">
+`
+
+const fcpxmlFooter = `            </gap>
+          </spine>
+        </sequence>
+      </project>
+    </event>
+  </library>
+</fcpxml>
+`
+
+// WriteFcpxml 写入final cut pro的fcpxml，每条字幕为一个iTT字幕，时间对齐到视频帧
+func WriteFcpxml(w io.Writer, sub *Subtitle, options Options) error {
+	rate := options.FrameRate.valid()
+	width, height := options.videoSize()
+	language := options.Language
+	if language == "" {
+		language = "en"
+	}
+	tcFormat := "NDF"
+	if rate.DropFrame() {
+		tcFormat = "DF"
+	}
+	var total int64
+	for _, cue := range sub.Cues {
+		total = max(total, rate.Frames(cue.End))
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(fcpxmlHeader, rate.Seconds(1), width, height, rate.Seconds(total), tcFormat, rate.Seconds(total)))
+	index := 0
+	for _, cue := range sub.Cues {
+		text := cue.Text(options.Layout)
+		start, end := rate.Frames(cue.Start), rate.Frames(cue.End)
+		if text == "" || end <= start {
+			continue
+		}
+		index++
+		builder.WriteString(fmt.Sprintf("              <caption lane=\"1\" offset=\"%s\" name=\"%s\" start=\"%s\" duration=\"%s\" role=\"iTT?captionFormat=ITT.%s\">\n",
+			rate.Seconds(start), escapeXml(JoinLines(text)), rate.Seconds(start), rate.Seconds(end-start), escapeXml(language)))
+		builder.WriteString(fmt.Sprintf("                <text placement=\"bottom\"><text-style ref=\"ts%d\">%s</text-style></text>\n", index, escapeXml(text)))
+		builder.WriteString(fmt.Sprintf("                <text-style-def id=\"ts%d\"><text-style font=\".AppleSystemUIFont\" fontSize=\"13\" fontFace=\"Regular\" fontColor=\"1 1 1 1\" backgroundColor=\"0 0 0 1\"/></text-style-def>\n", index))
+		builder.WriteString("              </caption>\n")
+	}
+	builder.WriteString(fcpxmlFooter)
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
@@ -0,0 +1,368 @@
+package subtitle
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 换行位置的评分，越小越优先，与行长偏差的平方相加比较
+const (
+	breakAfterSentence     = -80   // 句末标点之后
+	breakAfterClause       = -40   // 逗号等句中标点之后
+	breakBeforeConjunction = -20   // 连词之前
+	breakBeforePreposition = -15   // 介词之前
+	breakAfterPreposition  = 60    // 介词留在行尾
+	breakAfterArticle      = 1000  // 冠词与名词分开
+	breakInNumber          = 200   // 数字与单位或数字的两部分分开
+	breakInThaiWord        = 40    // 泰文没有空格时只能在音节之间换行，可能拆开单词
+	breakAtThaiSpace       = -20   // 泰文的空格用于分隔短语
+	lineOverflow           = 10000 // 每超出一个字符
+)
+
+// 中日韩文字的行首禁则：这些字符不能出现在行首
+const cjkNoLeading = "ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶーヽヾゝゞ々〻"
+
+// lineBreakWords 一种语言中影响换行的功能词
+type lineBreakWords struct {
+	articles     map[string]bool // 冠词和限定词，不能留在行尾
+	conjunctions map[string]bool // 连词，优先在其前面换行
+	prepositions map[string]bool // 介词，优先在其前面换行
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+var languageLineBreakWords = map[string]*lineBreakWords{
+	"en": {
+		articles:     wordSet("a an the this these those my your his her its our their mr mrs ms dr"),
+		conjunctions: wordSet("and but or nor so yet because although though while when if unless that which who where whereas"),
+		prepositions: wordSet("to of in on at for with from by about into onto over under after before between through during without within across"),
+	},
+	"fr": {
+		articles:     wordSet("le la les l' un une des du au aux ce cet cette ces mon ma mes ton ta tes son sa ses notre votre leur leurs"),
+		conjunctions: wordSet("et mais ou donc car que qui quand si parce lorsque"),
+		prepositions: wordSet("à de en dans sur sous avec pour par sans chez vers entre"),
+	},
+	"de": {
+		articles:     wordSet("der die das den dem des ein eine einen einem einer eines kein keine mein meine dein deine sein seine ihr ihre"),
+		conjunctions: wordSet("und aber oder denn sondern weil dass wenn als ob obwohl"),
+		prepositions: wordSet("zu von mit in im auf an am aus bei nach für über unter vor ohne durch gegen"),
+	},
+	"es": {
+		articles:     wordSet("el la los las un una unos unas lo mi tu su mis tus sus"),
+		conjunctions: wordSet("y e pero o u porque que cuando si aunque mientras"),
+		prepositions: wordSet("a de en con por para sin sobre entre desde hasta hacia"),
+	},
+	"it": {
+		articles:     wordSet("il lo la i gli le un uno una"),
+		conjunctions: wordSet("e ed ma o perché che quando se mentre"),
+		prepositions: wordSet("a di da in con su per tra fra del della nel nella"),
+	},
+	"pt": {
+		articles:     wordSet("o a os as um uma uns umas"),
+		conjunctions: wordSet("e mas ou porque que quando se embora"),
+		prepositions: wordSet("de em com por para sem sobre entre até no na do da"),
+	},
+	"ru": {
+		conjunctions: wordSet("и а но или что чтобы когда если потому"),
+		prepositions: wordSet("в во на с со к ко у о об от до по за из без для под над при про через"),
+	},
+}
+
+// wordsForLanguage 按语言代码选择功能词，如zh_cn取zh，未指定语言时按英语处理
+func wordsForLanguage(language string) *lineBreakWords {
+	language = strings.ToLower(language)
+	if i := strings.IndexAny(language, "_-"); i >= 0 {
+		language = language[:i]
+	}
+	if language == "" {
+		language = "en"
+	}
+	return languageLineBreakWords[language]
+}
+
+type wrapToken struct {
+	text        string
+	spaceBefore bool
+	thai        bool
+}
+
+func isOpeningRune(r rune) bool {
+	return unicode.In(r, unicode.Ps, unicode.Pi)
+}
+
+// isNoLeadingRune 不能出现在行首、需要跟在前一个字符后面的字符
+func isNoLeadingRune(r rune) bool {
+	return (unicode.IsPunct(r) && !isOpeningRune(r)) || strings.ContainsRune(cjkNoLeading, r)
+}
+
+// isThaiFollowing 泰文中依附于前一个辅音的元音和声调符号
+func isThaiFollowing(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || r == 'ะ' || r == 'า' || r == 'ำ' || r == 'ๅ' || r == 'ๆ'
+}
+
+// isThaiLeading 泰文中写在辅音前面的元音，后面不能换行
+func isThaiLeading(r rune) bool {
+	return r >= 'เ' && r <= 'ไ'
+}
+
+// wrapTokens 把文字切分为换行时不可拆分的单位
+// 中日文逐字切分并遵守行首行尾禁则，泰文按音节切分，其余按空格切分
+func wrapTokens(text string) []wrapToken {
+	var (
+		tokens   []wrapToken
+		word     strings.Builder
+		wordThai bool
+		lastRune rune
+		prefix   string // 等待放到下一个单位前面的开括号和开引号
+		space    bool
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, wrapToken{text: prefix + word.String(), spaceBefore: space, thai: wordThai})
+			word.Reset()
+			wordThai = false
+			prefix = ""
+			space = false
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+			space = len(tokens) > 0 || prefix != ""
+		case isOpeningRune(r) && word.Len() == 0:
+			prefix += string(r)
+		case unicode.Is(unicode.Thai, r):
+			if !wordThai || (!isThaiFollowing(r) && !isThaiLeading(lastRune)) {
+				flush()
+				wordThai = true
+			}
+			word.WriteRune(r)
+		case isNoLeadingRune(r) && !space && prefix == "" && (word.Len() > 0 || len(tokens) > 0):
+			if word.Len() > 0 {
+				word.WriteRune(r)
+			} else {
+				tokens[len(tokens)-1].text += string(r)
+			}
+		case isCjkRune(r) && !unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens = append(tokens, wrapToken{text: prefix + string(r), spaceBefore: space})
+			prefix = ""
+			space = false
+		default:
+			if wordThai {
+				flush()
+			}
+			word.WriteRune(r)
+		}
+		lastRune = r
+	}
+	flush()
+	if prefix != "" {
+		if len(tokens) > 0 {
+			tokens[len(tokens)-1].text += prefix
+		} else {
+			tokens = append(tokens, wrapToken{text: prefix})
+		}
+	}
+	return tokens
+}
+
+// tokenWord 去掉前后标点并转为小写，用于查找功能词
+func tokenWord(text string) string {
+	return strings.ToLower(strings.TrimFunc(text, func(r rune) bool {
+		return unicode.IsPunct(r) && r != '\''
+	}))
+}
+
+// breakPenalty 在prev和next之间换行的评分
+func breakPenalty(prev, next wrapToken, words *lineBreakWords) float64 {
+	var penalty float64
+	core := strings.TrimRightFunc(prev.text, func(r rune) bool {
+		return unicode.In(r, unicode.Pe, unicode.Pf) || r == '"' || r == '\''
+	})
+	last, _ := utf8.DecodeLastRuneInString(core)
+	switch {
+	case strings.ContainsRune(".!?…。！？", last):
+		penalty += breakAfterSentence
+	case strings.ContainsRune(",;:—–，、；：", last):
+		penalty += breakAfterClause
+	}
+	if unicode.IsDigit(last) {
+		penalty += breakInNumber
+	}
+	first, _ := utf8.DecodeRuneInString(next.text)
+	if unicode.IsDigit(first) && !next.spaceBefore {
+		penalty += breakInNumber
+	}
+	if prev.thai && next.thai {
+		if next.spaceBefore {
+			penalty += breakAtThaiSpace
+		} else {
+			penalty += breakInThaiWord
+		}
+	}
+	if words != nil && next.spaceBefore {
+		prevWord, nextWord := tokenWord(prev.text), tokenWord(next.text)
+		if words.articles[prevWord] {
+			penalty += breakAfterArticle
+		} else if words.prepositions[prevWord] {
+			penalty += breakAfterPreposition
+		}
+		if words.conjunctions[nextWord] {
+			penalty += breakBeforeConjunction
+		} else if words.prepositions[nextWord] {
+			penalty += breakBeforePreposition
+		}
+	}
+	return penalty
+}
+
+// BreakLines 把文字重新分为每行不超过maxLineLength个字符的若干行
+// 使用尽量少的行数，在此基础上让各行长度均衡，并优先在标点后、连词和介词前换行，不拆开冠词与名词以及数字
+func BreakLines(text string, maxLineLength int, language string) []string {
+	text = JoinLines(text)
+	tokens := wrapTokens(text)
+	if maxLineLength <= 0 || len(tokens) <= 1 || utf8.RuneCountInString(text) <= maxLineLength {
+		return []string{text}
+	}
+
+	// offsets[i]为前i个单位连在一行的长度，加上第i个单位前的空格
+	words := wordsForLanguage(language)
+	offsets := make([]int, len(tokens)+1)
+	penalties := make([]float64, len(tokens))
+	for i, token := range tokens {
+		offsets[i+1] = offsets[i] + utf8.RuneCountInString(token.text)
+		if token.spaceBefore {
+			offsets[i+1]++
+		}
+		if i > 0 {
+			penalties[i] = breakPenalty(tokens[i-1], token, words)
+		}
+	}
+	width := func(from, to int) int {
+		w := offsets[to] - offsets[from]
+		if tokens[from].spaceBefore {
+			w--
+		}
+		return w
+	}
+	total := width(0, len(tokens))
+
+	var fallback []string
+	minLines := (total + maxLineLength - 1) / maxLineLength
+	for lineNum := minLines; lineNum <= min(minLines+2, len(tokens)); lineNum++ {
+		lines, overflow := breakIntoLines(tokens, lineNum, maxLineLength, float64(total)/float64(lineNum), width, penalties)
+		if !overflow {
+			return lines
+		}
+		if fallback == nil {
+			fallback = lines
+		}
+	}
+	return fallback
+}
+
+// breakIntoLines 把单位分为lineNum行，动态规划求各行长度偏差的平方与换行评分之和最小的分法
+func breakIntoLines(tokens []wrapToken, lineNum, maxLineLength int, target float64, width func(from, to int) int, penalties []float64) ([]string, bool) {
+	n := len(tokens)
+	costs := make([][]float64, lineNum+1)
+	from := make([][]int, lineNum+1)
+	for k := range costs {
+		costs[k] = make([]float64, n+1)
+		from[k] = make([]int, n+1)
+		for j := range costs[k] {
+			costs[k][j] = math.Inf(1)
+		}
+	}
+	costs[0][0] = 0
+	for k := 1; k <= lineNum; k++ {
+		for j := k; j <= n; j++ {
+			for i := k - 1; i < j; i++ {
+				if math.IsInf(costs[k-1][i], 1) {
+					continue
+				}
+				w := width(i, j)
+				cost := costs[k-1][i] + (float64(w)-target)*(float64(w)-target)
+				if w > maxLineLength {
+					cost += float64(w-maxLineLength) * lineOverflow
+				}
+				if i > 0 {
+					cost += penalties[i]
+				}
+				if cost < costs[k][j] {
+					costs[k][j] = cost
+					from[k][j] = i
+				}
+			}
+		}
+	}
+
+	lines := make([]string, lineNum)
+	overflow := false
+	end := n
+	for k := lineNum; k > 0; k-- {
+		start := from[k][end]
+		lines[k-1] = joinTokens(tokens[start:end])
+		if utf8.RuneCountInString(lines[k-1]) > maxLineLength {
+			overflow = true
+		}
+		end = start
+	}
+	return lines, overflow
+}
+
+// joinTokens 把单位拼回文字，保留单位之间原有的空格
+func joinTokens(tokens []wrapToken) string {
+	var builder strings.Builder
+	for i, token := range tokens {
+		if i > 0 && token.spaceBefore {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString(token.text)
+	}
+	return builder.String()
+}
+
+// splitText 在最接近ratio的位置把文字分为两段，优先在标点后、连词和介词前拆分，返回两段文字和前一段的长度占比，只有一个单位时无法拆分
+func splitText(text string, ratio float64, language string) (string, string, float64, bool) {
+	tokens := wrapTokens(JoinLines(text))
+	if len(tokens) < 2 {
+		return "", "", 0, false
+	}
+	words := wordsForLanguage(language)
+	offsets := make([]int, len(tokens)+1)
+	for i, token := range tokens {
+		offsets[i+1] = offsets[i] + utf8.RuneCountInString(token.text)
+	}
+	total := float64(offsets[len(tokens)])
+	best, bestCost := 1, math.Inf(1)
+	for i := 1; i < len(tokens); i++ {
+		deviation := float64(offsets[i]) - ratio*total
+		if cost := deviation*deviation + breakPenalty(tokens[i-1], tokens[i], words); cost < bestCost {
+			best, bestCost = i, cost
+		}
+	}
+	return joinTokens(tokens[:best]), joinTokens(tokens[best:]), float64(offsets[best]) / total, true
+}
+
+// WrapText 按每行最多字符数重新换行，原有的各行都不超长时保持不变
+func WrapText(text string, maxLineLength int, language string) string {
+	if maxLineLength <= 0 {
+		return text
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if utf8.RuneCountInString(line) > maxLineLength {
+			return strings.Join(BreakLines(text, maxLineLength, language), "\n")
+		}
+	}
+	return text
+}
@@ -0,0 +1,42 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBreakLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		language  string
+		want      []string
+	}{
+		{"fits", "Hello there", 42, "en", []string{"Hello there"}},
+		{"balanced", "I think we should go home now before it gets dark", 42, "en", []string{"I think we should go home", "now before it gets dark"}},
+		{"after punctuation", "Well, I never thought that this would happen to us", 42, "en", []string{"Well, I never thought", "that this would happen to us"}},
+		{"keep article", "She gave the book to the little girl yesterday", 30, "en", []string{"She gave the book", "to the little girl yesterday"}},
+		{"keep number", "The tower is about 300 meters tall they say", 26, "en", []string{"The tower is about", "300 meters tall they say"}},
+		{"cjk no leading punctuation", "他说：“我们明天再来。”然后就走了", 12, "zh_cn", []string{"他说：“我们明天再来。”", "然后就走了"}},
+		{"cjk keep number", "会议将在2024年12月举行，请准时参加", 12, "zh_cn", []string{"会议将在2024年12月", "举行，请准时参加"}},
+		{"japanese small kana", "ちょっと待ってくださいね", 6, "ja", []string{"ちょっと待っ", "てくださいね"}},
+		{"thai cluster", "ผมไม่เข้าใจ เธอพูดอะไร", 12, "th", []string{"ผมไม่เข้าใจ", "เธอพูดอะไร"}},
+	}
+	for _, tt := range tests {
+		if got := BreakLines(tt.text, tt.maxLength, tt.language); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: BreakLines(%q, %d) = %q, want %q", tt.name, tt.text, tt.maxLength, got, tt.want)
+		}
+	}
+}
+
+func TestWrapTokensThai(t *testing.T) {
+	// 前置元音、后置元音和声调符号不能与辅音分开
+	var got []string
+	for _, token := range wrapTokens("เข้าใจ") {
+		got = append(got, token.text)
+	}
+	if want := []string{"เข้า", "ใจ"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrapTokens = %q, want %q", got, want)
+	}
+}
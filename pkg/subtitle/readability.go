@@ -0,0 +1,463 @@
+package subtitle
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Readability 字幕可读性约束，为0的项不检查也不调整
+type Readability struct {
+	MaxCps           float64       // 每秒最多字符数，不含换行
+	MaxCpsCjk        float64       // 中日韩文字每秒最多字符数
+	MaxLineLength    int           // 每行最多字符数
+	MaxLineLengthCjk int           // 中日韩文字每行最多字符数
+	MaxLines         int           // 原文和译文各自最多行数
+	MinDuration      time.Duration // 字幕最短显示时长
+	MaxDuration      time.Duration // 字幕最长显示时长
+	MinGap           time.Duration // 相邻字幕的最小间隔
+	MaxMergeGap      time.Duration // 延长后仍然过短的字幕与间隔不超过该值的相邻字幕合并，为0时不合并
+	OriginLanguage   string        // 原文语言，如en，用于选择换行规则
+	TargetLanguage   string        // 译文语言
+}
+
+// 可读性约束的类型
+const (
+	RuleCps         = "cps"
+	RuleLineLength  = "line_length"
+	RuleLines       = "lines"
+	RuleMinDuration = "min_duration"
+	RuleMaxDuration = "max_duration"
+	RuleGap         = "gap"
+	RuleOverlap     = "overlap"
+)
+
+// 约束针对的文字
+const (
+	TrackOrigin = "origin"
+	TrackTarget = "target"
+)
+
+// Violation 违反的可读性约束，时间单位为秒
+type Violation struct {
+	Index  int     `json:"index"`
+	Rule   string  `json:"rule"`
+	Track  string  `json:"track,omitempty"` // 与文字无关的约束为空
+	Value  float64 `json:"value"`
+	Limit  float64 `json:"limit"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+func isCjkRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// IsCjkText 文字中的字母是否以中日韩文字为主
+func IsCjkText(text string) bool {
+	var cjk, letters int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if isCjkRune(r) {
+			cjk++
+		}
+	}
+	return cjk*2 > letters
+}
+
+// isCjkWide 中日文字和全角标点，相互之间不加空格，韩文以空格分词不在此列
+func isCjkWide(r rune) bool {
+	return (isCjkRune(r) && !unicode.Is(unicode.Hangul, r)) || (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// joinText 拼接两段文字，中日韩文字之间不加空格
+func joinText(a, b string) string {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == "" || b == "" {
+		return a + b
+	}
+	last, _ := utf8.DecodeLastRuneInString(a)
+	first, _ := utf8.DecodeRuneInString(b)
+	if isCjkWide(last) && isCjkWide(first) {
+		return a + b
+	}
+	return a + " " + b
+}
+
+// JoinLines 把多行文字合并为一行
+func JoinLines(text string) string {
+	var result string
+	for _, line := range strings.Split(text, "\n") {
+		result = joinText(result, line)
+	}
+	return result
+}
+
+func textLength(text string) int {
+	return utf8.RuneCountInString(strings.ReplaceAll(text, "\n", ""))
+}
+
+func (r Readability) maxCps(text string) float64 {
+	if IsCjkText(text) && r.MaxCpsCjk > 0 {
+		return r.MaxCpsCjk
+	}
+	return r.MaxCps
+}
+
+func (r Readability) maxLineLength(text string) int {
+	if IsCjkText(text) && r.MaxLineLengthCjk > 0 {
+		return r.MaxLineLengthCjk
+	}
+	return r.MaxLineLength
+}
+
+// requiredDuration 满足最短时长和每秒字符数所需的显示时长，不超过最长显示时长
+func (r Readability) requiredDuration(cue *Cue) time.Duration {
+	required := r.MinDuration
+	for _, text := range []string{cue.OriginText, cue.TranslatedText} {
+		cps := r.maxCps(text)
+		if cps <= 0 || text == "" {
+			continue
+		}
+		required = max(required, time.Duration(float64(textLength(text))/cps*float64(time.Second)))
+	}
+	if r.MaxDuration > 0 && required > r.MaxDuration {
+		required = r.MaxDuration
+	}
+	return required
+}
+
+func (r Readability) wrapCue(cue *Cue) {
+	cue.OriginText = r.Wrap(cue.OriginText, r.OriginLanguage)
+	cue.TranslatedText = r.Wrap(cue.TranslatedText, r.TargetLanguage)
+}
+
+// Wrap 按文字对应的每行最多字符数换行，原有的各行都不超长时保持不变
+func (r Readability) Wrap(text, language string) string {
+	return WrapText(text, r.maxLineLength(text), language)
+}
+
+func (r Readability) lineCount(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(strings.Split(text, "\n"))
+}
+
+// ApplyReadability 调整换行和时间使字幕尽量满足可读性约束，返回调整后仍然违反的约束
+func (s *Subtitle) ApplyReadability(r Readability) []Violation {
+	for _, cue := range s.Cues {
+		r.wrapCue(cue)
+	}
+	s.splitLongCues(r)
+	s.extendShortCues(r)
+	if r.MaxMergeGap > 0 {
+		s.mergeShortCues(r)
+		s.extendShortCues(r)
+	}
+	s.keepMinGap(r)
+	s.Renumber()
+	return s.CheckReadability(r)
+}
+
+// 拆分长字幕时选择拆分位置的评分，越小越优先，与拆分时间偏离中点的比例相加比较
+const (
+	splitAfterSentence = -0.2 // 句末标点之后
+	splitAfterClause   = -0.1 // 逗号等句中标点之后
+)
+
+// splitLongCues 处理超过最长显示时长的字幕：说话已经结束的截短到最长时长，仍在说话的在词或标点处拆为多条
+func (s *Subtitle) splitLongCues(r Readability) {
+	if r.MaxDuration <= 0 {
+		return
+	}
+	cues := make([]*Cue, 0, len(s.Cues))
+	for _, cue := range s.Cues {
+		cues = append(cues, r.splitLongCue(cue)...)
+	}
+	s.Cues = cues
+}
+
+// splitLongCue 把字幕拆分到不超过最长显示时长，只有一个词无法拆分时保持不变，由可读性报告指出
+func (r Readability) splitLongCue(cue *Cue) []*Cue {
+	if cue.End-cue.Start <= r.MaxDuration {
+		return []*Cue{cue}
+	}
+	// 最后一个词在最长时长内结束时，后面只是静音
+	if n := len(cue.Words); n > 0 && cue.Words[n-1].End <= cue.Start+r.MaxDuration {
+		cue.End = cue.Start + r.MaxDuration
+		return []*Cue{cue}
+	}
+	first, second, ok := r.splitCue(cue)
+	if !ok {
+		return []*Cue{cue}
+	}
+	return append(r.splitLongCue(first), r.splitLongCue(second)...)
+}
+
+// wordSplitIndex 选择在第几个词之前拆分，优先选靠近中点和标点之后的位置，返回0表示没有可拆分的位置
+func wordSplitIndex(cue *Cue) int {
+	duration := float64(cue.End - cue.Start)
+	middle := cue.Start + (cue.End-cue.Start)/2
+	best, bestCost := 0, math.Inf(1)
+	for i := 1; i < len(cue.Words); i++ {
+		at := cue.Words[i].Start
+		if at <= cue.Start || at >= cue.End {
+			continue
+		}
+		cost := math.Abs(float64(at-middle)) / duration
+		last, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(cue.Words[i-1].Text))
+		switch {
+		case strings.ContainsRune(".!?…。！？", last):
+			cost += splitAfterSentence
+		case strings.ContainsRune(",;:—–，、；：", last):
+			cost += splitAfterClause
+		}
+		if cost < bestCost {
+			best, bestCost = i, cost
+		}
+	}
+	return best
+}
+
+// splitCue 把字幕拆为前后两条。有词级时间戳时在词之间拆分时间，否则按拆分后原文的长度比例拆分时间
+func (r Readability) splitCue(cue *Cue) (*Cue, *Cue, bool) {
+	mainText, mainLanguage := cue.OriginText, r.OriginLanguage
+	otherText, otherLanguage := cue.TranslatedText, r.TargetLanguage
+	originFirst := true
+	if strings.TrimSpace(mainText) == "" {
+		mainText, mainLanguage, otherText, otherLanguage = otherText, otherLanguage, mainText, mainLanguage
+		originFirst = false
+	}
+	ratio := 0.5
+	wordIndex := wordSplitIndex(cue)
+	if wordIndex > 0 {
+		var before, total int
+		for i, word := range cue.Words {
+			length := utf8.RuneCountInString(word.Text)
+			total += length
+			if i < wordIndex {
+				before += length
+			}
+		}
+		if total > 0 {
+			ratio = float64(before) / float64(total)
+		}
+	}
+	mainFirst, mainSecond, ratio, ok := splitText(mainText, ratio, mainLanguage)
+	if !ok {
+		return nil, nil, false
+	}
+	splitAt := cue.Start + time.Duration(ratio*float64(cue.End-cue.Start))
+	if wordIndex > 0 {
+		splitAt = cue.Words[wordIndex].Start
+	}
+	// 译文只有一个单位时留在前一条
+	otherFirst, otherSecond, _, ok := splitText(otherText, ratio, otherLanguage)
+	if !ok {
+		otherFirst, otherSecond = otherText, ""
+	}
+
+	first, second := *cue, *cue
+	first.End, second.Start = splitAt, splitAt
+	if originFirst {
+		first.OriginText, first.TranslatedText = mainFirst, otherFirst
+		second.OriginText, second.TranslatedText = mainSecond, otherSecond
+	} else {
+		first.TranslatedText, first.OriginText = mainFirst, otherFirst
+		second.TranslatedText, second.OriginText = mainSecond, otherSecond
+	}
+	first.Words, second.Words = nil, nil
+	for _, word := range cue.Words {
+		if word.Start < splitAt {
+			first.Words = append(first.Words, word)
+		} else {
+			second.Words = append(second.Words, word)
+		}
+	}
+	first.LowConfidenceWords, second.LowConfidenceWords = nil, nil
+	for _, word := range cue.LowConfidenceWords {
+		if strings.Contains(first.OriginText, word) {
+			first.LowConfidenceWords = append(first.LowConfidenceWords, word)
+		} else {
+			second.LowConfidenceWords = append(second.LowConfidenceWords, word)
+		}
+	}
+	r.wrapCue(&first)
+	r.wrapCue(&second)
+	return &first, &second, true
+}
+
+// extendShortCues 显示时间不够的字幕先向后、再向前延伸到相邻的空隙中
+func (s *Subtitle) extendShortCues(r Readability) {
+	for i, cue := range s.Cues {
+		need := r.requiredDuration(cue) - (cue.End - cue.Start)
+		if need <= 0 {
+			continue
+		}
+		end := cue.End + need
+		if i < len(s.Cues)-1 {
+			end = min(end, s.Cues[i+1].Start-r.MinGap)
+		}
+		if end > cue.End {
+			need -= end - cue.End
+			cue.End = end
+		}
+		if need <= 0 {
+			continue
+		}
+		start := max(cue.Start-need, 0)
+		if i > 0 {
+			start = max(start, s.Cues[i-1].End+r.MinGap)
+		}
+		if start < cue.Start {
+			cue.Start = start
+		}
+	}
+}
+
+// mergeShortCues 仍然过短的字幕与间隔较小的相邻字幕合并，合并后不能超过最长时长和最多行数
+func (s *Subtitle) mergeShortCues(r Readability) {
+	for i := 0; i < len(s.Cues); {
+		cue := s.Cues[i]
+		if cue.End-cue.Start >= r.MinDuration {
+			i++
+			continue
+		}
+		target, bestGap := -1, r.MaxMergeGap+1
+		for _, j := range []int{i - 1, i + 1} {
+			if j < 0 || j >= len(s.Cues) {
+				continue
+			}
+			first, second := s.Cues[min(i, j)], s.Cues[max(i, j)]
+			gap := second.Start - first.End
+			if gap > r.MaxMergeGap || gap >= bestGap || first.Speaker != second.Speaker {
+				continue
+			}
+			if r.MaxDuration > 0 && second.End-first.Start > r.MaxDuration {
+				continue
+			}
+			if r.MaxLines > 0 {
+				merged := mergeCues(first, second)
+				r.wrapCue(merged)
+				if r.lineCount(merged.OriginText) > r.MaxLines || r.lineCount(merged.TranslatedText) > r.MaxLines {
+					continue
+				}
+			}
+			target, bestGap = j, gap
+		}
+		if target < 0 {
+			i++
+			continue
+		}
+		first := min(i, target)
+		merged := mergeCues(s.Cues[first], s.Cues[first+1])
+		r.wrapCue(merged)
+		s.Cues = append(s.Cues[:first], append([]*Cue{merged}, s.Cues[first+2:]...)...)
+		// 合并后的字幕可能仍然过短，从合并位置重新检查
+		i = first
+	}
+}
+
+// mergeCues 合并两条相邻字幕，返回新的字幕
+func mergeCues(first, second *Cue) *Cue {
+	merged := *first
+	merged.End = second.End
+	merged.OriginText = joinText(JoinLines(first.OriginText), JoinLines(second.OriginText))
+	merged.TranslatedText = joinText(JoinLines(first.TranslatedText), JoinLines(second.TranslatedText))
+	merged.Words = append(append([]Word{}, first.Words...), second.Words...)
+	merged.LowConfidenceWords = append(append([]string{}, first.LowConfidenceWords...), second.LowConfidenceWords...)
+	if len(merged.LowConfidenceWords) == 0 {
+		merged.LowConfidenceWords = nil
+	}
+	var (
+		total float64
+		count int
+	)
+	for _, word := range merged.Words {
+		if word.Confidence > 0 {
+			total += word.Confidence
+			count++
+		}
+	}
+	if count > 0 {
+		merged.Confidence = total / float64(count)
+	}
+	return &merged
+}
+
+// keepMinGap 缩短前一条字幕的结束时间，保证相邻字幕的间隔并去除重叠
+func (s *Subtitle) keepMinGap(r Readability) {
+	for i := 0; i < len(s.Cues)-1; i++ {
+		cue, next := s.Cues[i], s.Cues[i+1]
+		if next.Start-cue.End >= r.MinGap {
+			continue
+		}
+		end := next.Start - r.MinGap
+		if end-cue.Start < r.MinDuration || end <= cue.Start {
+			// 间隔和最短时长不能同时满足时只去除重叠
+			end = min(cue.End, next.Start)
+		}
+		if end > cue.Start {
+			cue.End = end
+		}
+	}
+}
+
+func roundSeconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*1000) / 1000
+}
+
+// CheckReadability 检查字幕违反的可读性约束，不修改字幕
+func (s *Subtitle) CheckReadability(r Readability) []Violation {
+	var violations []Violation
+	for i, cue := range s.Cues {
+		duration := cue.End - cue.Start
+		for _, track := range []struct {
+			name string
+			text string
+		}{
+			{TrackOrigin, cue.OriginText},
+			{TrackTarget, cue.TranslatedText},
+		} {
+			if track.text == "" {
+				continue
+			}
+			if cps := r.maxCps(track.text); cps > 0 && duration > 0 {
+				if value := float64(textLength(track.text)) / duration.Seconds(); value > cps {
+					violations = append(violations, Violation{Index: cue.Index, Rule: RuleCps, Track: track.name, Value: math.Round(value*100) / 100, Limit: cps})
+				}
+			}
+			if lines := r.lineCount(track.text); r.MaxLines > 0 && lines > r.MaxLines {
+				violations = append(violations, Violation{Index: cue.Index, Rule: RuleLines, Track: track.name, Value: float64(lines), Limit: float64(r.MaxLines)})
+			}
+			if maxLength := r.maxLineLength(track.text); maxLength > 0 {
+				longest := 0
+				for _, line := range strings.Split(track.text, "\n") {
+					longest = max(longest, utf8.RuneCountInString(line))
+				}
+				if longest > maxLength {
+					violations = append(violations, Violation{Index: cue.Index, Rule: RuleLineLength, Track: track.name, Value: float64(longest), Limit: float64(maxLength)})
+				}
+			}
+		}
+		if r.MinDuration > 0 && duration < r.MinDuration {
+			violations = append(violations, Violation{Index: cue.Index, Rule: RuleMinDuration, Value: roundSeconds(duration), Limit: roundSeconds(r.MinDuration)})
+		}
+		if r.MaxDuration > 0 && duration > r.MaxDuration {
+			violations = append(violations, Violation{Index: cue.Index, Rule: RuleMaxDuration, Value: roundSeconds(duration), Limit: roundSeconds(r.MaxDuration)})
+		}
+		if i < len(s.Cues)-1 {
+			if gap := s.Cues[i+1].Start - cue.End; gap < 0 {
+				violations = append(violations, Violation{Index: cue.Index, Rule: RuleOverlap, Value: roundSeconds(-gap)})
+			} else if gap < r.MinGap {
+				violations = append(violations, Violation{Index: cue.Index, Rule: RuleGap, Value: roundSeconds(gap), Limit: roundSeconds(r.MinGap)})
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,150 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func testReadability() Readability {
+	return Readability{
+		MaxCps:           17,
+		MaxCpsCjk:        9,
+		MaxLineLength:    20,
+		MaxLineLengthCjk: 10,
+		MaxLines:         2,
+		MinDuration:      time.Second,
+		MaxDuration:      7 * time.Second,
+		MinGap:           80 * time.Millisecond,
+		MaxMergeGap:      500 * time.Millisecond,
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"short line", 20, "short line"},
+		{"already\nwrapped", 10, "already\nwrapped"},
+		{"the quick brown fox jumps over the lazy dog", 20, "the quick\nbrown fox jumps\nover the lazy dog"},
+		{"the quick\nbrown fox jumps over", 12, "the quick\nbrown fox\njumps over"},
+		{"今天天气很好，我们一起去公园散步吧。", 10, "今天天气很好，我们\n一起去公园散步吧。"},
+		{"我在用iPhone拍照片", 8, "我在用\niPhone\n拍照片"},
+	}
+	for _, tt := range tests {
+		if got := WrapText(tt.text, tt.maxLength, ""); got != tt.want {
+			t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.maxLength, got, tt.want)
+		}
+	}
+}
+
+func TestApplyReadabilityTiming(t *testing.T) {
+	sub := &Subtitle{Cues: []*Cue{
+		// 过短，后面有空隙可以延长
+		{Start: 0, End: 300 * time.Millisecond, OriginText: "Hi", TranslatedText: "你好", Speaker: "A"},
+		// 过短，延长后仍然不够，不同说话人不合并，与下一条合并
+		{Start: 1100 * time.Millisecond, End: 1300 * time.Millisecond, OriginText: "So", TranslatedText: "所以"},
+		{Start: 1400 * time.Millisecond, End: 4 * time.Second, OriginText: "we go", TranslatedText: "我们走"},
+		// 过长，说话已经结束
+		{Start: 5 * time.Second, End: 20 * time.Second, OriginText: "long", TranslatedText: "长", Words: []Word{{Text: "long", Start: 5 * time.Second, End: 6 * time.Second}}},
+		// 与上一条间隔过小
+		{Start: 12 * time.Second, End: 14 * time.Second, OriginText: "next", TranslatedText: "下一句"},
+	}}
+	violations := sub.ApplyReadability(testReadability())
+	if len(violations) != 0 {
+		t.Errorf("unexpected violations %+v", violations)
+	}
+	if len(sub.Cues) != 4 {
+		t.Fatalf("expected 4 cues after merging, got %d", len(sub.Cues))
+	}
+	if sub.Cues[0].End != time.Second {
+		t.Errorf("expected first cue extended to 1s, got %s", sub.Cues[0].End)
+	}
+	if merged := sub.Cues[1]; merged.Start != 1080*time.Millisecond || merged.End != 4*time.Second || merged.OriginText != "So we go" || merged.TranslatedText != "所以我们走" {
+		t.Errorf("unexpected merged cue %+v", merged)
+	}
+	if sub.Cues[2].End != 11920*time.Millisecond {
+		t.Errorf("expected long cue trimmed to 7s and kept apart from the next one, got end %s", sub.Cues[2].End)
+	}
+	for i, cue := range sub.Cues {
+		if cue.Index != i+1 {
+			t.Errorf("expected index %d, got %d", i+1, cue.Index)
+		}
+	}
+}
+
+func TestSplitLongCues(t *testing.T) {
+	r := testReadability()
+	r.MaxLineLength = 0
+
+	// 有词级时间戳时在句末标点后的词之间拆分
+	words := []Word{
+		{Text: "We", Start: 0, End: 1 * time.Second},
+		{Text: "start", Start: 1 * time.Second, End: 2 * time.Second},
+		{Text: "here.", Start: 2 * time.Second, End: 3500 * time.Millisecond},
+		{Text: "Then", Start: 4 * time.Second, End: 5 * time.Second},
+		{Text: "we", Start: 5 * time.Second, End: 6 * time.Second},
+		{Text: "go", Start: 6 * time.Second, End: 8 * time.Second},
+		{Text: "on", Start: 8 * time.Second, End: 10 * time.Second},
+	}
+	sub := &Subtitle{Cues: []*Cue{{Start: 0, End: 10 * time.Second, OriginText: "We start here. Then we go on", TranslatedText: "我们从这里开始。然后继续", Words: words, LowConfidenceWords: []string{"go"}}}}
+	sub.splitLongCues(r)
+	if len(sub.Cues) != 2 {
+		t.Fatalf("expected 2 cues, got %d", len(sub.Cues))
+	}
+	first, second := sub.Cues[0], sub.Cues[1]
+	if first.End != 4*time.Second || second.Start != 4*time.Second || second.End != 10*time.Second {
+		t.Errorf("unexpected timing %s-%s, %s-%s", first.Start, first.End, second.Start, second.End)
+	}
+	if first.OriginText != "We start here." || second.OriginText != "Then we go on" || first.TranslatedText != "我们从这里开始。" || second.TranslatedText != "然后继续" {
+		t.Errorf("unexpected text %q/%q, %q/%q", first.OriginText, first.TranslatedText, second.OriginText, second.TranslatedText)
+	}
+	if len(first.Words) != 3 || len(second.Words) != 4 || first.LowConfidenceWords != nil || len(second.LowConfidenceWords) != 1 {
+		t.Errorf("unexpected words %+v, %+v", first, second)
+	}
+
+	// 没有词级时间戳时按文字长度拆分时间，拆分后仍然过长的继续拆分
+	sub = &Subtitle{Cues: []*Cue{{Start: 0, End: 20 * time.Second, OriginText: "one two three, four five six", TranslatedText: "一二三，四五六"}}}
+	sub.splitLongCues(r)
+	if len(sub.Cues) < 3 {
+		t.Fatalf("expected at least 3 cues, got %d", len(sub.Cues))
+	}
+	if sub.Cues[0].Start != 0 || sub.Cues[len(sub.Cues)-1].End != 20*time.Second {
+		t.Errorf("split cues must cover the original time, got %+v", sub.Cues)
+	}
+	for i, cue := range sub.Cues {
+		if cue.End-cue.Start > r.MaxDuration {
+			t.Errorf("cue %d still too long: %s", i, cue.End-cue.Start)
+		}
+		if i > 0 && cue.Start != sub.Cues[i-1].End {
+			t.Errorf("cue %d does not continue the previous one", i)
+		}
+	}
+
+	// 只有一个词时无法拆分，保持原样
+	sub = &Subtitle{Cues: []*Cue{{Start: 0, End: 10 * time.Second, OriginText: "Hmmmm"}}}
+	sub.splitLongCues(r)
+	if len(sub.Cues) != 1 || sub.Cues[0].End != 10*time.Second {
+		t.Errorf("expected the cue unchanged, got %+v", sub.Cues)
+	}
+}
+
+func TestCheckReadability(t *testing.T) {
+	sub := &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: 0, End: time.Second, OriginText: "far too many characters for one second", TranslatedText: "一\n二\n三"},
+		{Index: 2, Start: 1020 * time.Millisecond, End: 3 * time.Second, OriginText: "ok", TranslatedText: "好"},
+	}}
+	got := map[string]bool{}
+	for _, v := range sub.CheckReadability(testReadability()) {
+		got[v.Rule+"/"+v.Track] = true
+	}
+	for _, want := range []string{RuleCps + "/" + TrackOrigin, RuleLineLength + "/" + TrackOrigin, RuleLines + "/" + TrackTarget, RuleGap + "/"} {
+		if !got[want] {
+			t.Errorf("expected violation %s, got %v", want, got)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("unexpected violations %v", got)
+	}
+}
@@ -0,0 +1,36 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// sbv的小时不补零，如0:00:01.000
+func formatSbvTime(d time.Duration) string {
+	hours, minutes, seconds, millis := splitDuration(d)
+	return fmt.Sprintf("%d:%02d:%02d.%03d", hours, minutes, seconds, millis)
+}
+
+// WriteSbv 写入youtube的sbv字幕
+func WriteSbv(w io.Writer, sub *Subtitle, options Options) error {
+	var builder strings.Builder
+	for _, cue := range sub.Cues {
+		lines := cue.Lines(options.Layout)
+		if len(lines) == 0 {
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(formatSbvTime(cue.Start) + "," + formatSbvTime(cue.End) + "\n")
+		for _, line := range lines {
+			builder.WriteString(line + "\n")
+		}
+	}
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("WriteSbv write err: %w", err)
+	}
+	return nil
+}
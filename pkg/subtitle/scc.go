@@ -0,0 +1,222 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cea-608每行最多32个字符，最多4行
+const (
+	sccMaxLineLength = 32
+	sccMaxLines      = 4
+)
+
+// cea-608控制码，已加奇校验，按惯例重复发送两次
+const (
+	sccResumeCaptionLoading = "9420"
+	sccEraseNonDisplayed    = "94ae"
+	sccEndOfCaption         = "942f"
+	sccEraseDisplayed       = "942c"
+)
+
+// sccRowCodes 各行前导码(PAC)的第一个字节和第二个字节的基数
+var sccRowCodes = map[int][2]byte{
+	1: {0x11, 0x40}, 2: {0x11, 0x60}, 3: {0x12, 0x40}, 4: {0x12, 0x60},
+	5: {0x15, 0x40}, 6: {0x15, 0x60}, 7: {0x16, 0x40}, 8: {0x16, 0x60},
+	9: {0x17, 0x40}, 10: {0x17, 0x60}, 11: {0x10, 0x40}, 12: {0x13, 0x40},
+	13: {0x13, 0x60}, 14: {0x14, 0x40}, 15: {0x14, 0x60},
+}
+
+// sccBasicChars cea-608基本字符集中与ascii不同的字符
+var sccBasicChars = map[rune]byte{
+	'á': 0x2A, 'é': 0x5C, 'í': 0x5E, 'ó': 0x5F, 'ú': 0x60,
+	'ç': 0x7B, '÷': 0x7C, 'Ñ': 0x7D, 'ñ': 0x7E, '■': 0x7F,
+}
+
+// sccSpecialChars 特殊字符集，以0x11加该字符的下标表示
+const sccSpecialChars = "®°½¿™¢£♪à èâêîôû"
+
+// sccFoldChars 不能显示的常见标点替换为基本字符
+var sccFoldChars = map[rune]string{
+	'‘': "'", '’': "'", '“': "\"", '”': "\"", '–': "-", '—': "-", '…': "...",
+}
+
+// sccParity 设置奇校验位
+func sccParity(b byte) byte {
+	b &= 0x7F
+	bits := 0
+	for v := b; v > 0; v >>= 1 {
+		bits += int(v & 1)
+	}
+	if bits%2 == 0 {
+		b |= 0x80
+	}
+	return b
+}
+
+func sccWord(first, second byte) string {
+	return fmt.Sprintf("%02x%02x", sccParity(first), sccParity(second))
+}
+
+// DroppedTextError 写入scc时被跳过的文字：cea-608不能显示的字符，以及超过4行的字幕中多出的行，字幕文件仍然正常写入
+type DroppedTextError struct {
+	Cues          []int  // 有字符被跳过的字幕序号
+	Chars         []rune // 被跳过的字符，按出现顺序去重
+	TruncatedCues []int  // 超过4行而丢掉多出的行的字幕序号
+}
+
+func (e *DroppedTextError) Error() string {
+	var parts []string
+	if len(e.Cues) > 0 {
+		parts = append(parts, fmt.Sprintf("%d cues contain characters that cea-608 cannot display: %s", len(e.Cues), string(e.Chars)))
+	}
+	if len(e.TruncatedCues) > 0 {
+		parts = append(parts, fmt.Sprintf("%d cues exceed %d lines and were truncated", len(e.TruncatedCues), sccMaxLines))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// sccTextWords 把一行文字编码为cea-608的字，基本字符每字两个，特殊字符单独占一个字并重复发送，返回不能显示而跳过的字符
+func sccTextWords(line string) ([]string, []rune) {
+	var (
+		words   []string
+		pending []byte
+		dropped []rune
+	)
+	flush := func() {
+		if len(pending) == 1 {
+			pending = append(pending, 0x00)
+		}
+		if len(pending) == 2 {
+			words = append(words, sccWord(pending[0], pending[1]))
+		}
+		pending = nil
+	}
+	var writeRune func(r rune)
+	writeRune = func(r rune) {
+		if code, ok := sccBasicChars[r]; ok {
+			pending = append(pending, code)
+		} else if r >= 0x20 && r < 0x7F && !strings.ContainsRune("*\\^_`{|}~", r) {
+			pending = append(pending, byte(r))
+		} else if i := strings.IndexRune(sccSpecialChars, r); i >= 0 {
+			flush()
+			word := sccWord(0x11, byte(0x30+utf8.RuneCountInString(sccSpecialChars[:i])))
+			words = append(words, word, word)
+		} else if folded, ok := sccFoldChars[r]; ok {
+			for _, c := range folded {
+				writeRune(c)
+			}
+		} else if !unicode.IsSpace(r) {
+			dropped = append(dropped, r)
+		}
+		if len(pending) == 2 {
+			flush()
+		}
+	}
+	for _, r := range line {
+		writeRune(r)
+	}
+	flush()
+	return words, dropped
+}
+
+// sccPreamble 把一行放在第row行并居中的前导码，列数为4的倍数部分用缩进，余数用制表符偏移
+func sccPreamble(row, length int) []string {
+	column := max(0, (sccMaxLineLength-length)/2)
+	codes := sccRowCodes[row]
+	pac := sccWord(codes[0], codes[1]+0x10+byte(column/4*2))
+	words := []string{pac, pac}
+	if column%4 > 0 {
+		tab := sccWord(0x17, byte(0x20+column%4))
+		words = append(words, tab, tab)
+	}
+	return words
+}
+
+// sccLines 字幕在屏幕上显示的各行，超过32个字符时重新换行，最多4行，超出的行被丢掉时truncated为true
+func sccLines(text, language string) (lines []string, truncated bool) {
+	lines = strings.Split(WrapText(text, sccMaxLineLength, language), "\n")
+	if len(lines) > sccMaxLines {
+		lines = strings.Split(WrapText(JoinLines(text), sccMaxLineLength, language), "\n")
+	}
+	if len(lines) > sccMaxLines {
+		return lines[:sccMaxLines], true
+	}
+	return lines, false
+}
+
+// WriteScc 写入cea-608弹出式(pop-on)的scc字幕，帧率不是29.97或30时按29.97丢帧时间码计时
+// 每条字幕提前装载，使显示命令正好在开始时间发出，字幕之间有间隔时在结束时间清屏
+// 有不能显示的字符或超过4行时跳过这些文字，写入后返回*DroppedTextError
+func WriteScc(w io.Writer, sub *Subtitle, options Options) error {
+	rate := options.FrameRate.valid()
+	if rate.Timebase() != 30 {
+		rate = DefaultFrameRate
+	}
+	type sccCaption struct {
+		start, end int64
+		words      []string
+	}
+	var (
+		captions []sccCaption
+		dropped  DroppedTextError
+		seen     = make(map[rune]bool)
+	)
+	for _, cue := range sub.Cues {
+		text := cue.Text(options.Layout)
+		start, end := rate.Frames(cue.Start), rate.Frames(cue.End)
+		if text == "" || end <= start {
+			continue
+		}
+		words := []string{sccResumeCaptionLoading, sccResumeCaptionLoading, sccEraseNonDisplayed, sccEraseNonDisplayed}
+		// 显示在屏幕底部，最后一行为第15行
+		lines, truncated := sccLines(text, options.Language)
+		if truncated {
+			dropped.TruncatedCues = append(dropped.TruncatedCues, cue.Index)
+		}
+		cueDropped := false
+		for i, line := range lines {
+			textWords, droppedChars := sccTextWords(line)
+			words = append(words, sccPreamble(16-len(lines)+i, utf8.RuneCountInString(line))...)
+			words = append(words, textWords...)
+			for _, r := range droppedChars {
+				cueDropped = true
+				if !seen[r] {
+					seen[r] = true
+					dropped.Chars = append(dropped.Chars, r)
+				}
+			}
+		}
+		if cueDropped {
+			dropped.Cues = append(dropped.Cues, cue.Index)
+		}
+		words = append(words, sccEndOfCaption, sccEndOfCaption)
+		captions = append(captions, sccCaption{start: start, end: end, words: words})
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Scenarist_SCC V1.0\n")
+	var next int64
+	for i, caption := range captions {
+		// 每个字占一帧
+		load := max(caption.start-int64(len(caption.words)), next)
+		builder.WriteString(fmt.Sprintf("\n%s\t%s\n", rate.Timecode(load), strings.Join(caption.words, " ")))
+		next = load + int64(len(caption.words))
+		// 下一条字幕装载前有空档时清屏，否则由下一条字幕直接替换
+		if i == len(captions)-1 || captions[i+1].start-int64(len(captions[i+1].words)) > caption.end {
+			clear := max(caption.end, next)
+			builder.WriteString(fmt.Sprintf("\n%s\t%s %s\n", rate.Timecode(clear), sccEraseDisplayed, sccEraseDisplayed))
+			next = clear + 2
+		}
+	}
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("WriteScc write err: %w", err)
+	}
+	if len(dropped.Cues) > 0 || len(dropped.TruncatedCues) > 0 {
+		return &dropped
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RuleForbiddenChars 出现了规范不允许的字符
+const RuleForbiddenChars = "forbidden_chars"
+
+// Spec 字幕交付规范，检查单一语言的字幕，为0或空的项不检查，字幕重叠总是违规
+type Spec struct {
+	Name             string  `json:"name"`
+	Description      string  `json:"description,omitempty"`
+	MaxLineLength    int     `json:"max_line_length"`             // 每行最多字符数
+	MaxLines         int     `json:"max_lines"`                   // 最多行数
+	MaxCps           float64 `json:"max_cps"`                     // 每秒最多字符数
+	MinDuration      float64 `json:"min_duration"`                // 最短显示时长，单位秒
+	MaxDuration      float64 `json:"max_duration"`                // 最长显示时长，单位秒
+	MinGap           float64 `json:"min_gap"`                     // 相邻字幕的最小间隔，单位秒
+	ForbiddenPattern string  `json:"forbidden_pattern,omitempty"` // 不允许出现的字符，正则表达式，如[<>{}]
+}
+
+// ValidationReport 字幕按规范检查的结果
+type ValidationReport struct {
+	Spec       string         `json:"spec"`
+	Language   string         `json:"language,omitempty"`
+	Track      string         `json:"track"`
+	CueNum     int            `json:"cue_num"`
+	Passed     bool           `json:"passed"`
+	Summary    map[string]int `json:"summary"` // 约束类型 -> 违反次数
+	Violations []Violation    `json:"violations"`
+}
+
+// Check 检查规范本身是否合法
+func (s *Spec) Check() error {
+	if s.MaxLineLength < 0 || s.MaxLines < 0 || s.MaxCps < 0 || s.MinDuration < 0 || s.MaxDuration < 0 || s.MinGap < 0 {
+		return errors.New("字幕规范的各项限制不能小于0")
+	}
+	if s.MaxDuration > 0 && s.MaxDuration < s.MinDuration {
+		return errors.New("字幕规范的max_duration需大于等于min_duration")
+	}
+	if _, err := regexp.Compile(s.ForbiddenPattern); err != nil {
+		return fmt.Errorf("字幕规范的forbidden_pattern不是合法的正则表达式: %w", err)
+	}
+	return nil
+}
+
+func (s *Spec) readability() Readability {
+	return Readability{
+		MaxCps:        s.MaxCps,
+		MaxLineLength: s.MaxLineLength,
+		MaxLines:      s.MaxLines,
+		MinDuration:   secondsDuration(s.MinDuration),
+		MaxDuration:   secondsDuration(s.MaxDuration),
+		MinGap:        secondsDuration(s.MinGap),
+	}
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond
+}
+
+// Validate 按规范检查字幕中原文或译文的一轨，该轨文字为空的字幕视为不存在
+func (s *Subtitle) Validate(spec *Spec, track string) *ValidationReport {
+	view := &Subtitle{}
+	for _, cue := range s.Cues {
+		trackCue := *cue
+		if track == TrackTarget {
+			trackCue.OriginText = ""
+		} else {
+			trackCue.TranslatedText = ""
+		}
+		if strings.TrimSpace(trackCue.OriginText+trackCue.TranslatedText) == "" {
+			continue
+		}
+		view.Cues = append(view.Cues, &trackCue)
+	}
+
+	violations := view.CheckReadability(spec.readability())
+	if forbidden, err := regexp.Compile(spec.ForbiddenPattern); err == nil && spec.ForbiddenPattern != "" {
+		for _, cue := range view.Cues {
+			matches := forbidden.FindAllString(cue.OriginText+cue.TranslatedText, -1)
+			if len(matches) == 0 {
+				continue
+			}
+			var chars []string
+			seen := make(map[string]bool)
+			for _, match := range matches {
+				if !seen[match] {
+					seen[match] = true
+					chars = append(chars, match)
+				}
+			}
+			violations = append(violations, Violation{Index: cue.Index, Rule: RuleForbiddenChars, Track: track, Value: float64(len(matches)), Detail: strings.Join(chars, " ")})
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Index < violations[j].Index
+	})
+
+	report := &ValidationReport{
+		Spec:       spec.Name,
+		Track:      track,
+		CueNum:     len(view.Cues),
+		Passed:     len(violations) == 0,
+		Summary:    make(map[string]int),
+		Violations: violations,
+	}
+	if report.Violations == nil {
+		report.Violations = []Violation{}
+	}
+	for _, violation := range violations {
+		report.Summary[violation.Rule]++
+	}
+	return report
+}
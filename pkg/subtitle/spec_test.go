@@ -0,0 +1,56 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	spec := &Spec{
+		Name:             "test",
+		MaxLineLength:    16,
+		MaxLines:         2,
+		MaxCps:           9,
+		MinDuration:      0.833,
+		MaxDuration:      7,
+		MinGap:           0.083,
+		ForbiddenPattern: `[,!?]`,
+	}
+	if err := spec.Check(); err != nil {
+		t.Fatalf("unexpected spec error %v", err)
+	}
+	sub := &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: 0, End: 2 * time.Second, OriginText: "Hello, world!", TranslatedText: "你好,世界!"},
+		{Index: 2, Start: 1900 * time.Millisecond, End: 4 * time.Second, OriginText: "Overlapping cue", TranslatedText: "重叠的字幕"},
+		// 译文为空的字幕不参与译文检查
+		{Index: 3, Start: 5 * time.Second, End: 6 * time.Second, OriginText: "Background music playing"},
+	}}
+
+	report := sub.Validate(spec, TrackTarget)
+	if report.CueNum != 2 || report.Passed {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Summary[RuleForbiddenChars] != 1 || report.Summary[RuleOverlap] != 1 || len(report.Violations) != 2 {
+		t.Errorf("unexpected violations %+v", report.Violations)
+	}
+	if v := report.Violations[1]; v.Rule != RuleForbiddenChars || v.Detail != ", !" || v.Value != 2 {
+		t.Errorf("unexpected forbidden chars violation %+v", v)
+	}
+
+	report = sub.Validate(spec, TrackOrigin)
+	if report.CueNum != 3 || report.Summary[RuleCps] != 1 || report.Summary[RuleLineLength] != 1 || report.Summary[RuleForbiddenChars] != 1 {
+		t.Errorf("unexpected origin report %+v", report)
+	}
+}
+
+func TestSpecCheck(t *testing.T) {
+	for _, spec := range []*Spec{
+		{Name: "negative", MaxCps: -1},
+		{Name: "duration", MinDuration: 2, MaxDuration: 1},
+		{Name: "pattern", ForbiddenPattern: "["},
+	} {
+		if spec.Check() == nil {
+			t.Errorf("expected error for spec %s", spec.Name)
+		}
+	}
+}
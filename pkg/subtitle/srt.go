@@ -0,0 +1,136 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	srtTimingPattern   = regexp.MustCompile(`^\s*(\d{1,2}):(\d{2}):(\d{2})[,.](\d{3})\s*-->\s*(\d{1,2}):(\d{2}):(\d{2})[,.](\d{3})`)
+	speakerPrefixRegex = regexp.MustCompile(`^\[([^\]]+)\]\s*`)
+)
+
+// ParseSrtTiming 解析srt的时间轴行，如00:00:01,000 --> 00:00:02,500
+func ParseSrtTiming(line string) (time.Duration, time.Duration, error) {
+	match := srtTimingPattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid srt timing: %s", line)
+	}
+	return parseTimingGroups(match[1:5]), parseTimingGroups(match[5:9]), nil
+}
+
+func parseTimingGroups(groups []string) time.Duration {
+	hours, _ := strconv.Atoi(groups[0])
+	minutes, _ := strconv.Atoi(groups[1])
+	seconds, _ := strconv.Atoi(groups[2])
+	millis, _ := strconv.Atoi(groups[3])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond
+}
+
+// FormatSrtTime 格式化为srt的时间，如00:00:01,000
+func FormatSrtTime(d time.Duration) string {
+	hours, minutes, seconds, millis := splitDuration(d)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", hours, minutes, seconds, millis)
+}
+
+// splitSpeaker 拆出第一行的[说话人]前缀，双语时下方语言第一行的同名前缀一并去掉
+func splitSpeaker(cue *Cue, layout Layout) {
+	parts := cue.Parts(layout)
+	if len(parts) == 0 {
+		return
+	}
+	match := speakerPrefixRegex.FindStringSubmatch(parts[0])
+	if match == nil {
+		return
+	}
+	cue.Speaker = match[1]
+	for i, part := range parts {
+		if m := speakerPrefixRegex.FindStringSubmatch(part); m != nil && m[1] == cue.Speaker {
+			parts[i] = part[len(m[0]):]
+		}
+	}
+	cue.SetParts(layout, parts...)
+}
+
+// addSpeaker 在每种语言的第一行加上[说话人]前缀
+func addSpeaker(parts []string, speaker string) []string {
+	if speaker == "" {
+		return parts
+	}
+	result := make([]string, len(parts))
+	for i, part := range parts {
+		result[i] = fmt.Sprintf("[%s] %s", speaker, part)
+	}
+	return result
+}
+
+// ReadSrt 读取单语srt字幕，跳过没有时间轴的块
+func ReadSrt(r io.Reader, options Options) (*Subtitle, error) {
+	if options.Layout.Bilingual() {
+		return nil, fmt.Errorf("ReadSrt err: %w", ErrPlainTextBilingual)
+	}
+	sub := &Subtitle{}
+	var cue *Cue
+	var lines []string
+	flush := func() {
+		if cue != nil {
+			cue.SetParts(options.Layout, strings.Join(lines, "\n"))
+			if options.SpeakerPrefix {
+				splitSpeaker(cue, options.Layout)
+			}
+		}
+		cue, lines = nil, nil
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if start, end, err := ParseSrtTiming(line); err == nil {
+			flush()
+			cue = &Cue{Index: len(sub.Cues) + 1, Start: start, End: end}
+			sub.Cues = append(sub.Cues, cue)
+			continue
+		}
+		// 时间轴之前的编号行
+		if cue == nil {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ReadSrt scan err: %w", err)
+	}
+	return sub, nil
+}
+
+// WriteSrt 写入srt字幕，没有文字的字幕跳过
+func WriteSrt(w io.Writer, sub *Subtitle, options Options) error {
+	var builder strings.Builder
+	index := 0
+	for _, cue := range sub.Cues {
+		parts := cue.Parts(options.Layout)
+		if len(parts) == 0 {
+			continue
+		}
+		if options.SpeakerPrefix {
+			parts = addSpeaker(parts, cue.Speaker)
+		}
+		index++
+		builder.WriteString(fmt.Sprintf("%d\n%s --> %s\n", index, FormatSrtTime(cue.Start), FormatSrtTime(cue.End)))
+		builder.WriteString(strings.Join(parts, "\n") + "\n\n")
+	}
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("WriteSrt write err: %w", err)
+	}
+	return nil
+}
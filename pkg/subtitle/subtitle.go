@@ -0,0 +1,283 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 字幕文件格式
+const (
+	FormatSrt  = "srt"
+	FormatVtt  = "vtt"
+	FormatAss  = "ass"
+	FormatTtml = "ttml"
+	FormatDfxp = "dfxp" // 与ttml内容相同，部分广电系统只认dfxp扩展名
+	FormatSbv  = "sbv"
+	// 视频剪辑软件导入的格式，按视频帧率计时
+	FormatFcpxml = "fcpxml" // final cut pro的字幕
+	FormatXmeml  = "xmeml"  // premiere pro可导入的final cut pro 7 xml，扩展名为xml
+	FormatScc    = "scc"    // cea-608字幕
+)
+
+// Formats 除srt外可以额外导出的格式
+var Formats = []string{FormatVtt, FormatTtml, FormatDfxp, FormatSbv, FormatFcpxml, FormatXmeml, FormatScc}
+
+// Layout 字幕块中原文和译文的排列方式
+type Layout int
+
+const (
+	LayoutOrigin      Layout = iota // 只有原文
+	LayoutTarget                    // 只有译文
+	LayoutOriginFirst               // 双语，原文在上
+	LayoutTargetFirst               // 双语，译文在上
+)
+
+func (l Layout) Bilingual() bool {
+	return l == LayoutOriginFirst || l == LayoutTargetFirst
+}
+
+// Word 字幕块内的一个词，时间为在完整音视频中的时间
+type Word struct {
+	Text       string        `json:"text"`
+	Start      time.Duration `json:"start"`
+	End        time.Duration `json:"end"`
+	Confidence float64       `json:"confidence,omitempty"`
+}
+
+// Cue 一条字幕，文字中可以包含换行
+type Cue struct {
+	Index              int           `json:"index"`
+	Start              time.Duration `json:"start"`
+	End                time.Duration `json:"end"`
+	OriginText         string        `json:"origin_text"`
+	TranslatedText     string        `json:"translated_text"`
+	Speaker            string        `json:"speaker,omitempty"`    // 说话人显示名称
+	Words              []Word        `json:"words,omitempty"`      // 原文的词级时间戳
+	Confidence         float64       `json:"confidence,omitempty"` // 词的平均识别置信度，转录服务不提供时为0
+	LowConfidenceWords []string      `json:"low_confidence_words,omitempty"`
+	Settings           string        `json:"settings,omitempty"` // webvtt的cue设置
+	Style              string        `json:"style,omitempty"`    // ass的样式名
+}
+
+// Subtitle 一份字幕，原文和译文在同一条字幕中
+type Subtitle struct {
+	Cues []*Cue `json:"cues"`
+}
+
+// Options 读写字幕时的可选参数
+type Options struct {
+	Layout         Layout
+	Language       string    // 字幕语言，如zh-CN，用于ttml的xml:lang
+	SpeakerPrefix  bool      // srt等纯文本格式中以[说话人]前缀标注说话人
+	VttCueSettings string    // 字幕没有单独设置时使用的webvtt cue设置，如line:85% align:center
+	AssHeader      string    // ass的头部，为空时使用只有Default样式的头部
+	FrameRate      FrameRate // 视频帧率，fcpxml、xmeml和scc按帧计时，为空时使用DefaultFrameRate
+	Width          int       // 视频宽度，fcpxml和xmeml使用，为0时使用1920
+	Height         int       // 视频高度，为0时使用1080
+}
+
+// FormatExtension 格式对应的文件扩展名，不含点
+func FormatExtension(format string) string {
+	if format == FormatXmeml {
+		return "xml"
+	}
+	return format
+}
+
+// IsFrameBasedFormat 是否为按视频帧计时的格式
+func IsFrameBasedFormat(format string) bool {
+	return format == FormatFcpxml || format == FormatXmeml || format == FormatScc
+}
+
+func (o Options) videoSize() (int, int) {
+	if o.Width <= 0 || o.Height <= 0 {
+		return 1920, 1080
+	}
+	return o.Width, o.Height
+}
+
+// IsSupportedFormat 是否为支持额外导出的格式
+func IsSupportedFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Parts 按排列方式返回字幕的各部分文字，双语时为原文和译文，空的部分不返回
+func (c *Cue) Parts(layout Layout) []string {
+	var parts []string
+	switch layout {
+	case LayoutOrigin:
+		parts = []string{c.OriginText}
+	case LayoutTarget:
+		parts = []string{c.TranslatedText}
+	case LayoutOriginFirst:
+		parts = []string{c.OriginText, c.TranslatedText}
+	case LayoutTargetFirst:
+		parts = []string{c.TranslatedText, c.OriginText}
+	}
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// Lines 按排列方式返回字幕的全部文字行
+func (c *Cue) Lines(layout Layout) []string {
+	var lines []string
+	for _, part := range c.Parts(layout) {
+		lines = append(lines, strings.Split(part, "\n")...)
+	}
+	return lines
+}
+
+// Text 按排列方式返回字幕文字，行之间以换行分隔
+func (c *Cue) Text(layout Layout) string {
+	return strings.Join(c.Lines(layout), "\n")
+}
+
+// SetParts 按排列方式设置原文和译文，是Parts的逆操作
+func (c *Cue) SetParts(layout Layout, parts ...string) {
+	var first, second string
+	if len(parts) > 0 {
+		first = parts[0]
+	}
+	if len(parts) > 1 {
+		second = strings.Join(parts[1:], "\n")
+	}
+	switch layout {
+	case LayoutOrigin:
+		c.OriginText = strings.Join(parts, "\n")
+	case LayoutTarget:
+		c.TranslatedText = strings.Join(parts, "\n")
+	case LayoutOriginFirst:
+		c.OriginText, c.TranslatedText = first, second
+	case LayoutTargetFirst:
+		c.TranslatedText, c.OriginText = first, second
+	}
+}
+
+// ErrPlainTextBilingual srt和webvtt中原文和译文都可能有多行，读取时无法区分，双语字幕只能从结构化数据或ass读取
+var ErrPlainTextBilingual = errors.New("srt and webvtt cannot tell origin lines from translated lines")
+
+// Renumber 按顺序重新编号
+func (s *Subtitle) Renumber() {
+	for i, cue := range s.Cues {
+		cue.Index = i + 1
+	}
+}
+
+// 把时间拆分为时、分、秒、毫秒
+func splitDuration(d time.Duration) (hours, minutes, seconds, millis int) {
+	if d < 0 {
+		d = 0
+	}
+	ms := int(d / time.Millisecond)
+	return ms / 3600000, ms / 60000 % 60, ms / 1000 % 60, ms % 1000
+}
+
+// Read 按格式读取字幕，支持srt、vtt和ass，其中srt和vtt只能按单语读取
+func Read(r io.Reader, format string, options Options) (*Subtitle, error) {
+	switch format {
+	case FormatSrt:
+		return ReadSrt(r, options)
+	case FormatVtt:
+		return ReadVtt(r, options)
+	case FormatAss:
+		return ReadAss(r, options)
+	}
+	return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+}
+
+// ReadFile 按格式读取字幕文件
+func ReadFile(path, format string, options Options) (*Subtitle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile open file err: %w", err)
+	}
+	defer file.Close()
+	return Read(file, format, options)
+}
+
+// Write 按格式写入字幕
+func Write(w io.Writer, sub *Subtitle, format string, options Options) error {
+	switch format {
+	case FormatSrt:
+		return WriteSrt(w, sub, options)
+	case FormatVtt:
+		return WriteVtt(w, sub, options)
+	case FormatAss:
+		return WriteAss(w, sub, options)
+	case FormatTtml, FormatDfxp:
+		return WriteTtml(w, sub, options)
+	case FormatSbv:
+		return WriteSbv(w, sub, options)
+	case FormatFcpxml:
+		return WriteFcpxml(w, sub, options)
+	case FormatXmeml:
+		return WriteXmeml(w, sub, options)
+	case FormatScc:
+		return WriteScc(w, sub, options)
+	}
+	return fmt.Errorf("unsupported subtitle format: %s", format)
+}
+
+// WriteFile 按格式写入字幕文件，返回*DroppedTextError时文件已经写入
+func WriteFile(path string, sub *Subtitle, format string, options Options) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("WriteFile create file err: %w", err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	writeErr := Write(writer, sub, format, options)
+	var dropped *DroppedTextError
+	if writeErr != nil && !errors.As(writeErr, &dropped) {
+		return writeErr
+	}
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("WriteFile flush err: %w", err)
+	}
+	return writeErr
+}
+
+// LoadJson 读取保存的字幕，保留词级时间戳和置信度等全部信息
+func LoadJson(path string) (*Subtitle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadJson read file err: %w", err)
+	}
+	var sub Subtitle
+	if err = json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("LoadJson unmarshal err: %w", err)
+	}
+	return &sub, nil
+}
+
+// SaveJson 保存字幕
+func SaveJson(path string, sub *Subtitle) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(sub); err != nil {
+		return fmt.Errorf("SaveJson marshal err: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("SaveJson write file err: %w", err)
+	}
+	return nil
+}
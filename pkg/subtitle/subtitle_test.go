@@ -0,0 +1,176 @@
+package subtitle
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSubtitle() *Subtitle {
+	return &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: time.Second, End: 3500 * time.Millisecond, OriginText: "Hello & <world>\nsecond line", TranslatedText: "你好，世界\n第二行", Speaker: "Alice"},
+		{Index: 2, Start: time.Minute + 2010*time.Millisecond, End: time.Hour, OriginText: "Bye", TranslatedText: "再见"},
+	}}
+}
+
+// 只比较格式和排列方式能表示的字段
+func comparableCues(sub *Subtitle, layout Layout, withSpeaker bool) []Cue {
+	cues := make([]Cue, len(sub.Cues))
+	for i, cue := range sub.Cues {
+		cues[i] = Cue{Index: cue.Index, Start: cue.Start, End: cue.End}
+		cues[i].SetParts(layout, cue.Parts(layout)...)
+		if withSpeaker {
+			cues[i].Speaker = cue.Speaker
+		}
+	}
+	return cues
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		format      string
+		options     Options
+		withSpeaker bool
+	}{
+		{FormatSrt, Options{Layout: LayoutOrigin, SpeakerPrefix: true}, true},
+		{FormatSrt, Options{Layout: LayoutTarget}, false},
+		{FormatVtt, Options{Layout: LayoutTarget}, true},
+		{FormatVtt, Options{Layout: LayoutOrigin, VttCueSettings: "line:85% align:center"}, true},
+		{FormatAss, Options{Layout: LayoutTargetFirst}, true},
+		{FormatAss, Options{Layout: LayoutOriginFirst}, true},
+	}
+	for _, test := range tests {
+		want := testSubtitle()
+		var first strings.Builder
+		if err := Write(&first, want, test.format, test.options); err != nil {
+			t.Fatalf("Write %s err: %v", test.format, err)
+		}
+		got, err := Read(strings.NewReader(first.String()), test.format, test.options)
+		if err != nil {
+			t.Fatalf("Read %s err: %v", test.format, err)
+		}
+		if !reflect.DeepEqual(comparableCues(got, test.options.Layout, test.withSpeaker), comparableCues(want, test.options.Layout, test.withSpeaker)) {
+			t.Errorf("%s %+v round trip mismatch:\n%s", test.format, test.options, first.String())
+		}
+		// 再写一次应得到完全相同的文件
+		var second strings.Builder
+		if err = Write(&second, got, test.format, test.options); err != nil {
+			t.Fatalf("Write %s again err: %v", test.format, err)
+		}
+		if first.String() != second.String() {
+			t.Errorf("%s output changed after round trip:\n%s\n---\n%s", test.format, first.String(), second.String())
+		}
+	}
+}
+
+func TestReadPlainTextBilingual(t *testing.T) {
+	for _, format := range []string{FormatSrt, FormatVtt} {
+		var builder strings.Builder
+		if err := Write(&builder, testSubtitle(), format, Options{Layout: LayoutOriginFirst}); err != nil {
+			t.Fatalf("Write %s err: %v", format, err)
+		}
+		if _, err := Read(strings.NewReader(builder.String()), format, Options{Layout: LayoutOriginFirst}); !errors.Is(err, ErrPlainTextBilingual) {
+			t.Errorf("Read bilingual %s err = %v, want ErrPlainTextBilingual", format, err)
+		}
+	}
+}
+
+func TestRoundTripMonolingual(t *testing.T) {
+	for _, format := range []string{FormatSrt, FormatVtt, FormatAss} {
+		options := Options{Layout: LayoutTarget}
+		want := testSubtitle()
+		var builder strings.Builder
+		if err := Write(&builder, want, format, options); err != nil {
+			t.Fatalf("Write %s err: %v", format, err)
+		}
+		got, err := Read(strings.NewReader(builder.String()), format, options)
+		if err != nil {
+			t.Fatalf("Read %s err: %v", format, err)
+		}
+		if len(got.Cues) != len(want.Cues) {
+			t.Fatalf("%s expected %d cues, got %d", format, len(want.Cues), len(got.Cues))
+		}
+		for i, cue := range got.Cues {
+			if cue.TranslatedText != want.Cues[i].TranslatedText || cue.OriginText != "" || cue.Start != want.Cues[i].Start || cue.End != want.Cues[i].End {
+				t.Errorf("%s cue %d mismatch: %+v", format, i, cue)
+			}
+		}
+	}
+}
+
+func TestReadExternalFiles(t *testing.T) {
+	srt := "\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\n[Bob] line one\r\nline two\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nlast\r\n"
+	sub, err := ReadSrt(strings.NewReader(srt), Options{Layout: LayoutOrigin, SpeakerPrefix: true})
+	if err != nil {
+		t.Fatalf("ReadSrt err: %v", err)
+	}
+	if len(sub.Cues) != 2 || sub.Cues[0].OriginText != "line one\nline two" || sub.Cues[0].Speaker != "Bob" {
+		t.Errorf("unexpected srt cues %+v", sub.Cues[0])
+	}
+
+	// 文字中的箭头不会被当成时间轴，字幕数量按解析结果统计
+	srt = "1\n00:00:01,000 --> 00:00:02,000\nA --> B\n\n2\n00:00:03,000 --> 00:00:04,000\nstep 1 --> step 2 --> done\n"
+	sub, err = ReadSrt(strings.NewReader(srt), Options{Layout: LayoutOrigin})
+	if err != nil {
+		t.Fatalf("ReadSrt err: %v", err)
+	}
+	if len(sub.Cues) != 2 || sub.Cues[0].OriginText != "A --> B" {
+		t.Errorf("unexpected srt cues with arrows %+v", sub.Cues)
+	}
+
+	vtt := "WEBVTT - test\n\nNOTE a comment\nspanning lines\n\nintro\n01:02.500 --> 01:03.000 align:start\n<v.loud Carol>Hi &amp; bye</v>\n"
+	sub, err = ReadVtt(strings.NewReader(vtt), Options{Layout: LayoutTarget})
+	if err != nil {
+		t.Fatalf("ReadVtt err: %v", err)
+	}
+	if len(sub.Cues) != 1 || sub.Cues[0].Start != time.Minute+2500*time.Millisecond || sub.Cues[0].TranslatedText != "Hi & bye" || sub.Cues[0].Speaker != "Carol" || sub.Cues[0].Settings != "align:start" {
+		t.Errorf("unexpected vtt cues %+v", sub.Cues)
+	}
+
+	ass := "[Script Info]\nTitle: x\n\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.50,0:00:02.00,Major1,,0,0,0,,{\\an2}{\\rMajor1}上方, 文字\\N{\\rMinor1}bottom text\n"
+	sub, err = ReadAss(strings.NewReader(ass), Options{Layout: LayoutTargetFirst})
+	if err != nil {
+		t.Fatalf("ReadAss err: %v", err)
+	}
+	if len(sub.Cues) != 1 || sub.Cues[0].TranslatedText != "上方, 文字" || sub.Cues[0].OriginText != "bottom text" || sub.Cues[0].Start != 1500*time.Millisecond {
+		t.Errorf("unexpected ass cues %+v", sub.Cues)
+	}
+}
+
+func TestWriteExportFormats(t *testing.T) {
+	tests := []struct {
+		format  string
+		options Options
+		want    []string
+	}{
+		{FormatVtt, Options{Layout: LayoutTargetFirst, VttCueSettings: "line:85%  align:center"}, []string{"WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.500 line:85% align:center\n<v Alice>你好，世界\n第二行\nHello &amp; &lt;world&gt;\n"}},
+		{FormatTtml, Options{Layout: LayoutOriginFirst, Language: "zh-CN"}, []string{`xml:lang="zh-CN"`, `<p xml:id="c1" begin="00:00:01.000" end="00:00:03.500">Hello &amp; &lt;world&gt;<br/>second line<br/>你好，世界<br/>第二行</p>`}},
+		{FormatSbv, Options{Layout: LayoutTarget}, []string{"0:00:01.000,0:00:03.500\n你好，世界\n第二行\n\n0:01:02.010,1:00:00.000\n再见\n"}},
+	}
+	for _, test := range tests {
+		var builder strings.Builder
+		if err := Write(&builder, testSubtitle(), test.format, test.options); err != nil {
+			t.Fatalf("Write %s err: %v", test.format, err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(builder.String(), want) {
+				t.Errorf("%s output missing %q, got:\n%s", test.format, want, builder.String())
+			}
+		}
+	}
+}
+
+func TestValidateVttCueSettings(t *testing.T) {
+	for _, settings := range []string{"", "line:85% align:center", "line:-2 position:50%,center size:80% vertical:rl"} {
+		if err := ValidateVttCueSettings(settings); err != nil {
+			t.Errorf("expected %q valid, got %v", settings, err)
+		}
+	}
+	for _, settings := range []string{"color:red", "align:middle", "line85%"} {
+		if err := ValidateVttCueSettings(settings); err == nil {
+			t.Errorf("expected %q invalid", settings)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FrameRate 视频帧率，以分数表示，如29.97为30000/1001
+type FrameRate struct {
+	Num int
+	Den int
+}
+
+// DefaultFrameRate 无法获取视频帧率时使用的帧率
+var DefaultFrameRate = FrameRate{Num: 30000, Den: 1001}
+
+// ParseFrameRate 解析ffprobe输出的帧率，支持30000/1001、25和29.97的形式
+// 平均帧率常是359160/11987这样的分数，接近整数或NTSC帧率时取标准值
+func ParseFrameRate(value string) (FrameRate, error) {
+	value = strings.TrimSpace(value)
+	var fps float64
+	if num, den, ok := strings.Cut(value, "/"); ok {
+		n, err1 := strconv.Atoi(num)
+		d, err2 := strconv.Atoi(den)
+		if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+			return FrameRate{}, fmt.Errorf("invalid frame rate: %s", value)
+		}
+		if d == 1 || d == 1001 {
+			return FrameRate{Num: n, Den: d}, nil
+		}
+		fps = float64(n) / float64(d)
+	} else {
+		var err error
+		fps, err = strconv.ParseFloat(value, 64)
+		if err != nil || fps <= 0 {
+			return FrameRate{}, fmt.Errorf("invalid frame rate: %s", value)
+		}
+	}
+	rounded := math.Round(fps)
+	if math.Abs(fps-rounded) <= 0.001 {
+		return FrameRate{Num: int(rounded), Den: 1}, nil
+	}
+	// 29.97、59.94等NTSC帧率转为x000/1001
+	if math.Abs(fps-rounded*1000/1001) < 0.01 {
+		return FrameRate{Num: int(rounded) * 1000, Den: 1001}, nil
+	}
+	return FrameRate{Num: int(math.Round(fps * 1000)), Den: 1000}, nil
+}
+
+func (f FrameRate) valid() FrameRate {
+	if f.Num <= 0 || f.Den <= 0 {
+		return DefaultFrameRate
+	}
+	return f
+}
+
+// Fps 每秒帧数
+func (f FrameRate) Fps() float64 {
+	f = f.valid()
+	return float64(f.Num) / float64(f.Den)
+}
+
+// Timebase 时间码使用的整数帧率，如29.97为30
+func (f FrameRate) Timebase() int {
+	return int(math.Round(f.Fps()))
+}
+
+// Ntsc 是否为29.97、59.94等NTSC帧率
+func (f FrameRate) Ntsc() bool {
+	f = f.valid()
+	return f.Den == 1001
+}
+
+// DropFrame 是否使用丢帧时间码，只用于29.97和59.94
+func (f FrameRate) DropFrame() bool {
+	return f.Ntsc() && f.Timebase()%30 == 0
+}
+
+// Frames 时间对应的帧序号，四舍五入到最近的帧
+func (f FrameRate) Frames(d time.Duration) int64 {
+	f = f.valid()
+	return int64(math.Round(d.Seconds() * float64(f.Num) / float64(f.Den)))
+}
+
+// Seconds 帧数对应的有理数秒，如1001/30000s，fcpxml使用
+func (f FrameRate) Seconds(frames int64) string {
+	f = f.valid()
+	if frames == 0 {
+		return "0s"
+	}
+	num, den := frames*int64(f.Den), int64(f.Num)
+	divisor := gcd(num, den)
+	if den/divisor == 1 {
+		return fmt.Sprintf("%ds", num/divisor)
+	}
+	return fmt.Sprintf("%d/%ds", num/divisor, den/divisor)
+}
+
+func gcd(a, b int64) int64 {
+	if a < 0 {
+		a = -a
+	}
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Timecode 帧序号对应的时间码，丢帧时间码为HH:MM:SS;FF，否则为HH:MM:SS:FF
+func (f FrameRate) Timecode(frames int64) string {
+	timebase := int64(f.Timebase())
+	separator := ":"
+	if f.DropFrame() {
+		// 每分钟开头丢弃编号0和1(59.94为0到3)，逢十分钟不丢
+		separator = ";"
+		drop := timebase / 15
+		framesPer10Minutes := timebase*600 - drop*9
+		framesPerMinute := timebase*60 - drop
+		tens, rest := frames/framesPer10Minutes, frames%framesPer10Minutes
+		frames += drop * 9 * tens
+		if rest > drop {
+			frames += drop * ((rest - drop) / framesPerMinute)
+		}
+	}
+	ff := frames % timebase
+	totalSeconds := frames / timebase
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", totalSeconds/3600, totalSeconds/60%60, totalSeconds%60, separator, ff)
+}
@@ -0,0 +1,120 @@
+package subtitle
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFrameRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  FrameRate
+	}{
+		{"30000/1001", FrameRate{30000, 1001}},
+		{"25/1", FrameRate{25, 1}},
+		{"25", FrameRate{25, 1}},
+		{"29.97", FrameRate{30000, 1001}},
+		{"23.976", FrameRate{24000, 1001}},
+		{"359160/11987", FrameRate{30000, 1001}},
+		{"50/2", FrameRate{25, 1}},
+		{"2997/100", FrameRate{30000, 1001}},
+		{"59/2", FrameRate{29500, 1000}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFrameRate(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFrameRate(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "0/0", "abc"} {
+		if _, err := ParseFrameRate(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestTimecode(t *testing.T) {
+	ntsc := FrameRate{30000, 1001}
+	tests := []struct {
+		rate   FrameRate
+		frames int64
+		want   string
+	}{
+		{FrameRate{25, 1}, 25*3600 + 24, "01:00:00:24"},
+		{ntsc, 1799, "00:00:59;29"},
+		// 丢帧时间码跳过每分钟开头的;00和;01
+		{ntsc, 1800, "00:01:00;02"},
+		// 逢十分钟不丢帧
+		{ntsc, 17982, "00:10:00;00"},
+		{FrameRate{60000, 1001}, 3600, "00:01:00;04"},
+	}
+	for _, tt := range tests {
+		if got := tt.rate.Timecode(tt.frames); got != tt.want {
+			t.Errorf("Timecode(%d) at %v = %s, want %s", tt.frames, tt.rate, got, tt.want)
+		}
+	}
+	if got := ntsc.Frames(10 * time.Second); got != 300 {
+		t.Errorf("expected 300 frames, got %d", got)
+	}
+	if got := ntsc.Seconds(300); got != "1001/100s" {
+		t.Errorf("expected 1001/100s, got %s", got)
+	}
+}
+
+func TestWriteScc(t *testing.T) {
+	sub := &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, OriginText: "Hi ♪"},
+	}}
+	var builder strings.Builder
+	if err := WriteScc(&builder, sub, Options{Layout: LayoutOrigin, FrameRate: FrameRate{25, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	// 29.97丢帧计时，显示命令在第30帧发出，居中的单行放在第15行并用制表偏移补齐列
+	want := "Scenarist_SCC V1.0\n\n00:00:00;16\t9420 9420 94ae 94ae 9476 9476 97a2 97a2 c8e9 2080 9137 9137 942f 942f\n\n00:00:02;00\t942c 942c\n"
+	if builder.String() != want {
+		t.Errorf("got %q, want %q", builder.String(), want)
+	}
+}
+
+func TestWriteSccUnsupportedChars(t *testing.T) {
+	sub := &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, OriginText: "Café “ok”"},
+		{Index: 2, Start: 3 * time.Second, End: 4 * time.Second, OriginText: "你好 ok 你"},
+		{Index: 3, Start: 5 * time.Second, End: 6 * time.Second, OriginText: "€5"},
+	}}
+	var builder strings.Builder
+	err := WriteScc(&builder, sub, Options{Layout: LayoutOrigin})
+	var dropped *DroppedTextError
+	if !errors.As(err, &dropped) {
+		t.Fatalf("expected DroppedTextError, got %v", err)
+	}
+	if !reflect.DeepEqual(dropped.Cues, []int{2, 3}) || string(dropped.Chars) != "你好€" || dropped.TruncatedCues != nil {
+		t.Errorf("got cues %v chars %q truncated %v", dropped.Cues, string(dropped.Chars), dropped.TruncatedCues)
+	}
+	if strings.Count(builder.String(), "942f 942f") != 3 {
+		t.Errorf("expected all cues written, got %q", builder.String())
+	}
+}
+
+func TestWriteSccTruncatedLines(t *testing.T) {
+	long := strings.Repeat("caption text that keeps going ", 6)
+	sub := &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: time.Second, End: 2 * time.Second, OriginText: "one\ntwo\nthree\nfour"},
+		{Index: 2, Start: 3 * time.Second, End: 5 * time.Second, OriginText: long},
+	}}
+	var builder strings.Builder
+	err := WriteScc(&builder, sub, Options{Layout: LayoutOrigin, Language: "en"})
+	var dropped *DroppedTextError
+	if !errors.As(err, &dropped) {
+		t.Fatalf("expected DroppedTextError, got %v", err)
+	}
+	if !reflect.DeepEqual(dropped.TruncatedCues, []int{2}) || dropped.Cues != nil {
+		t.Errorf("got truncated %v cues %v", dropped.TruncatedCues, dropped.Cues)
+	}
+	if strings.Count(builder.String(), "942f 942f") != 2 {
+		t.Errorf("expected all cues written, got %q", builder.String())
+	}
+}
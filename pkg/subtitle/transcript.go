@@ -0,0 +1,271 @@
+package subtitle
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 文稿导出格式
+const (
+	TranscriptFormatTxt  = "txt"
+	TranscriptFormatMd   = "md"
+	TranscriptFormatDocx = "docx"
+	TranscriptFormatJson = "json"
+)
+
+var TranscriptFormats = []string{TranscriptFormatTxt, TranscriptFormatMd, TranscriptFormatDocx, TranscriptFormatJson}
+
+// IsTranscriptFormat 是否为支持的文稿格式
+func IsTranscriptFormat(format string) bool {
+	for _, f := range TranscriptFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// TranscriptOptions 导出文稿时的可选参数
+type TranscriptOptions struct {
+	Layout       Layout        // LayoutOrigin或LayoutTarget
+	Language     string        // 文稿语言，如zh-CN
+	ParagraphGap time.Duration // 字幕之间的停顿不小于该时长时另起一段，为0时只在说话人变化时分段
+	Timestamps   bool          // 每段前标注开始时间
+	Speakers     bool          // 每段前标注说话人
+}
+
+// Paragraph 文稿中的一段，由连续的字幕合并而成
+type Paragraph struct {
+	Start   time.Duration
+	End     time.Duration
+	Speaker string
+	Text    string
+	Cues    []*Cue
+}
+
+// Paragraphs 按停顿时长和说话人把字幕合并为段落，文字为空的字幕跳过
+func (s *Subtitle) Paragraphs(options TranscriptOptions) []*Paragraph {
+	var (
+		paragraphs []*Paragraph
+		current    *Paragraph
+	)
+	for _, cue := range s.Cues {
+		text := JoinLines(cue.Text(options.Layout))
+		if text == "" {
+			continue
+		}
+		newParagraph := current == nil ||
+			(options.ParagraphGap > 0 && cue.Start-current.End >= options.ParagraphGap) ||
+			(options.Speakers && cue.Speaker != current.Speaker)
+		if newParagraph {
+			current = &Paragraph{Start: cue.Start, Speaker: cue.Speaker}
+			paragraphs = append(paragraphs, current)
+		}
+		current.End = cue.End
+		current.Text = joinText(current.Text, text)
+		current.Cues = append(current.Cues, cue)
+	}
+	return paragraphs
+}
+
+// 文稿中的时间不需要毫秒，如00:01:23
+func formatTranscriptTime(d time.Duration) string {
+	hours, minutes, seconds, _ := splitDuration(d)
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
+
+// paragraphLabel 段落前的时间和说话人标注，如[00:01:23] 张三
+func paragraphLabel(paragraph *Paragraph, options TranscriptOptions) string {
+	var labels []string
+	if options.Timestamps {
+		labels = append(labels, "["+formatTranscriptTime(paragraph.Start)+"]")
+	}
+	if options.Speakers && paragraph.Speaker != "" {
+		labels = append(labels, paragraph.Speaker)
+	}
+	return strings.Join(labels, " ")
+}
+
+// WriteTranscriptText 写入纯文本文稿，段落之间空一行
+func WriteTranscriptText(w io.Writer, sub *Subtitle, options TranscriptOptions) error {
+	var blocks []string
+	for _, paragraph := range sub.Paragraphs(options) {
+		text := paragraph.Text
+		if label := paragraphLabel(paragraph, options); label != "" {
+			text = label + ": " + text
+		}
+		blocks = append(blocks, text)
+	}
+	_, err := io.WriteString(w, strings.Join(blocks, "\n\n")+"\n")
+	return err
+}
+
+// WriteTranscriptMarkdown 写入markdown文稿，时间和说话人加粗放在段首
+func WriteTranscriptMarkdown(w io.Writer, sub *Subtitle, options TranscriptOptions) error {
+	var blocks []string
+	for _, paragraph := range sub.Paragraphs(options) {
+		text := paragraph.Text
+		if label := paragraphLabel(paragraph, options); label != "" {
+			text = "**" + label + "**: " + text
+		}
+		blocks = append(blocks, text)
+	}
+	_, err := io.WriteString(w, strings.Join(blocks, "\n\n")+"\n")
+	return err
+}
+
+type transcriptJsonWord struct {
+	Text       string  `json:"text"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+type transcriptJsonCue struct {
+	Index int                  `json:"index"`
+	Start float64              `json:"start"`
+	End   float64              `json:"end"`
+	Text  string               `json:"text"`
+	Words []transcriptJsonWord `json:"words,omitempty"`
+}
+
+type transcriptJsonParagraph struct {
+	Start   float64             `json:"start"`
+	End     float64             `json:"end"`
+	Speaker string              `json:"speaker,omitempty"`
+	Text    string              `json:"text"`
+	Cues    []transcriptJsonCue `json:"cues"`
+}
+
+type transcriptJson struct {
+	Language   string                    `json:"language,omitempty"`
+	Paragraphs []transcriptJsonParagraph `json:"paragraphs"`
+}
+
+// WriteTranscriptJson 写入json文稿，时间单位为秒，原文文稿包含词级时间戳
+func WriteTranscriptJson(w io.Writer, sub *Subtitle, options TranscriptOptions) error {
+	result := transcriptJson{
+		Language:   options.Language,
+		Paragraphs: []transcriptJsonParagraph{},
+	}
+	for _, paragraph := range sub.Paragraphs(options) {
+		item := transcriptJsonParagraph{
+			Start:   roundSeconds(paragraph.Start),
+			End:     roundSeconds(paragraph.End),
+			Speaker: paragraph.Speaker,
+			Text:    paragraph.Text,
+		}
+		for _, cue := range paragraph.Cues {
+			jsonCue := transcriptJsonCue{
+				Index: cue.Index,
+				Start: roundSeconds(cue.Start),
+				End:   roundSeconds(cue.End),
+				Text:  JoinLines(cue.Text(options.Layout)),
+			}
+			// 词级时间戳只对应原文
+			if options.Layout == LayoutOrigin {
+				for _, word := range cue.Words {
+					jsonCue.Words = append(jsonCue.Words, transcriptJsonWord{
+						Text:       word.Text,
+						Start:      roundSeconds(word.Start),
+						End:        roundSeconds(word.End),
+						Confidence: word.Confidence,
+					})
+				}
+			}
+			item.Cues = append(item.Cues, jsonCue)
+		}
+		result.Paragraphs = append(result.Paragraphs, item)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(result)
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="xml" ContentType="application/xml"/>
+  <Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>
+`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>
+`
+
+// docxRun 生成docx中的一段文字
+func docxRun(text string, bold bool) string {
+	var props string
+	if bold {
+		props = "<w:rPr><w:b/></w:rPr>"
+	}
+	return fmt.Sprintf(`<w:r>%s<w:t xml:space="preserve">%s</w:t></w:r>`, props, escapeXml(text))
+}
+
+// WriteTranscriptDocx 写入只包含正文的docx文稿，时间和说话人加粗放在段首
+func WriteTranscriptDocx(w io.Writer, sub *Subtitle, options TranscriptOptions) error {
+	var body strings.Builder
+	for _, paragraph := range sub.Paragraphs(options) {
+		body.WriteString("<w:p>")
+		if label := paragraphLabel(paragraph, options); label != "" {
+			body.WriteString(docxRun(label+": ", true))
+		}
+		body.WriteString(docxRun(paragraph.Text, false) + "</w:p>")
+	}
+	document := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s<w:sectPr/></w:body></w:document>
+`, body.String())
+
+	archive := zip.NewWriter(w)
+	for _, file := range []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"word/document.xml", document},
+	} {
+		writer, err := archive.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("WriteTranscriptDocx create %s err: %w", file.name, err)
+		}
+		if _, err = io.WriteString(writer, file.content); err != nil {
+			return fmt.Errorf("WriteTranscriptDocx write %s err: %w", file.name, err)
+		}
+	}
+	return archive.Close()
+}
+
+// WriteTranscript 按格式写入文稿
+func WriteTranscript(w io.Writer, sub *Subtitle, format string, options TranscriptOptions) error {
+	switch format {
+	case TranscriptFormatTxt:
+		return WriteTranscriptText(w, sub, options)
+	case TranscriptFormatMd:
+		return WriteTranscriptMarkdown(w, sub, options)
+	case TranscriptFormatDocx:
+		return WriteTranscriptDocx(w, sub, options)
+	case TranscriptFormatJson:
+		return WriteTranscriptJson(w, sub, options)
+	}
+	return fmt.Errorf("unsupported transcript format: %s", format)
+}
+
+// WriteTranscriptFile 按格式写入文稿文件
+func WriteTranscriptFile(path string, sub *Subtitle, format string, options TranscriptOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("WriteTranscriptFile create file err: %w", err)
+	}
+	defer file.Close()
+	return WriteTranscript(file, sub, format, options)
+}
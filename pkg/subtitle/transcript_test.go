@@ -0,0 +1,89 @@
+package subtitle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTranscriptSubtitle() *Subtitle {
+	return &Subtitle{Cues: []*Cue{
+		{Index: 1, Start: 0, End: 2 * time.Second, OriginText: "Hello\nthere.", TranslatedText: "你好。", Speaker: "A",
+			Words: []Word{{Text: "Hello", Start: 0, End: 500 * time.Millisecond}, {Text: "there.", Start: 600 * time.Millisecond, End: 2 * time.Second}}},
+		{Index: 2, Start: 2500 * time.Millisecond, End: 4 * time.Second, OriginText: "How are you?", TranslatedText: "你好吗？", Speaker: "A"},
+		// 停顿超过段落间隔
+		{Index: 3, Start: 10 * time.Second, End: 12 * time.Second, OriginText: "Fine.", TranslatedText: "很好。", Speaker: "A"},
+		// 说话人变化
+		{Index: 4, Start: 12 * time.Second, End: 13 * time.Second, OriginText: "Great!", TranslatedText: "太好了！", Speaker: "B"},
+	}}
+}
+
+func TestWriteTranscriptText(t *testing.T) {
+	var buf bytes.Buffer
+	options := TranscriptOptions{Layout: LayoutOrigin, ParagraphGap: 2 * time.Second, Timestamps: true, Speakers: true}
+	if err := WriteTranscriptText(&buf, testTranscriptSubtitle(), options); err != nil {
+		t.Fatal(err)
+	}
+	want := "[00:00:00] A: Hello there. How are you?\n\n[00:00:10] A: Fine.\n\n[00:00:12] B: Great!\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	options = TranscriptOptions{Layout: LayoutTarget, ParagraphGap: 2 * time.Second}
+	if err := WriteTranscriptMarkdown(&buf, testTranscriptSubtitle(), options); err != nil {
+		t.Fatal(err)
+	}
+	if want = "你好。你好吗？\n\n很好。太好了！\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriteTranscriptJson(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTranscriptJson(&buf, testTranscriptSubtitle(), TranscriptOptions{Layout: LayoutOrigin, Language: "en", ParagraphGap: 2 * time.Second, Speakers: true}); err != nil {
+		t.Fatal(err)
+	}
+	var result transcriptJson
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Paragraphs) != 3 || len(result.Paragraphs[0].Cues) != 2 {
+		t.Fatalf("unexpected paragraphs %+v", result.Paragraphs)
+	}
+	words := result.Paragraphs[0].Cues[0].Words
+	if len(words) != 2 || words[1].Text != "there." || words[1].Start != 0.6 {
+		t.Errorf("unexpected words %+v", words)
+	}
+}
+
+func TestWriteTranscriptDocx(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTranscriptDocx(&buf, testTranscriptSubtitle(), TranscriptOptions{Layout: LayoutOrigin, Speakers: true}); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document string
+	for _, file := range reader.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		document = string(data)
+	}
+	if len(reader.File) != 3 || strings.Count(document, "<w:p>") != 2 || !strings.Contains(document, "Hello there. How are you? Fine.") {
+		t.Errorf("unexpected docx document %s", document)
+	}
+}
@@ -0,0 +1,67 @@
+package subtitle
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const ttmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" ttp:timeBase="media" xml:lang="%s">
+  <head>
+    <styling>
+      <style xml:id="default" tts:fontFamily="proportionalSansSerif" tts:fontSize="100%%" tts:textAlign="center" tts:color="white"/>
+    </styling>
+    <layout>
+      <region xml:id="bottom" tts:origin="10%% 80%%" tts:extent="80%% 15%%" tts:displayAlign="after"/>
+    </layout>
+  </head>
+  <body style="default" region="bottom">
+    <div>
+`
+
+const ttmlFooter = `    </div>
+  </body>
+</tt>
+`
+
+func formatTtmlTime(d time.Duration) string {
+	hours, minutes, seconds, millis := splitDuration(d)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, millis)
+}
+
+func escapeXml(text string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// WriteTtml 写入ttml(dfxp)字幕，多行文字用<br/>分隔
+func WriteTtml(w io.Writer, sub *Subtitle, options Options) error {
+	language := options.Language
+	if language == "" {
+		language = "und"
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(ttmlHeader, escapeXml(language)))
+	index := 0
+	for _, cue := range sub.Cues {
+		lines := cue.Lines(options.Layout)
+		if len(lines) == 0 {
+			continue
+		}
+		index++
+		for i, line := range lines {
+			lines[i] = escapeXml(line)
+		}
+		builder.WriteString(fmt.Sprintf("      <p xml:id=\"c%d\" begin=\"%s\" end=\"%s\">%s</p>\n", index, formatTtmlTime(cue.Start), formatTtmlTime(cue.End), strings.Join(lines, "<br/>")))
+	}
+	builder.WriteString(ttmlFooter)
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("WriteTtml write err: %w", err)
+	}
+	return nil
+}
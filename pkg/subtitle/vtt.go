@@ -0,0 +1,155 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	vttEscaper       = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	vttUnescaper     = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&amp;", "&")
+	vttTimingPattern = regexp.MustCompile(`^\s*((?:\d+:)?\d{2}:\d{2}\.\d{3})\s*-->\s*((?:\d+:)?\d{2}:\d{2}\.\d{3})(.*)$`)
+	vttVoicePattern  = regexp.MustCompile(`^<v(?:\.[^ >]+)* ([^>]+)>`)
+)
+
+// 各cue设置允许的取值，百分比设置可带,start/center/end等对齐
+var vttCueSettingPatterns = map[string]*regexp.Regexp{
+	"vertical": regexp.MustCompile(`^(rl|lr)$`),
+	"line":     regexp.MustCompile(`^(-?\d+|\d{1,3}(\.\d+)?%)(,(start|center|end))?$`),
+	"position": regexp.MustCompile(`^\d{1,3}(\.\d+)?%(,(line-left|center|line-right))?$`),
+	"size":     regexp.MustCompile(`^\d{1,3}(\.\d+)?%$`),
+	"align":    regexp.MustCompile(`^(start|center|end|left|right)$`),
+	"region":   regexp.MustCompile(`^[^\s:]+$`),
+}
+
+// ValidateVttCueSettings 校验webvtt的cue设置，如line:85% align:center
+func ValidateVttCueSettings(settings string) error {
+	for _, setting := range strings.Fields(settings) {
+		name, value, ok := strings.Cut(setting, ":")
+		pattern, known := vttCueSettingPatterns[name]
+		if !ok || !known {
+			return fmt.Errorf("unknown webvtt cue setting: %s", setting)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("invalid webvtt cue setting value: %s", setting)
+		}
+	}
+	return nil
+}
+
+func formatVttTime(d time.Duration) string {
+	hours, minutes, seconds, millis := splitDuration(d)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, millis)
+}
+
+// webvtt的小时可以省略，如01:02.500
+func parseVttTime(value string) time.Duration {
+	if strings.Count(value, ":") == 1 {
+		value = "00:" + value
+	}
+	start, _, _ := ParseSrtTiming(value + " --> " + value)
+	return start
+}
+
+// ReadVtt 读取单语webvtt字幕，<v 说话人>标签读为说话人，忽略NOTE、STYLE和REGION块
+func ReadVtt(r io.Reader, options Options) (*Subtitle, error) {
+	if options.Layout.Bilingual() {
+		return nil, fmt.Errorf("ReadVtt err: %w", ErrPlainTextBilingual)
+	}
+	sub := &Subtitle{}
+	var cue *Cue
+	var lines []string
+	skipBlock := false
+	flush := func() {
+		if cue != nil {
+			if len(lines) > 0 {
+				if match := vttVoicePattern.FindStringSubmatch(lines[0]); match != nil {
+					cue.Speaker = vttUnescaper.Replace(match[1])
+					lines[0] = lines[0][len(match[0]):]
+					last := len(lines) - 1
+					lines[last] = strings.TrimSuffix(lines[last], "</v>")
+				}
+			}
+			for i, line := range lines {
+				lines[i] = vttUnescaper.Replace(line)
+			}
+			cue.SetParts(options.Layout, strings.Join(lines, "\n"))
+		}
+		cue, lines, skipBlock = nil, nil, false
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), "\r")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if skipBlock {
+			continue
+		}
+		if cue == nil {
+			if strings.HasPrefix(line, "WEBVTT") || strings.HasPrefix(line, "NOTE") || line == "STYLE" || line == "REGION" {
+				skipBlock = true
+				continue
+			}
+			if match := vttTimingPattern.FindStringSubmatch(line); match != nil {
+				cue = &Cue{
+					Index:    len(sub.Cues) + 1,
+					Start:    parseVttTime(match[1]),
+					End:      parseVttTime(match[2]),
+					Settings: strings.Join(strings.Fields(match[3]), " "),
+				}
+				sub.Cues = append(sub.Cues, cue)
+			}
+			// 时间轴之前的cue标识行
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ReadVtt scan err: %w", err)
+	}
+	return sub, nil
+}
+
+// WriteVtt 写入webvtt字幕，说话人写为<v 说话人>标签
+func WriteVtt(w io.Writer, sub *Subtitle, options Options) error {
+	defaultSettings := strings.Join(strings.Fields(options.VttCueSettings), " ")
+	var builder strings.Builder
+	builder.WriteString("WEBVTT\n\n")
+	index := 0
+	for _, cue := range sub.Cues {
+		lines := cue.Lines(options.Layout)
+		if len(lines) == 0 {
+			continue
+		}
+		index++
+		settings := cue.Settings
+		if settings == "" {
+			settings = defaultSettings
+		}
+		builder.WriteString(fmt.Sprintf("%d\n%s --> %s", index, formatVttTime(cue.Start), formatVttTime(cue.End)))
+		if settings != "" {
+			builder.WriteString(" " + settings)
+		}
+		builder.WriteString("\n")
+		for i, line := range lines {
+			line = vttEscaper.Replace(line)
+			if i == 0 && cue.Speaker != "" {
+				line = fmt.Sprintf("<v %s>%s", vttEscaper.Replace(cue.Speaker), line)
+			}
+			builder.WriteString(line + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("WriteVtt write err: %w", err)
+	}
+	return nil
+}
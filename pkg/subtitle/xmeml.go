@@ -0,0 +1,119 @@
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const xmemlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE xmeml>
+<xmeml version="4">
+  <sequence id="sequence-1">
+    <name>Subtitles</name>
+    <duration>%d</duration>
+    %s
+    <timecode>
+      %s
+      <string>%s</string>
+      <frame>0</frame>
+      <displayformat>%s</displayformat>
+    </timecode>
+    <media>
+      <video>
+        <format>
+          <samplecharacteristics>
+            %s
+            <width>%d</width>
+            <height>%d</height>
+          </samplecharacteristics>
+        </format>
+        <track>
+`
+
+const xmemlFooter = `        </track>
+      </video>
+    </media>
+  </sequence>
+</xmeml>
+`
+
+const xmemlGeneratorItem = `          <generatoritem id="caption-%d">
+            <name>%s</name>
+            <enabled>TRUE</enabled>
+            <duration>%d</duration>
+            %s
+            <start>%d</start>
+            <end>%d</end>
+            <in>0</in>
+            <out>%d</out>
+            <effect>
+              <name>Text</name>
+              <effectid>Text</effectid>
+              <effectcategory>Text</effectcategory>
+              <effecttype>generator</effecttype>
+              <mediatype>video</mediatype>
+              <parameter>
+                <parameterid>str</parameterid>
+                <name>Text</name>
+                <value>%s</value>
+              </parameter>
+              <parameter>
+                <parameterid>fontsize</parameterid>
+                <name>Size</name>
+                <valuemin>0</valuemin>
+                <valuemax>1000</valuemax>
+                <value>%d</value>
+              </parameter>
+              <parameter>
+                <parameterid>origin</parameterid>
+                <name>Origin</name>
+                <value>
+                  <horiz>0</horiz>
+                  <vert>0.4</vert>
+                </value>
+              </parameter>
+            </effect>
+          </generatoritem>
+`
+
+// xmemlRate xmeml中的帧率，29.97等NTSC帧率为timebase 30加ntsc标记
+func xmemlRate(rate FrameRate) string {
+	ntsc := "FALSE"
+	if rate.Ntsc() {
+		ntsc = "TRUE"
+	}
+	return fmt.Sprintf("<rate><timebase>%d</timebase><ntsc>%s</ntsc></rate>", rate.Timebase(), ntsc)
+}
+
+// WriteXmeml 写入final cut pro 7 xml(xmeml)，每条字幕为视频轨道上的一个文字生成器，premiere pro导入后可编辑
+func WriteXmeml(w io.Writer, sub *Subtitle, options Options) error {
+	rate := options.FrameRate.valid()
+	width, height := options.videoSize()
+	displayFormat := "NDF"
+	if rate.DropFrame() {
+		displayFormat = "DF"
+	}
+	var total int64
+	for _, cue := range sub.Cues {
+		total = max(total, rate.Frames(cue.End))
+	}
+	rateXml := xmemlRate(rate)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(xmemlHeader, total, rateXml, rateXml, rate.Timecode(0), displayFormat, rateXml, width, height))
+	index := 0
+	for _, cue := range sub.Cues {
+		text := cue.Text(options.Layout)
+		start, end := rate.Frames(cue.Start), rate.Frames(cue.End)
+		if text == "" || end <= start {
+			continue
+		}
+		index++
+		builder.WriteString(fmt.Sprintf(xmemlGeneratorItem, index, escapeXml(JoinLines(text)), end-start, rateXml, start, end, end-start,
+			escapeXml(strings.ReplaceAll(text, "\n", "\r")), height/20))
+	}
+	builder.WriteString(xmemlFooter)
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
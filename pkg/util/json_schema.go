@@ -0,0 +1,97 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type jsonSchema struct {
+	Type                 string                 `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+}
+
+// ValidateJsonSchema 按json schema校验数据，只支持type、properties、required、additionalProperties、items和minItems
+func ValidateJsonSchema(schema []byte, data []byte) error {
+	var s jsonSchema
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("invalid json schema: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return fmt.Errorf("invalid json: %w", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("invalid json: unexpected data after top-level value")
+	}
+	return validateJsonValue("$", &s, v)
+}
+
+func validateJsonValue(path string, s *jsonSchema, v any) error {
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expect object", path)
+		}
+		for _, key := range s.Required {
+			if _, ok = obj[key]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, key)
+			}
+		}
+		for key, value := range obj {
+			property, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected field %q", path, key)
+				}
+				continue
+			}
+			if err := validateJsonValue(path+"."+key, property, value); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expect array", path)
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			return fmt.Errorf("%s: expect at least %d items, got %d", path, *s.MinItems, len(arr))
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := validateJsonValue(fmt.Sprintf("%s[%d]", path, i), s.Items, item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: expect string", path)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expect integer", path)
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s: expect integer", path)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return fmt.Errorf("%s: expect number", path)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expect boolean", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package util
+
+import "testing"
+
+func TestValidateJsonSchema(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"translations": {
+				"type": "array",
+				"minItems": 1,
+				"items": {
+					"type": "object",
+					"properties": {"id": {"type": "integer"}, "text": {"type": "string"}},
+					"required": ["id", "text"],
+					"additionalProperties": false
+				}
+			}
+		},
+		"required": ["translations"]
+	}`)
+	cases := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"translations":[{"id":1,"text":"你好"}],"summary":"extra field allowed"}`, false},
+		{"invalid json", `{"translations":[`, true},
+		{"missing required", `{"summary":""}`, true},
+		{"empty array", `{"translations":[]}`, true},
+		{"wrong type", `{"translations":[{"id":"1","text":"你好"}]}`, true},
+		{"float id", `{"translations":[{"id":1.5,"text":"你好"}]}`, true},
+		{"additional property", `{"translations":[{"id":1,"text":"你好","note":""}]}`, true},
+		{"trailing data", `{"translations":[{"id":1,"text":"你好"}]} {}`, true},
+	}
+	for _, c := range cases {
+		err := ValidateJsonSchema(schema, []byte(c.data))
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: wantErr %v, got %v", c.name, c.wantErr, err)
+		}
+	}
+}
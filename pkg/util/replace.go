@@ -0,0 +1,156 @@
+package util
+
+import (
+	"fmt"
+	"krillin-ai/internal/types"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordReplacer 按顺序应用文字替换规则
+type WordReplacer struct {
+	rules []replaceRule
+}
+
+type replaceRule struct {
+	types.ReplaceRule
+	re *regexp.Regexp
+}
+
+// NewWordReplacer 编译替换规则，规则为空时返回nil，nil也可以直接调用Replace
+func NewWordReplacer(rules []types.ReplaceRule) (*WordReplacer, error) {
+	var compiled []replaceRule
+	for _, rule := range rules {
+		if rule.From == "" {
+			continue
+		}
+		pattern := rule.From
+		if !rule.Regex {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if rule.IgnoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("替换规则%s不是合法的正则表达式: %w", rule.From, err)
+		}
+		compiled = append(compiled, replaceRule{ReplaceRule: rule, re: re})
+	}
+	if len(compiled) == 0 {
+		return nil, nil
+	}
+	return &WordReplacer{rules: compiled}, nil
+}
+
+// Replace 依次应用各规则，前一条规则的结果作为后一条规则的输入
+func (r *WordReplacer) Replace(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, rule := range r.rules {
+		text = rule.replace(text)
+	}
+	return text
+}
+
+// Split 把规则分为逐词替换和整句替换两组，一个词替换为一个词的普通规则可以作用于词级时间戳中的每个词，
+// 正则表达式、含空格或删除文字的规则会改变分词，只能作用于整句，分组后各组内仍保持原有顺序
+func (r *WordReplacer) Split() (words, phrases *WordReplacer) {
+	if r == nil {
+		return nil, nil
+	}
+	var wordRules, phraseRules []replaceRule
+	for _, rule := range r.rules {
+		if !rule.Regex && isSingleToken(rule.From) && isSingleToken(rule.To) {
+			wordRules = append(wordRules, rule)
+		} else {
+			phraseRules = append(phraseRules, rule)
+		}
+	}
+	if len(wordRules) > 0 {
+		words = &WordReplacer{rules: wordRules}
+	}
+	if len(phraseRules) > 0 {
+		phrases = &WordReplacer{rules: phraseRules}
+	}
+	return words, phrases
+}
+
+// isSingleToken 文字不含空格，且不是多个逐字成词的字符，替换前后词的数量不变
+func isSingleToken(text string) bool {
+	if text == "" || strings.IndexFunc(text, unicode.IsSpace) >= 0 {
+		return false
+	}
+	if utf8.RuneCountInString(text) == 1 {
+		return true
+	}
+	return strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) && !isWordRune(r) }) < 0
+}
+
+func (rule replaceRule) replace(text string) string {
+	matches := rule.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var (
+		builder strings.Builder
+		last    int
+	)
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if start == end || (!rule.PartialMatch && !isWholeWord(text, start, end)) {
+			continue
+		}
+		replacement := rule.To
+		if rule.Regex {
+			replacement = string(rule.re.ExpandString(nil, rule.To, text, match))
+		}
+		if rule.IgnoreCase && rule.PreserveCase {
+			replacement = matchCase(text[start:end], replacement)
+		}
+		builder.WriteString(text[last:start])
+		builder.WriteString(replacement)
+		last = end
+	}
+	builder.WriteString(text[last:])
+	return builder.String()
+}
+
+// isWordRune 组成单词的字符，中日文和泰文等不用空格分词的文字逐字视为独立的词
+func isWordRune(r rune) bool {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar) {
+		return false
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || r == '_'
+}
+
+// isWholeWord 匹配的文字两端没有和前后的字符连成一个词
+func isWholeWord(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	if start > 0 && isWordRune(first) && isWordRune(before) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	if end < len(text) && isWordRune(last) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+// matchCase 按被替换文字的大小写调整替换文字，全大写时替换为全大写，首字母大写时替换文字首字母大写
+func matchCase(matched, replacement string) string {
+	if strings.ToUpper(matched) == matched && strings.ToLower(matched) != matched {
+		return strings.ToUpper(replacement)
+	}
+	first, _ := utf8.DecodeRuneInString(matched)
+	if unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(replacement)
+		return string(unicode.ToUpper(r)) + replacement[size:]
+	}
+	return replacement
+}
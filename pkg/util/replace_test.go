@@ -0,0 +1,64 @@
+package util
+
+import (
+	"krillin-ai/internal/types"
+	"testing"
+)
+
+func TestWordReplacer(t *testing.T) {
+	tests := []struct {
+		name string
+		rule types.ReplaceRule
+		text string
+		want string
+	}{
+		{"whole word", types.ReplaceRule{From: "cat", To: "dog"}, "cat, category and concat cat", "dog, category and concat dog"},
+		{"case sensitive", types.ReplaceRule{From: "ai", To: "AI"}, "Ai and ai", "Ai and AI"},
+		{"ignore case", types.ReplaceRule{From: "open ai", To: "OpenAI", IgnoreCase: true}, "Open AI and open ai", "OpenAI and OpenAI"},
+		{"preserve case", types.ReplaceRule{From: "colour", To: "color", IgnoreCase: true, PreserveCase: true}, "Colour COLOUR colour", "Color COLOR color"},
+		{"partial match", types.ReplaceRule{From: "colour", To: "color", PartialMatch: true}, "colourful", "colorful"},
+		{"regex", types.ReplaceRule{From: `(\d+) percent`, To: "$1%", Regex: true}, "up 20 percent", "up 20%"},
+		{"cjk", types.ReplaceRule{From: "克林", To: "Krillin"}, "我是克林AI", "我是KrillinAI"},
+	}
+	for _, tt := range tests {
+		replacer, err := NewWordReplacer([]types.ReplaceRule{tt.rule})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := replacer.Replace(tt.text); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := NewWordReplacer([]types.ReplaceRule{{From: "(", Regex: true}}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	var replacer *WordReplacer
+	if got := replacer.Replace("text"); got != "text" {
+		t.Errorf("nil replacer changed text: %q", got)
+	}
+}
+
+func TestWordReplacerSplit(t *testing.T) {
+	replacer, err := NewWordReplacer([]types.ReplaceRule{
+		{From: "colour", To: "color"},
+		{From: "open ai", To: "OpenAI"},
+		{From: `(\d+) percent`, To: "$1%", Regex: true},
+		{From: "um", To: ""},
+		{From: "克林", To: "Krillin"},
+		{From: "gonna", To: "going-to"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, phrases := replacer.Split()
+	if got := words.Replace("colour gonna open ai 克林"); got != "color going-to open ai 克林" {
+		t.Errorf("word rules: got %q", got)
+	}
+	if got := phrases.Replace("open ai up 20 percent um 克林"); got != "OpenAI up 20%  Krillin" {
+		t.Errorf("phrase rules: got %q", got)
+	}
+	words, phrases = (*WordReplacer)(nil).Split()
+	if words != nil || phrases != nil {
+		t.Error("nil replacer should split into nil replacers")
+	}
+}
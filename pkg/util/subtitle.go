@@ -14,55 +14,6 @@ import (
 	"unicode"
 )
 
-// 处理每一个字幕块
-func ProcessBlock(block []string, targetLanguageFile, targetLanguageTextFile, originLanguageFile, originLanguageTextFile *os.File, isTargetOnTop bool) {
-	var targetLines, originLines []string
-	// 匹配时间戳的正则表达式
-	timePattern := regexp.MustCompile(`\d{2}:\d{2}:\d{2},\d{3} --> \d{2}:\d{2}:\d{2},\d{3}`)
-	for _, line := range block {
-		if timePattern.MatchString(line) || IsNumber(line) {
-			// 时间戳和编号行保留在两个文件中
-			targetLines = append(targetLines, line)
-			originLines = append(originLines, line)
-			continue
-		}
-		if len(targetLines) == 2 && len(originLines) == 2 { // 刚写完编号和时间戳，到了上方的文字行
-			if isTargetOnTop {
-				targetLines = append(targetLines, line)
-				targetLanguageTextFile.WriteString(line + " ") // 文稿文件
-			} else {
-				originLines = append(originLines, line)
-				originLanguageTextFile.WriteString(line + " ")
-			}
-			continue
-		}
-		// 到了下方的文字行
-		if isTargetOnTop {
-			originLines = append(originLines, line)
-			originLanguageTextFile.WriteString(line + " ")
-		} else {
-			targetLines = append(targetLines, line)
-			targetLanguageTextFile.WriteString(line + " ")
-		}
-	}
-
-	if len(targetLines) > 2 {
-		// 写入目标语言文件
-		for _, line := range targetLines {
-			targetLanguageFile.WriteString(line + "\n")
-		}
-		targetLanguageFile.WriteString("\n")
-	}
-
-	if len(originLines) > 2 {
-		// 写入源语言文件
-		for _, line := range originLines {
-			originLanguageFile.WriteString(line + "\n")
-		}
-		originLanguageFile.WriteString("\n")
-	}
-}
-
 // IsSubtitleText 是否是字幕文件中的字幕文字行
 func IsSubtitleText(line string) bool {
 	if line == "" {
@@ -88,6 +39,9 @@ type SrtBlock struct {
 	Timestamp              string
 	TargetLanguageSentence string
 	OriginLanguageSentence string
+	Speaker                string   // 说话人id，未开启说话人分离时为空
+	Confidence             float64  // 块内词的平均识别置信度，转录服务不提供时为0
+	LowConfidenceWords     []string // 置信度低于阈值的词
 }
 
 func TrimString(s string) string {
@@ -182,39 +136,6 @@ func MergeSrtFiles(finalFile string, files ...string) error {
 	return nil
 }
 
-// 给定文件和替换map，将文件中所有的key替换成value
-func ReplaceFileContent(srcFile, dstFile string, replacements map[string]string) error {
-	file, err := os.Open(srcFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	outFile, err := os.Create(dstFile)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
-
-	scanner := bufio.NewScanner(file)
-	writer := bufio.NewWriter(outFile) // 提高性能
-	defer writer.Flush()
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		for before, after := range replacements {
-			line = strings.ReplaceAll(line, before, after)
-		}
-		_, _ = writer.WriteString(line + "\n")
-	}
-
-	if err = scanner.Err(); err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // 获得文件名后加上后缀的新文件名，不改变扩展名，例如：/home/ubuntu/abc.srt变成/home/ubuntu/abc_tmp.srt
 func AddSuffixToFileName(filePath, suffix string) string {
 	dir := filepath.Dir(filePath)
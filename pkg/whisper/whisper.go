@@ -9,7 +9,7 @@ import (
 	"strings"
 )
 
-func (c *Client) Transcription(audioFile, language, workDir string) (*types.TranscriptionData, error) {
+func (c *Client) Transcription(audioFile, language, workDir string, options types.TranscriptionOptions) (*types.TranscriptionData, error) {
 	resp, err := c.client.CreateTranscription(
 		context.Background(),
 		openai.AudioRequest{
@@ -20,6 +20,7 @@ func (c *Client) Transcription(audioFile, language, workDir string) (*types.Tran
 				openai.TranscriptionTimestampGranularityWord,
 			},
 			Language: language,
+			Prompt:   options.HotwordsPrompt(),
 		},
 	)
 	if err != nil {
@@ -16,7 +16,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func (c *WhispercppProcessor) Transcription(audioFile, language, workDir string) (*types.TranscriptionData, error) {
+func (c *WhispercppProcessor) Transcription(audioFile, language, workDir string, options types.TranscriptionOptions) (*types.TranscriptionData, error) {
 	name := util.ChangeFileExtension(audioFile, "")
 	cmdArgs := []string{
 		"-m", fmt.Sprintf("./models/whispercpp/ggml-%s.bin", c.Model),
@@ -27,6 +27,9 @@ func (c *WhispercppProcessor) Transcription(audioFile, language, workDir string)
 		"--output-file", name,
 		"--file", audioFile,
 	}
+	if len(options.Hotwords) > 0 {
+		cmdArgs = append(cmdArgs, "--prompt", options.HotwordsPrompt())
+	}
 	cmd := exec.Command(storage.WhispercppPath, cmdArgs...)
 	log.GetLogger().Info("WhispercppProcessor转录开始", zap.String("cmd", cmd.String()))
 	output, err := cmd.CombinedOutput()
@@ -76,25 +79,28 @@ func (c *WhispercppProcessor) Transcription(audioFile, language, workDir string)
 				seperatedWords := strings.Split(word.Text, "—")
 				transcriptionData.Words = append(transcriptionData.Words, []types.Word{
 					{
-						Num:   num,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
-						Start: fromSec,
-						End:   mid,
+						Num:        num,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
+						Confidence: word.P,
+						Start:      fromSec,
+						End:        mid,
 					},
 					{
-						Num:   num + 1,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
-						Start: mid,
-						End:   toSec,
+						Num:        num + 1,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
+						Confidence: word.P,
+						Start:      mid,
+						End:        toSec,
 					},
 				}...)
 				num += 2
 			} else {
 				transcriptionData.Words = append(transcriptionData.Words, types.Word{
-					Num:   num,
-					Text:  util.CleanPunction(strings.TrimSpace(word.Text)),
-					Start: fromSec,
-					End:   toSec,
+					Num:        num,
+					Text:       util.CleanPunction(strings.TrimSpace(word.Text)),
+					Confidence: word.P,
+					Start:      fromSec,
+					End:        toSec,
 				})
 				num++
 			}
@@ -13,7 +13,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func (c *WhisperKitProcessor) Transcription(audioFile, language, workDir string) (*types.TranscriptionData, error) {
+func (c *WhisperKitProcessor) Transcription(audioFile, language, workDir string, options types.TranscriptionOptions) (*types.TranscriptionData, error) {
 	cmdArgs := []string{
 		"transcribe",
 		"--model-path", "./models/whisperkit/openai_whisper-large-v2",
@@ -26,6 +26,9 @@ func (c *WhisperKitProcessor) Transcription(audioFile, language, workDir string)
 		"--skip-special-tokens",
 		"--audio-path", audioFile,
 	}
+	if len(options.Hotwords) > 0 {
+		cmdArgs = append(cmdArgs, "--prompt", options.HotwordsPrompt())
+	}
 	cmd := exec.Command(storage.WhisperKitPath, cmdArgs...)
 	log.GetLogger().Info("WhisperKitProcessor转录开始", zap.String("cmd", cmd.String()))
 	output, err := cmd.CombinedOutput()
@@ -61,25 +64,28 @@ func (c *WhisperKitProcessor) Transcription(audioFile, language, workDir string)
 				seperatedWords := strings.Split(word.Word, "—")
 				transcriptionData.Words = append(transcriptionData.Words, []types.Word{
 					{
-						Num:   num,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
-						Start: word.Start,
-						End:   mid,
+						Num:        num,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
+						Confidence: word.Probability,
+						Start:      word.Start,
+						End:        mid,
 					},
 					{
-						Num:   num + 1,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
-						Start: mid,
-						End:   word.End,
+						Num:        num + 1,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
+						Confidence: word.Probability,
+						Start:      mid,
+						End:        word.End,
 					},
 				}...)
 				num += 2
 			} else {
 				transcriptionData.Words = append(transcriptionData.Words, types.Word{
-					Num:   num,
-					Text:  util.CleanPunction(strings.TrimSpace(word.Word)),
-					Start: word.Start,
-					End:   word.End,
+					Num:        num,
+					Text:       util.CleanPunction(strings.TrimSpace(word.Word)),
+					Confidence: word.Probability,
+					Start:      word.Start,
+					End:        word.End,
 				})
 				num++
 			}
@@ -0,0 +1,37 @@
+package whisperx
+
+import (
+	"krillin-ai/internal/types"
+	"krillin-ai/log"
+
+	"go.uber.org/zap"
+)
+
+// Diarization 对完整音频运行带说话人分离的whisperx，把说话人相同的连续词合并为说话人片段
+func (c *WhisperXProcessor) Diarization(audioFile, workDir string) ([]types.SpeakerSegment, error) {
+	args := []string{"--diarize", "--hf_token", c.HfToken}
+	if c.DiarizeModel != "" {
+		args = append(args, "--diarize_model", c.DiarizeModel)
+	}
+	result, err := c.run(audioFile, "", workDir, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []types.SpeakerSegment
+	for _, segment := range result.Segments {
+		for _, word := range segment.Words {
+			// 没有对齐时间戳的词跳过
+			if word.Speaker == "" || word.End <= word.Start {
+				continue
+			}
+			if last := len(segments) - 1; last >= 0 && segments[last].Speaker == word.Speaker {
+				segments[last].End = word.End
+				continue
+			}
+			segments = append(segments, types.SpeakerSegment{Speaker: word.Speaker, Start: word.Start, End: word.End})
+		}
+	}
+	log.GetLogger().Info("WhisperXProcessor说话人分离成功", zap.Int("segments", len(segments)))
+	return segments, nil
+}
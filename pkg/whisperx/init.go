@@ -1,8 +1,10 @@
 package whisperx
 
 type WhisperXProcessor struct {
-	WorkDir string // 生成中间文件的目录
-	Model   string
+	WorkDir      string // 生成中间文件的目录
+	Model        string
+	HfToken      string // 说话人分离使用的HuggingFace Token
+	DiarizeModel string // 说话人分离模型，为空时使用whisperx的默认模型
 }
 
 func NewWhisperXProcessor(model string) *WhisperXProcessor {
@@ -10,3 +12,12 @@ func NewWhisperXProcessor(model string) *WhisperXProcessor {
 		Model: model,
 	}
 }
+
+// NewWhisperXDiarizer 使用whisperx自带的说话人分离
+func NewWhisperXDiarizer(model, hfToken, diarizeModel string) *WhisperXProcessor {
+	return &WhisperXProcessor{
+		Model:        model,
+		HfToken:      hfToken,
+		DiarizeModel: diarizeModel,
+	}
+}
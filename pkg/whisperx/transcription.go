@@ -8,72 +8,20 @@ import (
 	"krillin-ai/pkg/util"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 
 	"go.uber.org/zap"
 )
 
-func (c *WhisperXProcessor) Transcription(audioFile, language, workDir string) (*types.TranscriptionData, error) {
-	var (
-		cmdArgs []string
-		envPath string
-		cmd     *exec.Cmd
-	)
-	if runtime.GOOS == "windows" {
-		envPath = ".\\bin\\whisperx\\.venv\\Scripts\\activate"
-	} else {
-		envPath = storage.WhisperXPath
+func (c *WhisperXProcessor) Transcription(audioFile, language, workDir string, options types.TranscriptionOptions) (*types.TranscriptionData, error) {
+	var extraArgs []string
+	if len(options.Hotwords) > 0 {
+		extraArgs = append(extraArgs, "--initial_prompt", options.HotwordsPrompt())
 	}
-	if runtime.GOOS == "windows" {
-		cmdArgs = []string{
-			"&&",
-			storage.WhisperXPath,
-			audioFile,
-			"--model_dir", "./models/whisperx",
-			"--model", c.Model,
-			"--language", language,
-			"--output_dir", workDir,
-			"--compute_type", "float16",
-			"--batch_size", "6",
-			"--model_cache_only", "True",
-		}
-		cmd = exec.Command(envPath, cmdArgs...)
-	} else {
-		cmdArgs = []string{
-			audioFile,
-			"--model_dir", "./models/whisperx",
-			"--model", c.Model,
-			"--language", language,
-			"--output_dir", workDir,
-			"--compute_type", "float16",
-			"--batch_size", "6",
-			"--model_cache_only", "True",
-		}
-		cmd = exec.Command(envPath, cmdArgs...)
-		cudaLibPath := "LD_LIBRARY_PATH=./bin/whisperx/.venv/lib/python3.12/site-packages/nvidia/cudnn/lib"
-		currentEnv := os.Environ()
-		newEnv := append(currentEnv, cudaLibPath)
-		cmd.Env = newEnv
-	}
-	log.GetLogger().Info("WhisperXProcessor转录开始", zap.String("cmd", cmd.String()))
-	output, err := cmd.CombinedOutput()
+	result, err := c.run(audioFile, language, workDir, extraArgs)
 	if err != nil {
-		log.GetLogger().Error("WhisperXProcessor  cmd 执行失败", zap.String("output", string(output)), zap.Error(err))
-		return nil, err
-	}
-	log.GetLogger().Info("WhisperXProcessor转录json生成完毕", zap.String("audio file", audioFile))
-
-	var result types.WhisperXOutput
-	fileData, err := os.Open(util.ChangeFileExtension(audioFile, ".json"))
-	if err != nil {
-		log.GetLogger().Error("WhisperXProcessor 打开json文件失败", zap.Error(err))
-		return nil, err
-	}
-	defer fileData.Close()
-	decoder := json.NewDecoder(fileData)
-	if err = decoder.Decode(&result); err != nil {
-		log.GetLogger().Error("WhisperXProcessor 解析json文件失败", zap.Error(err))
 		return nil, err
 	}
 
@@ -90,25 +38,31 @@ func (c *WhisperXProcessor) Transcription(audioFile, language, workDir string) (
 				seperatedWords := strings.Split(word.Word, "—")
 				transcriptionData.Words = append(transcriptionData.Words, []types.Word{
 					{
-						Num:   num,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
-						Start: word.Start,
-						End:   mid,
+						Num:        num,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[0])),
+						Confidence: word.Probability,
+						Start:      word.Start,
+						End:        mid,
+						Speaker:    word.Speaker,
 					},
 					{
-						Num:   num + 1,
-						Text:  util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
-						Start: mid,
-						End:   word.End,
+						Num:        num + 1,
+						Text:       util.CleanPunction(strings.TrimSpace(seperatedWords[1])),
+						Confidence: word.Probability,
+						Start:      mid,
+						End:        word.End,
+						Speaker:    word.Speaker,
 					},
 				}...)
 				num += 2
 			} else {
 				transcriptionData.Words = append(transcriptionData.Words, types.Word{
-					Num:   num,
-					Text:  util.CleanPunction(strings.TrimSpace(word.Word)),
-					Start: word.Start,
-					End:   word.End,
+					Num:        num,
+					Text:       util.CleanPunction(strings.TrimSpace(word.Word)),
+					Confidence: word.Probability,
+					Start:      word.Start,
+					End:        word.End,
+					Speaker:    word.Speaker,
 				})
 				num++
 			}
@@ -117,3 +71,57 @@ func (c *WhisperXProcessor) Transcription(audioFile, language, workDir string) (
 	log.GetLogger().Info("WhisperXProcessor转录成功")
 	return &transcriptionData, nil
 }
+
+// run 执行whisperx命令并读取生成的json结果，language为空时由whisperx自动检测
+func (c *WhisperXProcessor) run(audioFile, language, workDir string, extraArgs []string) (*types.WhisperXOutput, error) {
+	var (
+		cmdArgs []string
+		envPath string
+		cmd     *exec.Cmd
+	)
+	if runtime.GOOS == "windows" {
+		envPath = ".\\bin\\whisperx\\.venv\\Scripts\\activate"
+		cmdArgs = []string{"&&", storage.WhisperXPath}
+	} else {
+		envPath = storage.WhisperXPath
+	}
+	cmdArgs = append(cmdArgs,
+		audioFile,
+		"--model_dir", "./models/whisperx",
+		"--model", c.Model,
+		"--output_dir", workDir,
+		"--compute_type", "float16",
+		"--batch_size", "6",
+		"--model_cache_only", "True",
+	)
+	if language != "" {
+		cmdArgs = append(cmdArgs, "--language", language)
+	}
+	cmdArgs = append(cmdArgs, extraArgs...)
+	cmd = exec.Command(envPath, cmdArgs...)
+	if runtime.GOOS != "windows" {
+		cudaLibPath := "LD_LIBRARY_PATH=./bin/whisperx/.venv/lib/python3.12/site-packages/nvidia/cudnn/lib"
+		cmd.Env = append(os.Environ(), cudaLibPath)
+	}
+	log.GetLogger().Info("WhisperXProcessor转录开始", zap.String("cmd", cmd.String()))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.GetLogger().Error("WhisperXProcessor  cmd 执行失败", zap.String("output", string(output)), zap.Error(err))
+		return nil, err
+	}
+	log.GetLogger().Info("WhisperXProcessor转录json生成完毕", zap.String("audio file", audioFile))
+
+	var result types.WhisperXOutput
+	fileData, err := os.Open(filepath.Join(workDir, strings.TrimSuffix(filepath.Base(audioFile), filepath.Ext(audioFile))+".json"))
+	if err != nil {
+		log.GetLogger().Error("WhisperXProcessor 打开json文件失败", zap.Error(err))
+		return nil, err
+	}
+	defer fileData.Close()
+	decoder := json.NewDecoder(fileData)
+	if err = decoder.Decode(&result); err != nil {
+		log.GetLogger().Error("WhisperXProcessor 解析json文件失败", zap.Error(err))
+		return nil, err
+	}
+	return &result, nil
+}